@@ -0,0 +1,475 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package accounts
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+
+	"github.com/ashkanabbasii/thor/api/utils"
+	"github.com/ashkanabbasii/thor/bft"
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/runtime"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/xenv"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+type Accounts struct {
+	repo         *chain.Repository
+	stater       *state.Stater
+	callGasLimit uint64
+	forkConfig   thor.ForkConfig
+	bft          bft.Committer
+}
+
+func New(
+	repo *chain.Repository,
+	stater *state.Stater,
+	callGasLimit uint64,
+	forkConfig thor.ForkConfig,
+	bft bft.Committer,
+) *Accounts {
+	return &Accounts{
+		repo,
+		stater,
+		callGasLimit,
+		forkConfig,
+		bft,
+	}
+}
+
+func (a *Accounts) getCode(addr thor.Address, summary *chain.BlockSummary, withProof bool) (*GetCodeResult, error) {
+	st := a.stater.NewState(summary.Header.StateRoot(), summary.Header.Number(), summary.Conflicts, summary.SteadyNum)
+	code, err := st.GetCode(addr)
+	if err != nil {
+		return nil, err
+	}
+	result := &GetCodeResult{Code: hexutil.Encode(code)}
+	if withProof {
+		if result.Proof, err = a.prove(st, summary.Header, addr, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (a *Accounts) handleGetCode(w http.ResponseWriter, req *http.Request) error {
+	addr, err := thor.ParseAddress(mux.Vars(req)["address"])
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "address"))
+	}
+	summary, withProof, err := a.parseQuery(req)
+	if err != nil {
+		return err
+	}
+	result, err := a.getCode(addr, summary, withProof)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, result)
+}
+
+func (a *Accounts) getAccount(addr thor.Address, summary *chain.BlockSummary, withProof bool) (*Account, error) {
+	st := a.stater.NewState(summary.Header.StateRoot(), summary.Header.Number(), summary.Conflicts, summary.SteadyNum)
+	b, err := st.GetBalance(addr)
+	if err != nil {
+		return nil, err
+	}
+	energy, err := st.GetEnergy(addr, summary.Header.Timestamp())
+	if err != nil {
+		return nil, err
+	}
+	code, err := st.GetCode(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	acc := &Account{
+		Balance: math.HexOrDecimal256(*b),
+		Energy:  math.HexOrDecimal256(*energy),
+		HasCode: len(code) != 0,
+	}
+	if withProof {
+		if acc.Proof, err = a.prove(st, summary.Header, addr, nil); err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+func (a *Accounts) handleGetAccount(w http.ResponseWriter, req *http.Request) error {
+	addr, err := thor.ParseAddress(mux.Vars(req)["address"])
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "address"))
+	}
+	summary, withProof, err := a.parseQuery(req)
+	if err != nil {
+		return err
+	}
+	acc, err := a.getAccount(addr, summary, withProof)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, acc)
+}
+
+func (a *Accounts) getStorage(addr thor.Address, key thor.Bytes32, summary *chain.BlockSummary, withProof bool) (*GetStorageResult, error) {
+	st := a.stater.NewState(summary.Header.StateRoot(), summary.Header.Number(), summary.Conflicts, summary.SteadyNum)
+	storage, err := st.GetStorage(addr, key)
+	if err != nil {
+		return nil, err
+	}
+	result := &GetStorageResult{Value: storage.String()}
+	if withProof {
+		if result.Proof, err = a.prove(st, summary.Header, addr, &key); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (a *Accounts) handleGetStorage(w http.ResponseWriter, req *http.Request) error {
+	addr, err := thor.ParseAddress(mux.Vars(req)["address"])
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "address"))
+	}
+	key, err := thor.ParseBytes32(mux.Vars(req)["key"])
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "key"))
+	}
+	summary, withProof, err := a.parseQuery(req)
+	if err != nil {
+		return err
+	}
+	result, err := a.getStorage(addr, key, summary, withProof)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, result)
+}
+
+// parseQuery parses the revision and proof flag of account queries.
+func (a *Accounts) parseQuery(req *http.Request) (*chain.BlockSummary, bool, error) {
+	revision, err := utils.ParseRevision(req.URL.Query().Get("revision"), false)
+	if err != nil {
+		return nil, false, utils.BadRequest(errors.WithMessage(err, "revision"))
+	}
+	proof := req.URL.Query().Get("proof")
+	if proof != "" && proof != "false" && proof != "true" {
+		return nil, false, utils.BadRequest(errors.WithMessage(errors.New("should be boolean"), "proof"))
+	}
+	summary, err := utils.GetSummary(revision, a.repo, a.bft)
+	if err != nil {
+		if a.repo.IsNotFound(err) {
+			return nil, false, utils.BadRequest(errors.WithMessage(err, "revision"))
+		}
+		return nil, false, err
+	}
+	return summary, proof == "true", nil
+}
+
+// prove builds the merkle proof of the account, and of the storage slot if key is given.
+func (a *Accounts) prove(st *state.State, header *block.Header, addr thor.Address, key *thor.Bytes32) (*Proof, error) {
+	toHex := func(nodes [][]byte) []hexutil.Bytes {
+		hexNodes := make([]hexutil.Bytes, len(nodes))
+		for i, node := range nodes {
+			hexNodes[i] = node
+		}
+		return hexNodes
+	}
+
+	accountProof, err := st.ProveAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	proof := &Proof{
+		StateRoot: header.StateRoot(),
+		Account:   toHex(accountProof),
+	}
+	if key != nil {
+		storageProof, err := st.ProveStorage(addr, *key)
+		if err != nil {
+			return nil, err
+		}
+		proof.Storage = toHex(storageProof)
+	}
+	return proof, nil
+}
+
+func (a *Accounts) handleCallBatchCode(w http.ResponseWriter, req *http.Request) error {
+	var batchCallData BatchCallData
+	if err := utils.ParseJSON(req.Body, &batchCallData); err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "body"))
+	}
+	revision, err := utils.ParseRevision(req.URL.Query().Get("revision"), true)
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "revision"))
+	}
+	estimate := req.URL.Query().Get("estimateGas")
+	if estimate != "" && estimate != "false" && estimate != "true" {
+		return utils.BadRequest(errors.WithMessage(errors.New("should be boolean"), "estimateGas"))
+	}
+
+	summary, st, err := utils.GetSummaryAndState(revision, a.repo, a.bft, a.stater)
+	if err != nil {
+		if a.repo.IsNotFound(err) {
+			return utils.BadRequest(errors.WithMessage(err, "revision"))
+		}
+		return err
+	}
+
+	txCtx, gas, clauses, err := a.handleBatchCallData(&batchCallData)
+	if err != nil {
+		return err
+	}
+	rt := a.newRuntime(summary.Header, st, batchCallData.BlockContext)
+
+	if estimate == "true" {
+		result, err := a.estimateGas(req.Context(), rt, txCtx, gas, clauses)
+		if err != nil {
+			return err
+		}
+		return utils.WriteJSON(w, result)
+	}
+
+	results, _, err := a.batchCall(req.Context(), rt, txCtx, gas, clauses)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, results)
+}
+
+// newRuntime creates a runtime in the context of the given block header, with the optional overrides applied.
+func (a *Accounts) newRuntime(header *block.Header, st *state.State, override *BlockContextOption) *runtime.Runtime {
+	// the signer of a mocked "next" block is not available, leave it as zero
+	signer, _ := header.Signer()
+
+	blockCtx := xenv.BlockContext{
+		Beneficiary: header.Beneficiary(),
+		Signer:      signer,
+		Number:      header.Number(),
+		Time:        header.Timestamp(),
+		GasLimit:    header.GasLimit(),
+		TotalScore:  header.TotalScore(),
+	}
+	if override != nil {
+		if override.Number != nil {
+			blockCtx.Number = *override.Number
+		}
+		if override.Timestamp != nil {
+			blockCtx.Time = *override.Timestamp
+		}
+		if override.GasLimit != nil {
+			blockCtx.GasLimit = *override.GasLimit
+		}
+		if override.TotalScore != nil {
+			blockCtx.TotalScore = *override.TotalScore
+		}
+		if override.Beneficiary != nil {
+			blockCtx.Beneficiary = *override.Beneficiary
+		}
+		if override.Signer != nil {
+			blockCtx.Signer = *override.Signer
+		}
+	}
+	return runtime.New(a.repo.NewChain(header.ParentID()), st, &blockCtx, a.forkConfig)
+}
+
+// batchCall executes the clauses one by one, and stops at the first clause which fails.
+func (a *Accounts) batchCall(
+	ctx context.Context,
+	rt *runtime.Runtime,
+	txCtx *xenv.TransactionContext,
+	gas uint64,
+	clauses []*tx.Clause,
+) (results BatchCallResults, totalGasUsed uint64, err error) {
+	results = make(BatchCallResults, 0, len(clauses))
+	for i, clause := range clauses {
+		exec, interrupt := rt.PrepareClause(clause, uint32(i), gas, txCtx)
+
+		// exec runs on the caller's goroutine so the state is never touched after
+		// returning, the watcher only interrupts the vm once the context is done
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				interrupt()
+			case <-done:
+			}
+		}()
+		output, _, err := exec()
+		close(done)
+
+		if err != nil {
+			return nil, 0, err
+		}
+		// an interrupted clause ends with a vm error, report the cancellation instead
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		results = append(results, convertCallResultWithInputGas(output, gas))
+		totalGasUsed += gas - output.LeftOverGas
+		if output.VMErr != nil {
+			return results, totalGasUsed, nil
+		}
+		gas = output.LeftOverGas
+	}
+	return results, totalGasUsed, nil
+}
+
+// estimateGas binary searches the lowest gas with which the clauses can be executed
+// without failure, the returned gas includes the intrinsic gas of the clauses.
+func (a *Accounts) estimateGas(
+	ctx context.Context,
+	rt *runtime.Runtime,
+	txCtx *xenv.TransactionContext,
+	gas uint64,
+	clauses []*tx.Clause,
+) (*EstimateGasResult, error) {
+	intrinsicGas, err := tx.IntrinsicGas(clauses...)
+	if err != nil {
+		return nil, utils.BadRequest(errors.WithMessage(err, "clauses"))
+	}
+
+	// each attempt executes on the same state, changes are reverted afterwards
+	checkpoint := rt.State().NewCheckpoint()
+	attempt := func(gas uint64) (*CallResult, uint64, error) {
+		defer rt.State().RevertTo(checkpoint)
+
+		results, gasUsed, err := a.batchCall(ctx, rt, txCtx, gas, clauses)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(results) > 0 && results[len(results)-1].Reverted {
+			return results[len(results)-1], gasUsed, nil
+		}
+		return nil, gasUsed, nil
+	}
+
+	failure, gasUsed, err := attempt(gas)
+	if err != nil {
+		return nil, err
+	}
+	if failure != nil {
+		// fails even with the maximum gas
+		return &EstimateGasResult{
+			Gas:          intrinsicGas + gasUsed,
+			Reverted:     true,
+			VMError:      failure.VMError,
+			RevertReason: failure.RevertReason,
+		}, nil
+	}
+
+	if gasUsed == 0 {
+		return &EstimateGasResult{Gas: intrinsicGas}, nil
+	}
+
+	// gas used is the lower bound, but the clauses may require more gas to be
+	// supplied than actually used, e.g. the 63/64 rule
+	lo, hi := gasUsed-1, gas
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		failure, _, err := attempt(mid)
+		if err != nil {
+			return nil, err
+		}
+		if failure != nil {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return &EstimateGasResult{Gas: intrinsicGas + hi}, nil
+}
+
+func (a *Accounts) handleBatchCallData(batchCallData *BatchCallData) (txCtx *xenv.TransactionContext, gas uint64, clauses []*tx.Clause, err error) {
+	if batchCallData.Gas > a.callGasLimit {
+		return nil, 0, nil, utils.Forbidden(errors.New("gas: exceeds limit"))
+	} else if batchCallData.Gas == 0 {
+		gas = a.callGasLimit
+	} else {
+		gas = batchCallData.Gas
+	}
+
+	txCtx = &xenv.TransactionContext{
+		GasPrice:    new(big.Int),
+		ProvedWork:  new(big.Int),
+		Expiration:  batchCallData.Expiration,
+		ClauseCount: uint32(len(batchCallData.Clauses)),
+	}
+	if batchCallData.GasPrice != nil {
+		txCtx.GasPrice = (*big.Int)(batchCallData.GasPrice)
+	}
+	if batchCallData.Caller != nil {
+		txCtx.Origin = *batchCallData.Caller
+	}
+	if batchCallData.GasPayer != nil {
+		txCtx.GasPayer = *batchCallData.GasPayer
+	} else {
+		txCtx.GasPayer = txCtx.Origin
+	}
+	if batchCallData.ProvedWork != nil {
+		txCtx.ProvedWork = (*big.Int)(batchCallData.ProvedWork)
+	}
+	if batchCallData.BlockRef != "" {
+		blockRef, err := hexutil.Decode(batchCallData.BlockRef)
+		if err != nil {
+			return nil, 0, nil, utils.BadRequest(errors.WithMessage(err, "blockRef"))
+		}
+		if len(blockRef) != len(txCtx.BlockRef) {
+			return nil, 0, nil, utils.BadRequest(errors.New("blockRef: invalid length"))
+		}
+		copy(txCtx.BlockRef[:], blockRef)
+	}
+
+	clauses = make([]*tx.Clause, len(batchCallData.Clauses))
+	for i, c := range batchCallData.Clauses {
+		var value *big.Int
+		if c.Value == nil {
+			value = new(big.Int)
+		} else {
+			value = (*big.Int)(c.Value)
+		}
+		var data []byte
+		if c.Data != "" {
+			data, err = hexutil.Decode(c.Data)
+			if err != nil {
+				return nil, 0, nil, utils.BadRequest(errors.WithMessagef(err, "data[%d]", i))
+			}
+		}
+		clauses[i] = tx.NewClause(c.To).WithData(data).WithValue(value)
+	}
+	return
+}
+
+func (a *Accounts) Mount(root *mux.Router, pathPrefix string) {
+	sub := root.PathPrefix(pathPrefix).Subrouter()
+
+	sub.Path("/*").
+		Methods(http.MethodPost).
+		Name("accounts_call_batch_code").
+		HandlerFunc(utils.WrapHandlerFunc(a.handleCallBatchCode))
+	sub.Path("/{address}").
+		Methods(http.MethodGet).
+		Name("accounts_get_account").
+		HandlerFunc(utils.WrapHandlerFunc(a.handleGetAccount))
+	sub.Path("/{address}/code").
+		Methods(http.MethodGet).
+		Name("accounts_get_code").
+		HandlerFunc(utils.WrapHandlerFunc(a.handleGetCode))
+	sub.Path("/{address}/storage/{key}").
+		Methods(http.MethodGet).
+		Name("accounts_get_storage").
+		HandlerFunc(utils.WrapHandlerFunc(a.handleGetStorage))
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package accounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/trie"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+const callGasLimit = 10_000_000
+
+var (
+	// stores 1 at slot 0
+	storeAddr = thor.BytesToAddress([]byte("store"))
+	storeCode = hexutil.MustDecode("0x600160005500")
+	// returns the block number
+	numberAddr = thor.BytesToAddress([]byte("number"))
+	numberCode = hexutil.MustDecode("0x4360005260206000f3")
+	// reverts with reason "boom"
+	revertAddr = thor.BytesToAddress([]byte("revert"))
+
+	storageKey   = thor.BytesToBytes32([]byte("key"))
+	storageValue = thor.BytesToBytes32([]byte("value"))
+)
+
+func revertCode() []byte {
+	reason := hexutil.MustDecode("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"626f6f6d00000000000000000000000000000000000000000000000000000000")
+	// codecopy the reason to memory and revert with it
+	code := []byte{0x61, 0, byte(len(reason)), 0x60, 0x0e, 0x60, 0x00, 0x39, 0x61, 0, byte(len(reason)), 0x60, 0x00, 0xfd}
+	return append(code, reason...)
+}
+
+type committer struct {
+	finalized thor.Bytes32
+}
+
+func (c *committer) Finalized() thor.Bytes32          { return c.finalized }
+func (c *committer) Justified() (thor.Bytes32, error) { return c.finalized, nil }
+
+func newTestRouter(t *testing.T) *mux.Router {
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+
+	st := stater.NewState(thor.Bytes32{}, 0, 0, 0)
+	st.SetCode(storeAddr, storeCode)
+	st.SetCode(numberAddr, numberCode)
+	st.SetCode(revertAddr, revertCode())
+	st.SetBalance(storeAddr, big.NewInt(100))
+	st.SetStorage(storeAddr, storageKey, storageValue)
+	stage, err := st.Stage(0, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+
+	router := mux.NewRouter()
+	New(repo, stater, callGasLimit, thor.NoFork, &committer{genesis.Header().ID()}).Mount(router, "/accounts")
+	return router
+}
+
+func post(t *testing.T, router *mux.Router, path string, body interface{}) (int, []byte) {
+	data, err := json.Marshal(body)
+	assert.Nil(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.Bytes()
+}
+
+func get(t *testing.T, router *mux.Router, path string) (int, []byte) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.Bytes()
+}
+
+// proofDB serves proof nodes by their hash.
+type proofDB map[thor.Bytes32][]byte
+
+func (db proofDB) Get(key []byte) ([]byte, error) {
+	return db[thor.BytesToBytes32(key)], nil
+}
+
+func newProofDB(proof []hexutil.Bytes) proofDB {
+	db := make(proofDB)
+	for _, node := range proof {
+		db[thor.Blake2b(node)] = node
+	}
+	return db
+}
+
+func TestGetAccount(t *testing.T) {
+	router := newTestRouter(t)
+
+	code, res := get(t, router, "/accounts/"+storeAddr.String())
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var acc map[string]interface{}
+	assert.Nil(t, json.Unmarshal(res, &acc))
+	assert.Equal(t, map[string]interface{}{"balance": "0x64", "energy": "0x0", "hasCode": true}, acc)
+
+	code, res = get(t, router, "/accounts/"+storeAddr.String()+"?proof=true&revision=0")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var withProof struct {
+		Proof *Proof
+	}
+	assert.Nil(t, json.Unmarshal(res, &withProof))
+	enc, err, _ := trie.VerifyProof(withProof.Proof.StateRoot, thor.Blake2b(storeAddr.Bytes()).Bytes(), newProofDB(withProof.Proof.Account))
+	assert.Nil(t, err)
+	var stateAcc state.Account
+	assert.Nil(t, rlp.DecodeBytes(enc, &stateAcc))
+	assert.Equal(t, big.NewInt(100), stateAcc.Balance)
+
+	code, _ = get(t, router, "/accounts/0xzz")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = get(t, router, "/accounts/"+storeAddr.String()+"?revision=next")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = get(t, router, "/accounts/"+storeAddr.String()+"?proof=1")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGetCode(t *testing.T) {
+	router := newTestRouter(t)
+
+	code, res := get(t, router, "/accounts/"+storeAddr.String()+"/code")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var result GetCodeResult
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.Equal(t, hexutil.Encode(storeCode), result.Code)
+	assert.Nil(t, result.Proof)
+
+	code, res = get(t, router, "/accounts/"+storeAddr.String()+"/code?proof=true")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.NotEmpty(t, result.Proof.Account)
+
+	code, _ = get(t, router, "/accounts/0xzz/code")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGetStorage(t *testing.T) {
+	router := newTestRouter(t)
+
+	path := "/accounts/" + storeAddr.String() + "/storage/" + storageKey.String()
+	code, res := get(t, router, path)
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var result GetStorageResult
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.Equal(t, storageValue.String(), result.Value)
+
+	code, res = get(t, router, path+"?proof=true")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Nil(t, json.Unmarshal(res, &result))
+	enc, err, _ := trie.VerifyProof(result.Proof.StateRoot, thor.Blake2b(storeAddr.Bytes()).Bytes(), newProofDB(result.Proof.Account))
+	assert.Nil(t, err)
+	var stateAcc state.Account
+	assert.Nil(t, rlp.DecodeBytes(enc, &stateAcc))
+	enc, err, _ = trie.VerifyProof(thor.BytesToBytes32(stateAcc.StorageRoot), thor.Blake2b(storageKey.Bytes()).Bytes(), newProofDB(result.Proof.Storage))
+	assert.Nil(t, err)
+	_, content, _, err := rlp.Split(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, storageValue, thor.BytesToBytes32(content))
+
+	code, _ = get(t, router, "/accounts/"+storeAddr.String()+"/storage/0xzz")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestCallBatchCode(t *testing.T) {
+	router := newTestRouter(t)
+
+	code, res := post(t, router, "/accounts/*", &BatchCallData{
+		Clauses: Clauses{
+			{To: &storeAddr},
+			{To: &numberAddr},
+		},
+	})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var results BatchCallResults
+	assert.Nil(t, json.Unmarshal(res, &results))
+	assert.Equal(t, 2, len(results))
+	assert.False(t, results[0].Reverted)
+	assert.NotZero(t, results[0].GasUsed)
+	assert.Equal(t, hexutil.Encode(thor.Bytes32{}.Bytes()), results[1].Data)
+
+	// block context override
+	number := uint32(1234)
+	code, res = post(t, router, "/accounts/*", &BatchCallData{
+		Clauses:      Clauses{{To: &numberAddr}},
+		BlockContext: &BlockContextOption{Number: &number},
+	})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	results = nil
+	assert.Nil(t, json.Unmarshal(res, &results))
+	assert.Equal(t, hexutil.Encode(thor.BytesToBytes32(big.NewInt(1234).Bytes()).Bytes()), results[0].Data)
+
+	// stops at the reverted clause
+	code, res = post(t, router, "/accounts/*?revision=next", &BatchCallData{
+		Clauses: Clauses{
+			{To: &revertAddr},
+			{To: &storeAddr},
+		},
+	})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	results = nil
+	assert.Nil(t, json.Unmarshal(res, &results))
+	assert.Equal(t, 1, len(results))
+	assert.True(t, results[0].Reverted)
+	assert.Equal(t, "execution reverted", results[0].VMError)
+	assert.Equal(t, "boom", results[0].RevertReason)
+
+	// the caller has no balance to transfer
+	code, res = post(t, router, "/accounts/*", &BatchCallData{
+		Clauses: Clauses{{To: &storeAddr, Value: (*math.HexOrDecimal256)(big.NewInt(1))}},
+	})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	results = nil
+	assert.Nil(t, json.Unmarshal(res, &results))
+	assert.True(t, results[0].Reverted)
+	assert.NotEmpty(t, results[0].VMError)
+
+	code, _ = post(t, router, "/accounts/*", &BatchCallData{Gas: callGasLimit + 1})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = post(t, router, "/accounts/*", &BatchCallData{Clauses: Clauses{{To: &storeAddr, Data: "0xzz"}}})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = post(t, router, "/accounts/*?revision=0xzz", &BatchCallData{})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestEstimateGas(t *testing.T) {
+	router := newTestRouter(t)
+
+	clauses := Clauses{{To: &storeAddr}, {To: &numberAddr}}
+	code, res := post(t, router, "/accounts/*?estimateGas=true", &BatchCallData{Clauses: clauses})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var result EstimateGasResult
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.False(t, result.Reverted)
+
+	intrinsicGas, err := tx.IntrinsicGas(tx.NewClause(&storeAddr), tx.NewClause(&numberAddr))
+	assert.Nil(t, err)
+	assert.True(t, result.Gas > intrinsicGas)
+
+	// the estimated gas is exactly enough
+	var results BatchCallResults
+	code, res = post(t, router, "/accounts/*", &BatchCallData{Clauses: clauses, Gas: result.Gas - intrinsicGas})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Nil(t, json.Unmarshal(res, &results))
+	assert.False(t, results[len(results)-1].Reverted)
+
+	results = nil
+	code, res = post(t, router, "/accounts/*", &BatchCallData{Clauses: clauses, Gas: result.Gas - intrinsicGas - 1})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Nil(t, json.Unmarshal(res, &results))
+	assert.True(t, results[len(results)-1].Reverted)
+
+	// no execution gas required
+	code, res = post(t, router, "/accounts/*?estimateGas=true", &BatchCallData{Clauses: Clauses{{To: &thor.Address{}}}})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	result = EstimateGasResult{}
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.Equal(t, thor.TxGas+thor.ClauseGas, result.Gas)
+
+	code, res = post(t, router, "/accounts/*?estimateGas=true", &BatchCallData{Clauses: Clauses{{To: &revertAddr}}})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	result = EstimateGasResult{}
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.True(t, result.Reverted)
+	assert.Equal(t, "boom", result.RevertReason)
+
+	code, _ = post(t, router, "/accounts/*?estimateGas=maybe", &BatchCallData{})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
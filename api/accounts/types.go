@@ -0,0 +1,157 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package accounts
+
+import (
+	"github.com/ashkanabbasii/thor/runtime"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Account for marshal account.
+type Account struct {
+	Balance math.HexOrDecimal256 `json:"balance"`
+	Energy  math.HexOrDecimal256 `json:"energy"`
+	HasCode bool                 `json:"hasCode"`
+	Proof   *Proof               `json:"proof,omitempty"`
+}
+
+// GetCodeResult the code of an account.
+type GetCodeResult struct {
+	Code  string `json:"code"`
+	Proof *Proof `json:"proof,omitempty"`
+}
+
+// GetStorageResult the value of a storage slot.
+type GetStorageResult struct {
+	Value string `json:"value"`
+	Proof *Proof `json:"proof,omitempty"`
+}
+
+// Proof the merkle proofs against the state root of the revision.
+// The storage proof is against the storage root contained in the proved account.
+type Proof struct {
+	StateRoot thor.Bytes32    `json:"stateRoot"`
+	Account   []hexutil.Bytes `json:"account"`
+	Storage   []hexutil.Bytes `json:"storage,omitempty"`
+}
+
+// Clause for json marshal.
+type Clause struct {
+	To    *thor.Address         `json:"to"`
+	Value *math.HexOrDecimal256 `json:"value"`
+	Data  string                `json:"data"`
+}
+
+// Clauses array of clauses.
+type Clauses []Clause
+
+// BlockContextOption overrides the context of the block the clauses executed in.
+type BlockContextOption struct {
+	Number      *uint32       `json:"number"`
+	Timestamp   *uint64       `json:"timestamp"`
+	GasLimit    *uint64       `json:"gasLimit"`
+	TotalScore  *uint64       `json:"totalScore"`
+	Beneficiary *thor.Address `json:"beneficiary"`
+	Signer      *thor.Address `json:"signer"`
+}
+
+// BatchCallData executes a batch of codes.
+type BatchCallData struct {
+	Clauses      Clauses               `json:"clauses"`
+	Gas          uint64                `json:"gas"`
+	GasPrice     *math.HexOrDecimal256 `json:"gasPrice"`
+	ProvedWork   *math.HexOrDecimal256 `json:"provedWork"`
+	Caller       *thor.Address         `json:"caller"`
+	GasPayer     *thor.Address         `json:"gasPayer"`
+	Expiration   uint32                `json:"expiration"`
+	BlockRef     string                `json:"blockRef"`
+	BlockContext *BlockContextOption   `json:"blockContext"`
+}
+
+// Event event.
+type Event struct {
+	Address thor.Address   `json:"address"`
+	Topics  []thor.Bytes32 `json:"topics"`
+	Data    string         `json:"data"`
+}
+
+// Transfer transfer log.
+type Transfer struct {
+	Sender    thor.Address          `json:"sender"`
+	Recipient thor.Address          `json:"recipient"`
+	Amount    *math.HexOrDecimal256 `json:"amount"`
+}
+
+// CallResult the result of a clause execution.
+type CallResult struct {
+	Data         string      `json:"data"`
+	Events       []*Event    `json:"events"`
+	Transfers    []*Transfer `json:"transfers"`
+	GasUsed      uint64      `json:"gasUsed"`
+	Reverted     bool        `json:"reverted"`
+	VMError      string      `json:"vmError"`
+	RevertReason string      `json:"revertReason,omitempty"`
+}
+
+// BatchCallResults the results of a batch call.
+type BatchCallResults []*CallResult
+
+// EstimateGasResult the estimated gas of a batch call.
+type EstimateGasResult struct {
+	Gas          uint64 `json:"gas"`
+	Reverted     bool   `json:"reverted"`
+	VMError      string `json:"vmError"`
+	RevertReason string `json:"revertReason,omitempty"`
+}
+
+func convertCallResultWithInputGas(output *runtime.Output, inputGas uint64) *CallResult {
+	gasUsed := inputGas - output.LeftOverGas
+	var (
+		vmError      string
+		revertReason string
+	)
+	if output.VMErr != nil {
+		vmError = output.VMErr.Error()
+		if output.VMErr == vm.ErrExecutionReverted {
+			if reason, err := abi.UnpackRevert(output.Data); err == nil {
+				revertReason = reason
+			}
+		}
+	}
+
+	events := make([]*Event, len(output.Events))
+	for i, txEvent := range output.Events {
+		event := &Event{
+			Address: txEvent.Address,
+			Data:    hexutil.Encode(txEvent.Data),
+		}
+		event.Topics = make([]thor.Bytes32, len(txEvent.Topics))
+		copy(event.Topics, txEvent.Topics)
+		events[i] = event
+	}
+	transfers := make([]*Transfer, len(output.Transfers))
+	for i, txTransfer := range output.Transfers {
+		transfers[i] = &Transfer{
+			Sender:    txTransfer.Sender,
+			Recipient: txTransfer.Recipient,
+			Amount:    (*math.HexOrDecimal256)(txTransfer.Amount),
+		}
+	}
+
+	return &CallResult{
+		Data:         hexutil.Encode(output.Data),
+		Events:       events,
+		Transfers:    transfers,
+		GasUsed:      gasUsed,
+		Reverted:     output.VMErr != nil,
+		VMError:      vmError,
+		RevertReason: revertReason,
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package blocks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type committer struct {
+	finalized thor.Bytes32
+}
+
+func (c *committer) Finalized() thor.Bytes32          { return c.finalized }
+func (c *committer) Justified() (thor.Bytes32, error) { return c.finalized, nil }
+
+func newTestRouter(t *testing.T) (*chain.Repository, *mux.Router) {
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(muxdb.NewMem(), genesis)
+	assert.Nil(t, err)
+
+	key, _ := crypto.GenerateKey()
+	parent := genesis.Header()
+	for i := 0; i < 2; i++ {
+		blk := new(block.Builder).
+			ParentID(parent.ID()).
+			Timestamp(parent.Timestamp() + thor.BlockInterval).
+			TotalScore(parent.TotalScore() + 1).
+			GasLimit(parent.GasLimit()).
+			ReceiptsRoot(tx.Receipts(nil).RootHash()).
+			Build()
+		sig, err := crypto.Sign(blk.Header().SigningHash().Bytes(), key)
+		assert.Nil(t, err)
+		blk = blk.WithSignature(sig)
+		assert.Nil(t, repo.AddBlock(blk, nil, 0))
+		assert.Nil(t, repo.SetBestBlockID(blk.Header().ID()))
+		parent = blk.Header()
+	}
+
+	router := mux.NewRouter()
+	New(repo, &committer{genesis.Header().ID()}).Mount(router, "/blocks")
+	return repo, router
+}
+
+func get(t *testing.T, router *mux.Router, path string) (int, []byte) {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	return rr.Code, rr.Body.Bytes()
+}
+
+func TestGetBlock(t *testing.T) {
+	repo, router := newTestRouter(t)
+	best := repo.BestBlockSummary().Header
+
+	code, res := get(t, router, "/blocks/best")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var blk JSONCollapsedBlock
+	assert.Nil(t, json.Unmarshal(res, &blk))
+	assert.Equal(t, best.ID(), blk.ID)
+	assert.Equal(t, uint32(2), blk.Number)
+	assert.True(t, blk.IsTrunk)
+	assert.False(t, blk.IsFinalized)
+	assert.Empty(t, blk.Transactions)
+
+	code, res = get(t, router, "/blocks/0?expanded=true")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var expanded JSONExpandedBlock
+	assert.Nil(t, json.Unmarshal(res, &expanded))
+	assert.Equal(t, repo.GenesisBlock().Header().ID(), expanded.ID)
+	assert.True(t, expanded.IsFinalized)
+
+	code, res = get(t, router, "/blocks/1?raw=true")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var raw JSONRawBlockSummary
+	assert.Nil(t, json.Unmarshal(res, &raw))
+	var header block.Header
+	assert.Nil(t, rlp.DecodeBytes(hexutil.MustDecode(raw.Raw), &header))
+	assert.Equal(t, best.ParentID(), header.ID())
+	signer, err := header.Signer()
+	assert.Nil(t, err)
+	assert.False(t, signer.IsZero())
+
+	// not found
+	code, res = get(t, router, "/blocks/100")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "null", string(res[:4]))
+
+	for _, path := range []string{"/blocks/0xzz", "/blocks/best?expanded=1", "/blocks/best?raw=1", "/blocks/best?raw=true&expanded=true"} {
+		code, _ = get(t, router, path)
+		assert.Equal(t, http.StatusBadRequest, code, path)
+	}
+}
@@ -33,6 +33,11 @@ type JSONBlockSummary struct {
 	IsFinalized  bool         `json:"isFinalized"`
 }
 
+// JSONRawBlockSummary is the rlp encoded block header.
+type JSONRawBlockSummary struct {
+	Raw string `json:"raw"`
+}
+
 type JSONCollapsedBlock struct {
 	*JSONBlockSummary
 	Transactions []thor.Bytes32 `json:"transactions"`
@@ -0,0 +1,455 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package debug
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ashkanabbasii/thor/api/utils"
+	"github.com/ashkanabbasii/thor/bft"
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/runtime"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tracers"
+	"github.com/ashkanabbasii/thor/trie"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/xenv"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	// register the bundled tracers
+	_ "github.com/ashkanabbasii/thor/tracers/logger"
+	_ "github.com/ashkanabbasii/thor/tracers/native"
+)
+
+const (
+	// traceTimeout limits the time spent on a single trace request.
+	traceTimeout = 5 * time.Second
+	// defaultMaxStorageResult the default and maximum page size of storage range requests.
+	defaultMaxStorageResult = 1000
+)
+
+type Debug struct {
+	repo         *chain.Repository
+	stater       *state.Stater
+	forkConfig   thor.ForkConfig
+	callGasLimit uint64
+	bft          bft.Committer
+}
+
+func New(repo *chain.Repository, stater *state.Stater, forkConfig thor.ForkConfig, callGasLimit uint64, bft bft.Committer) *Debug {
+	return &Debug{
+		repo,
+		stater,
+		forkConfig,
+		callGasLimit,
+		bft,
+	}
+}
+
+// prepareClauseEnv replays the block up to the target clause, and returns the
+// runtime and the executor of the target tx with the target clause as the next one.
+func (d *Debug) prepareClauseEnv(ctx context.Context, blockID thor.Bytes32, txIndex uint64, clauseIndex uint32) (*runtime.Runtime, *runtime.TransactionExecutor, thor.Bytes32, error) {
+	blk, err := d.repo.GetBlock(blockID)
+	if err != nil {
+		if d.repo.IsNotFound(err) {
+			return nil, nil, thor.Bytes32{}, utils.Forbidden(errors.New("block not found"))
+		}
+		return nil, nil, thor.Bytes32{}, err
+	}
+	txs := blk.Transactions()
+	if txIndex >= uint64(len(txs)) {
+		return nil, nil, thor.Bytes32{}, utils.Forbidden(errors.New("tx index out of range"))
+	}
+	txID := txs[txIndex].ID()
+	if clauseIndex >= uint32(len(txs[txIndex].Clauses())) {
+		return nil, nil, thor.Bytes32{}, utils.Forbidden(errors.New("clause index out of range"))
+	}
+
+	parent, err := d.repo.GetBlockSummary(blk.Header().ParentID())
+	if err != nil {
+		return nil, nil, thor.Bytes32{}, err
+	}
+	st := d.stater.NewState(parent.Header.StateRoot(), parent.Header.Number(), parent.Conflicts, parent.SteadyNum)
+	rt := d.newRuntime(blk.Header(), st)
+
+	for i, tx := range txs[:txIndex+1] {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, thor.Bytes32{}, err
+		}
+		txExec, err := rt.PrepareTransaction(tx)
+		if err != nil {
+			return nil, nil, thor.Bytes32{}, err
+		}
+		clauseCounter := uint32(0)
+		for txExec.HasNextClause() {
+			if txIndex == uint64(i) && clauseIndex == clauseCounter {
+				return rt, txExec, txID, nil
+			}
+			exec, _, err := txExec.PrepareNext()
+			if err != nil {
+				return nil, nil, thor.Bytes32{}, err
+			}
+			if _, _, err := exec(); err != nil {
+				return nil, nil, thor.Bytes32{}, err
+			}
+			clauseCounter++
+		}
+		if _, err := txExec.Finalize(); err != nil {
+			return nil, nil, thor.Bytes32{}, err
+		}
+	}
+	// the tx reverted before reaching the target clause
+	return nil, nil, thor.Bytes32{}, utils.Forbidden(errors.New("early reverted"))
+}
+
+// newRuntime creates a runtime to execute clauses in the context of the given block header.
+func (d *Debug) newRuntime(header *block.Header, st *state.State) *runtime.Runtime {
+	// the signer of a mocked "next" block is not available, leave it as zero
+	signer, _ := header.Signer()
+
+	return runtime.New(
+		d.repo.NewChain(header.ParentID()),
+		st,
+		&xenv.BlockContext{
+			Beneficiary: header.Beneficiary(),
+			Signer:      signer,
+			Number:      header.Number(),
+			Time:        header.Timestamp(),
+			GasLimit:    header.GasLimit(),
+			TotalScore:  header.TotalScore(),
+		},
+		d.forkConfig)
+}
+
+// runTraced executes the prepared clause with the tracer attached, and returns the trace result.
+func runTraced(ctx context.Context, tracer *tracers.Tracer, exec func() error, interrupt func()) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, traceTimeout)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		if err := ctx.Err(); errors.Is(err, context.DeadlineExceeded) {
+			tracer.Stop(errors.New("execution timeout"))
+			interrupt()
+		}
+	}()
+
+	if err := exec(); err != nil {
+		return nil, err
+	}
+	return tracer.GetResult()
+}
+
+func (d *Debug) traceClause(ctx context.Context, opt *TracerOption, blockID thor.Bytes32, txIndex uint64, clauseIndex uint32) (interface{}, error) {
+	rt, txExec, txID, err := d.prepareClauseEnv(ctx, blockID, txIndex, clauseIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	tracer, err := tracers.DefaultDirectory.New(opt.Name, &tracers.Context{
+		BlockID:     blockID,
+		BlockTime:   rt.Context().Time,
+		TxIndex:     int(txIndex),
+		TxID:        txID,
+		ClauseIndex: int(clauseIndex),
+		State:       rt.State(),
+	}, opt.Config)
+	if err != nil {
+		return nil, utils.BadRequest(errors.WithMessage(err, "name"))
+	}
+	rt.SetVMConfig(vm.Config{Tracer: tracer.Hooks})
+
+	exec, interrupt, err := txExec.PrepareNext()
+	if err != nil {
+		return nil, err
+	}
+	return runTraced(ctx, tracer, func() error {
+		_, _, err := exec()
+		return err
+	}, interrupt)
+}
+
+func (d *Debug) handleTraceClause(w http.ResponseWriter, req *http.Request) error {
+	var opt TraceClauseOption
+	if err := utils.ParseJSON(req.Body, &opt); err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "body"))
+	}
+	blockID, txIndex, clauseIndex, err := d.parseTarget(opt.Target)
+	if err != nil {
+		return err
+	}
+	res, err := d.traceClause(req.Context(), &opt.TracerOption, blockID, txIndex, clauseIndex)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, res)
+}
+
+func (d *Debug) traceCall(ctx context.Context, opt *TracerOption, summary *chain.BlockSummary, st *state.State, txCtx *xenv.TransactionContext, gas uint64, clause *tx.Clause) (interface{}, error) {
+	header := summary.Header
+	tracer, err := tracers.DefaultDirectory.New(opt.Name, &tracers.Context{
+		BlockID:   header.ID(),
+		BlockTime: header.Timestamp(),
+		State:     st,
+	}, opt.Config)
+	if err != nil {
+		return nil, utils.BadRequest(errors.WithMessage(err, "name"))
+	}
+
+	rt := d.newRuntime(header, st)
+	rt.SetVMConfig(vm.Config{Tracer: tracer.Hooks})
+
+	exec, interrupt := rt.PrepareClause(clause, 0, gas, txCtx)
+	return runTraced(ctx, tracer, func() error {
+		_, _, err := exec()
+		return err
+	}, interrupt)
+}
+
+func (d *Debug) handleTraceCall(w http.ResponseWriter, req *http.Request) error {
+	var opt TraceCallOption
+	if err := utils.ParseJSON(req.Body, &opt); err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "body"))
+	}
+	revision, err := utils.ParseRevision(req.URL.Query().Get("revision"), true)
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "revision"))
+	}
+	summary, st, err := utils.GetSummaryAndState(revision, d.repo, d.bft, d.stater)
+	if err != nil {
+		if d.repo.IsNotFound(err) {
+			return utils.BadRequest(errors.WithMessage(err, "revision"))
+		}
+		return err
+	}
+
+	txCtx, gas, clause, err := d.handleTraceCallOption(&opt)
+	if err != nil {
+		return err
+	}
+
+	res, err := d.traceCall(req.Context(), &opt.TracerOption, summary, st, txCtx, gas, clause)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, res)
+}
+
+func (d *Debug) handleTraceCallOption(opt *TraceCallOption) (*xenv.TransactionContext, uint64, *tx.Clause, error) {
+	gas := opt.Gas
+	if opt.Gas > d.callGasLimit {
+		return nil, 0, nil, utils.Forbidden(errors.New("gas: exceeds limit"))
+	} else if opt.Gas == 0 {
+		gas = d.callGasLimit
+	}
+
+	txCtx := xenv.TransactionContext{
+		GasPrice:    new(big.Int),
+		ProvedWork:  new(big.Int),
+		Expiration:  opt.Expiration,
+		ClauseCount: 1,
+	}
+	if opt.GasPrice != nil {
+		txCtx.GasPrice = (*big.Int)(opt.GasPrice)
+	}
+	if opt.Caller != nil {
+		txCtx.Origin = *opt.Caller
+	}
+	if opt.GasPayer != nil {
+		txCtx.GasPayer = *opt.GasPayer
+	} else {
+		txCtx.GasPayer = txCtx.Origin
+	}
+	if opt.ProvedWork != nil {
+		txCtx.ProvedWork = (*big.Int)(opt.ProvedWork)
+	}
+	if opt.BlockRef != "" {
+		blockRef, err := hexutil.Decode(opt.BlockRef)
+		if err != nil {
+			return nil, 0, nil, utils.BadRequest(errors.WithMessage(err, "blockRef"))
+		}
+		if len(blockRef) != len(txCtx.BlockRef) {
+			return nil, 0, nil, utils.BadRequest(errors.New("blockRef: invalid length"))
+		}
+		copy(txCtx.BlockRef[:], blockRef)
+	}
+
+	var value *big.Int
+	if opt.Value == nil {
+		value = new(big.Int)
+	} else {
+		value = (*big.Int)(opt.Value)
+	}
+	var data []byte
+	if opt.Data != "" {
+		var err error
+		data, err = hexutil.Decode(opt.Data)
+		if err != nil {
+			return nil, 0, nil, utils.BadRequest(errors.WithMessage(err, "data"))
+		}
+	}
+	clause := tx.NewClause(opt.To).WithValue(value).WithData(data)
+	return &txCtx, gas, clause, nil
+}
+
+func (d *Debug) debugStorage(ctx context.Context, contractAddress thor.Address, blockID thor.Bytes32, txIndex uint64, clauseIndex uint32, keyStart []byte, maxResult int) (*StorageRangeResult, error) {
+	rt, txExec, _, err := d.prepareClauseEnv(ctx, blockID, txIndex, clauseIndex)
+	if err != nil {
+		return nil, err
+	}
+	// execute the target clause, the storage is inspected after it
+	exec, _, err := txExec.PrepareNext()
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := exec(); err != nil {
+		return nil, err
+	}
+
+	storageTrie, err := rt.State().BuildStorageTrie(contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	return storageRangeAt(storageTrie, keyStart, maxResult)
+}
+
+// storageRangeAt iterates the storage trie from the given hashed key, and collects at most maxResult entries.
+func storageRangeAt(t *muxdb.Trie, start []byte, maxResult int) (*StorageRangeResult, error) {
+	it := trie.NewIterator(t.NodeIterator(start))
+
+	result := StorageRangeResult{Storage: StorageMap{}}
+	for i := 0; i < maxResult && it.Next(); i++ {
+		_, content, _, err := rlp.Split(it.Value)
+		if err != nil {
+			return nil, err
+		}
+		v := thor.BytesToBytes32(content)
+		e := StorageEntry{Value: &v}
+		if len(it.Meta) > 0 {
+			preimage := thor.BytesToBytes32(it.Meta)
+			e.Key = &preimage
+		}
+		result.Storage[thor.BytesToBytes32(it.Key).String()] = e
+	}
+	if it.Next() {
+		next := thor.BytesToBytes32(it.Key)
+		result.NextKey = &next
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	return &result, nil
+}
+
+func (d *Debug) handleDebugStorage(w http.ResponseWriter, req *http.Request) error {
+	var opt StorageRangeOption
+	if err := utils.ParseJSON(req.Body, &opt); err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "body"))
+	}
+	if opt.Address == nil {
+		return utils.BadRequest(errors.New("address: empty"))
+	}
+
+	var keyStart []byte
+	if opt.KeyStart != "" {
+		k, err := thor.ParseBytes32(opt.KeyStart)
+		if err != nil {
+			return utils.BadRequest(errors.WithMessage(err, "keyStart"))
+		}
+		keyStart = k.Bytes()
+	}
+
+	maxResult := opt.MaxResult
+	if maxResult == 0 {
+		maxResult = defaultMaxStorageResult
+	} else if maxResult < 0 || maxResult > defaultMaxStorageResult {
+		return utils.BadRequest(errors.New("maxResult: out of range"))
+	}
+
+	blockID, txIndex, clauseIndex, err := d.parseTarget(opt.Target)
+	if err != nil {
+		return err
+	}
+	res, err := d.debugStorage(req.Context(), *opt.Address, blockID, txIndex, clauseIndex, keyStart, maxResult)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, res)
+}
+
+// parseTarget parses the target in the form of `blockID/(txIndex|txID)/clauseIndex`.
+func (d *Debug) parseTarget(target string) (blockID thor.Bytes32, txIndex uint64, clauseIndex uint32, err error) {
+	// target example: 0x0000000054a8b3d1d7ed6a1b1dffac3a0dc4a71cbd8568357ff1fe80d9b5f5b2/0x99c94ba0c1c5d0d8ab2f3b5e3f4b69a19e06fd4d6d0e1e4ef6ee2ab31e38eb40/0
+	parts := strings.Split(target, "/")
+	if len(parts) != 3 {
+		return thor.Bytes32{}, 0, 0, utils.BadRequest(errors.New("target: unsupported"))
+	}
+	blockID, err = thor.ParseBytes32(parts[0])
+	if err != nil {
+		return thor.Bytes32{}, 0, 0, utils.BadRequest(errors.WithMessage(err, "target[0]"))
+	}
+	if len(parts[1]) == 64 || len(parts[1]) == 66 {
+		txID, err := thor.ParseBytes32(parts[1])
+		if err != nil {
+			return thor.Bytes32{}, 0, 0, utils.BadRequest(errors.WithMessage(err, "target[1]"))
+		}
+
+		txMeta, err := d.repo.NewChain(blockID).GetTransactionMeta(txID)
+		if err != nil {
+			if d.repo.IsNotFound(err) {
+				return thor.Bytes32{}, 0, 0, utils.Forbidden(errors.New("transaction not found"))
+			}
+			return thor.Bytes32{}, 0, 0, err
+		}
+		if txMeta.BlockID != blockID {
+			return thor.Bytes32{}, 0, 0, utils.Forbidden(errors.New("transaction not found in block"))
+		}
+		txIndex = txMeta.Index
+	} else {
+		i, err := strconv.ParseUint(parts[1], 0, 0)
+		if err != nil {
+			return thor.Bytes32{}, 0, 0, utils.BadRequest(errors.WithMessage(err, "target[1]"))
+		}
+		txIndex = i
+	}
+	i, err := strconv.ParseUint(parts[2], 0, 0)
+	if err != nil {
+		return thor.Bytes32{}, 0, 0, utils.BadRequest(errors.WithMessage(err, "target[2]"))
+	} else if i > uint64(^uint32(0)) {
+		return thor.Bytes32{}, 0, 0, utils.BadRequest(errors.New("invalid target[2]"))
+	}
+	clauseIndex = uint32(i)
+	return
+}
+
+func (d *Debug) Mount(root *mux.Router, pathPrefix string) {
+	sub := root.PathPrefix(pathPrefix).Subrouter()
+
+	sub.Path("/tracers").
+		Methods(http.MethodPost).
+		Name("debug_trace_clause").
+		HandlerFunc(utils.WrapHandlerFunc(d.handleTraceClause))
+	sub.Path("/tracers/call").
+		Methods(http.MethodPost).
+		Name("debug_trace_call").
+		HandlerFunc(utils.WrapHandlerFunc(d.handleTraceCall))
+	sub.Path("/storage-range").
+		Methods(http.MethodPost).
+		Name("debug_trace_storage").
+		HandlerFunc(utils.WrapHandlerFunc(d.handleDebugStorage))
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package debug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/runtime"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/xenv"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// deploys a contract, whose runtime code stores 1 at slot 0.
+var deployCode = hexutil.MustDecode("0x600680600b6000396000f3600160005500")
+
+type committer struct {
+	finalized thor.Bytes32
+}
+
+func (c *committer) Finalized() thor.Bytes32          { return c.finalized }
+func (c *committer) Justified() (thor.Bytes32, error) { return c.finalized, nil }
+
+// newTestEnv creates a chain with a genesis block and a block which contains
+// a tx deploying the test contract and a tx calling it, it returns the router and the block.
+func newTestEnv(t *testing.T) (*mux.Router, *block.Block, thor.Address) {
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+
+	key, _ := crypto.GenerateKey()
+	origin := thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+
+	st := stater.NewState(thor.Bytes32{}, 0, 0, 0)
+	st.SetEnergy(origin, new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6)), 0)
+	stage, err := st.Stage(0, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+
+	trx := tx.MustSign(new(tx.Builder).
+		ChainTag(repo.ChainTag()).
+		Clause(tx.NewClause(nil).WithData(deployCode)).
+		Gas(100000).
+		Expiration(32).
+		Build(), key)
+
+	st = stater.NewState(root, 0, 0, 0)
+	rt := runtime.New(repo.NewChain(genesis.Header().ID()), st, &xenv.BlockContext{
+		Number:   1,
+		Time:     thor.BlockInterval,
+		GasLimit: thor.InitialGasLimit,
+	}, thor.NoFork)
+	execTx := func(trx *tx.Transaction) (*tx.Receipt, *runtime.Output) {
+		txExec, err := rt.PrepareTransaction(trx)
+		assert.Nil(t, err)
+		exec, _, err := txExec.PrepareNext()
+		assert.Nil(t, err)
+		_, output, err := exec()
+		assert.Nil(t, err)
+		receipt, err := txExec.Finalize()
+		assert.Nil(t, err)
+		assert.False(t, receipt.Reverted)
+		return receipt, output
+	}
+	receipt, output := execTx(trx)
+	contract := *output.ContractAddress
+
+	// calls the contract to set its storage
+	callTx := tx.MustSign(new(tx.Builder).
+		ChainTag(repo.ChainTag()).
+		Clause(tx.NewClause(&contract)).
+		Gas(100000).
+		Expiration(32).
+		Nonce(1).
+		Build(), key)
+	callReceipt, _ := execTx(callTx)
+
+	stage, err = st.Stage(1, 0)
+	assert.Nil(t, err)
+	root, err = stage.Commit()
+	assert.Nil(t, err)
+
+	blk := new(block.Builder).
+		ParentID(genesis.Header().ID()).
+		Timestamp(thor.BlockInterval).
+		GasLimit(thor.InitialGasLimit).
+		GasUsed(receipt.GasUsed + callReceipt.GasUsed).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts{receipt, callReceipt}.RootHash()).
+		Transaction(trx).
+		Transaction(callTx).
+		Build()
+	assert.Nil(t, repo.AddBlock(blk, tx.Receipts{receipt, callReceipt}, 0))
+	assert.Nil(t, repo.SetBestBlockID(blk.Header().ID()))
+
+	router := mux.NewRouter()
+	New(repo, stater, thor.NoFork, 10_000_000, &committer{genesis.Header().ID()}).Mount(router, "/debug")
+
+	return router, blk, contract
+}
+
+func post(t *testing.T, router *mux.Router, path string, body interface{}) (int, []byte) {
+	data, err := json.Marshal(body)
+	assert.Nil(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.Bytes()
+}
+
+func TestTraceClause(t *testing.T) {
+	router, blk, _ := newTestEnv(t)
+	target := fmt.Sprintf("%v/0/0", blk.Header().ID())
+
+	code, res := post(t, router, "/debug/tracers", map[string]interface{}{"name": "call", "target": target})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var frame map[string]interface{}
+	assert.Nil(t, json.Unmarshal(res, &frame))
+	assert.Equal(t, "CREATE", frame["type"])
+
+	// default struct logger
+	code, res = post(t, router, "/debug/tracers", map[string]interface{}{"target": target})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var result struct {
+		Failed     bool
+		StructLogs []map[string]interface{}
+	}
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.False(t, result.Failed)
+	assert.NotEmpty(t, result.StructLogs)
+
+	// target by tx id
+	code, _ = post(t, router, "/debug/tracers", map[string]interface{}{
+		"name":   "4byte",
+		"target": fmt.Sprintf("%v/%v/0", blk.Header().ID(), blk.Transactions()[0].ID()),
+	})
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = post(t, router, "/debug/tracers", map[string]interface{}{"name": "unknown", "target": target})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = post(t, router, "/debug/tracers", map[string]interface{}{"target": "0x00/0"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = post(t, router, "/debug/tracers", map[string]interface{}{"target": fmt.Sprintf("%v/2/0", blk.Header().ID())})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = post(t, router, "/debug/tracers", map[string]interface{}{"target": fmt.Sprintf("%v/0/1", blk.Header().ID())})
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestTraceCall(t *testing.T) {
+	router, _, contract := newTestEnv(t)
+
+	code, res := post(t, router, "/debug/tracers/call", map[string]interface{}{"name": "prestate", "to": contract.String()})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var pre map[common.Address]struct {
+		Code    hexutil.Bytes
+		Storage map[common.Hash]common.Hash
+	}
+	assert.Nil(t, json.Unmarshal(res, &pre))
+	assert.Equal(t, hexutil.Bytes(deployCode[11:]), pre[common.Address(contract)].Code)
+	assert.Equal(t, common.BytesToHash([]byte{1}), pre[common.Address(contract)].Storage[common.Hash{}])
+
+	code, res = post(t, router, "/debug/tracers/call?revision=next", map[string]interface{}{"name": "call", "to": contract.String(), "value": "0x0"})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var frame map[string]interface{}
+	assert.Nil(t, json.Unmarshal(res, &frame))
+	assert.Equal(t, "CALL", frame["type"])
+	assert.Equal(t, contract.String(), frame["to"])
+
+	code, _ = post(t, router, "/debug/tracers/call", map[string]interface{}{"name": "call", "to": contract.String(), "gas": 10_000_001})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = post(t, router, "/debug/tracers/call", map[string]interface{}{"name": "call", "data": "0xzz"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestStorageRange(t *testing.T) {
+	router, blk, contract := newTestEnv(t)
+
+	// after the deploying clause, the storage is empty
+	code, res := post(t, router, "/debug/storage-range", map[string]interface{}{
+		"address": contract.String(),
+		"target":  fmt.Sprintf("%v/0/0", blk.Header().ID()),
+	})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var result StorageRangeResult
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.Empty(t, result.Storage)
+	assert.Nil(t, result.NextKey)
+
+	// after the calling clause, slot 0 is set
+	code, res = post(t, router, "/debug/storage-range", map[string]interface{}{
+		"address":   contract.String(),
+		"target":    fmt.Sprintf("%v/1/0", blk.Header().ID()),
+		"maxResult": 10,
+	})
+	assert.Equal(t, http.StatusOK, code, string(res))
+	result = StorageRangeResult{}
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.Equal(t, StorageMap{
+		thor.Blake2b(thor.Bytes32{}.Bytes()).String(): {
+			Key:   &thor.Bytes32{},
+			Value: &thor.Bytes32{31: 1},
+		},
+	}, result.Storage)
+	assert.Nil(t, result.NextKey)
+
+	code, _ = post(t, router, "/debug/storage-range", map[string]interface{}{"target": fmt.Sprintf("%v/1/0", blk.Header().ID())})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = post(t, router, "/debug/storage-range", map[string]interface{}{
+		"address":  contract.String(),
+		"keyStart": "0xzz",
+		"target":   fmt.Sprintf("%v/1/0", blk.Header().ID()),
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = post(t, router, "/debug/storage-range", map[string]interface{}{
+		"address":   contract.String(),
+		"maxResult": 1001,
+		"target":    fmt.Sprintf("%v/1/0", blk.Header().ID()),
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
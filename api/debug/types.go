@@ -0,0 +1,63 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package debug
+
+import (
+	"encoding/json"
+
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// TracerOption selects the tracer and its configuration.
+type TracerOption struct {
+	Name   string          `json:"name"`
+	Config json.RawMessage `json:"config"`
+}
+
+// TraceClauseOption the option to trace a clause of a mined transaction.
+type TraceClauseOption struct {
+	TracerOption
+	Target string `json:"target"`
+}
+
+// TraceCallOption the option to trace a simulated call.
+type TraceCallOption struct {
+	TracerOption
+	To         *thor.Address         `json:"to"`
+	Value      *math.HexOrDecimal256 `json:"value"`
+	Data       string                `json:"data"`
+	Gas        uint64                `json:"gas"`
+	GasPrice   *math.HexOrDecimal256 `json:"gasPrice"`
+	ProvedWork *math.HexOrDecimal256 `json:"provedWork"`
+	Caller     *thor.Address         `json:"caller"`
+	GasPayer   *thor.Address         `json:"gasPayer"`
+	Expiration uint32                `json:"expiration"`
+	BlockRef   string                `json:"blockRef"`
+}
+
+// StorageRangeOption the option to page through the storage of an account.
+type StorageRangeOption struct {
+	Address   *thor.Address `json:"address"`
+	KeyStart  string        `json:"keyStart"`
+	MaxResult int           `json:"maxResult"`
+	Target    string        `json:"target"`
+}
+
+// StorageRangeResult a page of storage entries, and the hashed key to continue from.
+type StorageRangeResult struct {
+	Storage StorageMap    `json:"storage"`
+	NextKey *thor.Bytes32 `json:"nextKey"`
+}
+
+// StorageMap maps hashed storage keys to entries.
+type StorageMap map[string]StorageEntry
+
+// StorageEntry a storage slot, the key is nil if its preimage is unknown.
+type StorageEntry struct {
+	Key   *thor.Bytes32 `json:"key"`
+	Value *thor.Bytes32 `json:"value"`
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package node
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ashkanabbasii/thor/api/utils"
+	"github.com/ashkanabbasii/thor/bft"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultFinalityRounds = 5
+	maxFinalityRounds     = 100
+)
+
+// FinalityReporter is implemented by the bft engine.
+type FinalityReporter interface {
+	Status(rounds int) (*bft.Status, error)
+	ProveFinality(blockID thor.Bytes32) (*bft.FinalityProof, error)
+}
+
+type Node struct {
+	repo       *chain.Repository
+	forkConfig thor.ForkConfig
+	bft        FinalityReporter
+}
+
+// New creates the node api, the finality endpoints are mounted only if bft is not nil.
+func New(repo *chain.Repository, forkConfig thor.ForkConfig, bft FinalityReporter) *Node {
+	return &Node{
+		repo,
+		forkConfig,
+		bft,
+	}
+}
+
+func (n *Node) handleGetForks(w http.ResponseWriter, _ *http.Request) error {
+	return utils.WriteJSON(w, convertForks(n.repo.GenesisBlock().Header().ID(), n.forkConfig))
+}
+
+func (n *Node) handleGetFinality(w http.ResponseWriter, req *http.Request) error {
+	rounds := defaultFinalityRounds
+	if s := req.URL.Query().Get("rounds"); s != "" {
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return utils.BadRequest(errors.WithMessage(err, "rounds"))
+		}
+		if v > maxFinalityRounds {
+			return utils.BadRequest(errors.Errorf("rounds: exceeds maximum %d", maxFinalityRounds))
+		}
+		rounds = int(v)
+	}
+
+	status, err := n.bft.Status(rounds)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, convertFinality(status))
+}
+
+func (n *Node) handleGetFinalityProof(w http.ResponseWriter, req *http.Request) error {
+	id, err := thor.ParseBytes32(mux.Vars(req)["id"])
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "id"))
+	}
+
+	proof, err := n.bft.ProveFinality(id)
+	if err != nil {
+		if errors.Is(err, bft.ErrNotFinalized) {
+			return utils.BadRequest(errors.WithMessage(err, "id"))
+		}
+		return err
+	}
+	result, err := convertFinalityProof(proof)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, result)
+}
+
+func (n *Node) Mount(root *mux.Router, pathPrefix string) {
+	sub := root.PathPrefix(pathPrefix).Subrouter()
+
+	sub.Path("/forks").
+		Methods(http.MethodGet).
+		Name("node_get_forks").
+		HandlerFunc(utils.WrapHandlerFunc(n.handleGetForks))
+
+	if n.bft != nil {
+		sub.Path("/finality").
+			Methods(http.MethodGet).
+			Name("node_get_finality").
+			HandlerFunc(utils.WrapHandlerFunc(n.handleGetFinality))
+		sub.Path("/finality/proofs/{id}").
+			Methods(http.MethodGet).
+			Name("node_get_finality_proof").
+			HandlerFunc(utils.WrapHandlerFunc(n.handleGetFinalityProof))
+	}
+}
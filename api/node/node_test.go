@@ -0,0 +1,185 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package node
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/bft"
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetForks(t *testing.T) {
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(muxdb.NewMem(), genesis)
+	assert.Nil(t, err)
+
+	fc, err := thor.ParseForkConfig([]byte("VIP191: 0\nETH_CONST: 10\nETH_IST: 10\n"), true)
+	assert.Nil(t, err)
+	assert.Nil(t, thor.RegisterForkConfig(genesis.Header().ID(), fc))
+
+	router := mux.NewRouter()
+	New(repo, thor.GetForkConfig(genesis.Header().ID()), nil).Mount(router, "/node")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/node/forks", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var forks map[string]interface{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &forks))
+	assert.Equal(t, map[string]interface{}{
+		"genesisID": genesis.Header().ID().String(),
+		"VIP191":    float64(0),
+		"ETH_CONST": float64(10),
+		"BLOCKLIST": nil,
+		"ETH_IST":   float64(10),
+		"VIP214":    nil,
+		"FINALITY":  nil,
+	}, forks)
+}
+
+type fakeReporter struct {
+	rounds int
+	proof  *bft.FinalityProof
+}
+
+func (r *fakeReporter) ProveFinality(blockID thor.Bytes32) (*bft.FinalityProof, error) {
+	if r.proof == nil || r.proof.BlockID != blockID {
+		return nil, bft.ErrNotFinalized
+	}
+	return r.proof, nil
+}
+
+func (r *fakeReporter) Status(rounds int) (*bft.Status, error) {
+	r.rounds = rounds
+	return &bft.Status{
+		Finalized: thor.Bytes32{1},
+		Justified: thor.Bytes32{2},
+		Round: bft.RoundStatus{
+			Checkpoint: 540,
+			Quality:    2,
+			Votes:      1,
+			Threshold:  2,
+		},
+		Checkpoints: []bft.CheckpointStatus{
+			{ID: thor.Bytes32{0, 0, 0x1, 0x68}, Quality: 2},
+		},
+	}, nil
+}
+
+func TestGetFinality(t *testing.T) {
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(muxdb.NewMem(), genesis)
+	assert.Nil(t, err)
+
+	// not mounted without bft
+	router := mux.NewRouter()
+	New(repo, thor.NoFork, nil).Mount(router, "/node")
+	for _, path := range []string{"/node/finality", "/node/finality/proofs/" + genesis.Header().ID().String()} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	}
+
+	reporter := &fakeReporter{}
+	router = mux.NewRouter()
+	New(repo, thor.NoFork, reporter).Mount(router, "/node")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/node/finality", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, defaultFinalityRounds, reporter.rounds)
+
+	var finality Finality
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &finality))
+	assert.Equal(t, Finality{
+		Finalized: thor.Bytes32{1},
+		Justified: thor.Bytes32{2},
+		Round: Round{
+			Checkpoint: 540,
+			Quality:    2,
+			Votes:      1,
+			Threshold:  2,
+		},
+		Checkpoints: []*Checkpoint{
+			{ID: thor.Bytes32{0, 0, 0x1, 0x68}, Number: 360, Quality: 2},
+		},
+	}, finality)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/node/finality?rounds=10", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, 10, reporter.rounds)
+
+	for _, rounds := range []string{"abc", "-1", "101"} {
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/node/finality?rounds="+rounds, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, rounds)
+	}
+}
+
+func TestGetFinalityProof(t *testing.T) {
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(muxdb.NewMem(), genesis)
+	assert.Nil(t, err)
+
+	proof := &bft.FinalityProof{
+		BlockID: genesis.Header().ID(),
+		Headers: []*block.Header{genesis.Header()},
+		Nodes:   [][]byte{{1, 2, 3}},
+	}
+	router := mux.NewRouter()
+	New(repo, thor.NoFork, &fakeReporter{proof: proof}).Mount(router, "/node")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/node/finality/proofs/"+genesis.Header().ID().String(), nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var result FinalityProof
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, genesis.Header().ID(), result.BlockID)
+	assert.Len(t, result.Headers, 1)
+	assert.Equal(t, []hexutil.Bytes{{1, 2, 3}}, result.Nodes)
+
+	var decoded bft.FinalityProof
+	assert.Nil(t, rlp.DecodeBytes(result.Raw, &decoded))
+	assert.Equal(t, proof.BlockID, decoded.BlockID)
+	assert.Equal(t, genesis.Header().ID(), decoded.Headers[0].ID())
+	var header block.Header
+	assert.Nil(t, rlp.DecodeBytes(result.Headers[0], &header))
+	assert.Equal(t, genesis.Header().ID(), header.ID())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/node/finality/proofs/"+thor.Bytes32{1}.String(), nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/node/finality/proofs/0xzz", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package node
+
+import (
+	"math"
+
+	"github.com/ashkanabbasii/thor/bft"
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Forks lists activation block numbers of forks, nil for the disabled ones.
+type Forks struct {
+	GenesisID thor.Bytes32 `json:"genesisID"`
+	VIP191    *uint32      `json:"VIP191"`
+	ETH_CONST *uint32      `json:"ETH_CONST"` // nolint: revive
+	BLOCKLIST *uint32      `json:"BLOCKLIST"`
+	ETH_IST   *uint32      `json:"ETH_IST"` // nolint: revive
+	VIP214    *uint32      `json:"VIP214"`
+	FINALITY  *uint32      `json:"FINALITY"`
+}
+
+func convertForks(genesisID thor.Bytes32, fc thor.ForkConfig) *Forks {
+	activation := func(num uint32) *uint32 {
+		if num == math.MaxUint32 {
+			return nil
+		}
+		return &num
+	}
+	return &Forks{
+		GenesisID: genesisID,
+		VIP191:    activation(fc.VIP191),
+		ETH_CONST: activation(fc.ETH_CONST),
+		BLOCKLIST: activation(fc.BLOCKLIST),
+		ETH_IST:   activation(fc.ETH_IST),
+		VIP214:    activation(fc.VIP214),
+		FINALITY:  activation(fc.FINALITY),
+	}
+}
+
+// Finality is the finality status regarding the best block.
+type Finality struct {
+	Finalized   thor.Bytes32  `json:"finalized"`
+	Justified   thor.Bytes32  `json:"justified"`
+	Round       Round         `json:"round"`
+	Checkpoints []*Checkpoint `json:"checkpoints"`
+}
+
+// Round is the vote statistics of the round the best block belongs to.
+type Round struct {
+	Checkpoint uint32 `json:"checkpoint"`
+	Quality    uint32 `json:"quality"`
+	Justified  bool   `json:"justified"`
+	Committed  bool   `json:"committed"`
+	Votes      uint64 `json:"votes"`
+	COMVotes   uint64 `json:"comVotes"`
+	Threshold  uint64 `json:"threshold"`
+}
+
+// Checkpoint is the quality of a concluded round.
+type Checkpoint struct {
+	ID      thor.Bytes32 `json:"id"`
+	Number  uint32       `json:"number"`
+	Quality uint32       `json:"quality"`
+}
+
+func convertFinality(status *bft.Status) *Finality {
+	checkpoints := make([]*Checkpoint, 0, len(status.Checkpoints))
+	for _, cp := range status.Checkpoints {
+		checkpoints = append(checkpoints, &Checkpoint{
+			ID:      cp.ID,
+			Number:  block.Number(cp.ID),
+			Quality: cp.Quality,
+		})
+	}
+	return &Finality{
+		Finalized:   status.Finalized,
+		Justified:   status.Justified,
+		Round:       Round(status.Round),
+		Checkpoints: checkpoints,
+	}
+}
+
+// FinalityProof proves that a block is finalized. Headers are rlp encoded, and nodes are trie nodes
+// proving the proposers against the state roots of the headers.
+type FinalityProof struct {
+	BlockID thor.Bytes32    `json:"blockID"`
+	Headers []hexutil.Bytes `json:"headers"`
+	Nodes   []hexutil.Bytes `json:"nodes"`
+	Raw     hexutil.Bytes   `json:"raw"` // rlp encoded proof
+}
+
+func convertFinalityProof(proof *bft.FinalityProof) (*FinalityProof, error) {
+	raw, err := rlp.EncodeToBytes(proof)
+	if err != nil {
+		return nil, err
+	}
+	result := &FinalityProof{
+		BlockID: proof.BlockID,
+		Headers: make([]hexutil.Bytes, 0, len(proof.Headers)),
+		Nodes:   make([]hexutil.Bytes, 0, len(proof.Nodes)),
+		Raw:     raw,
+	}
+	for _, h := range proof.Headers {
+		enc, err := rlp.EncodeToBytes(h)
+		if err != nil {
+			return nil, err
+		}
+		result.Headers = append(result.Headers, enc)
+	}
+	for _, node := range proof.Nodes {
+		result.Nodes = append(result.Nodes, node)
+	}
+	return result, nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package proposers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ashkanabbasii/thor/api/utils"
+	"github.com/ashkanabbasii/thor/bft"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/liveness"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultScheduleSlots = 10
+	maxScheduleSlots     = 1000
+)
+
+// LivenessReporter is implemented by the liveness tracker.
+type LivenessReporter interface {
+	Record(addr thor.Address) (*liveness.Record, error)
+	Records() ([]*liveness.Record, error)
+}
+
+// Proposers serves information of block proposers.
+type Proposers struct {
+	repo       *chain.Repository
+	stater     *state.Stater
+	seeder     *poa.Seeder
+	forkConfig thor.ForkConfig
+	bft        bft.Committer
+	liveness   LivenessReporter
+}
+
+// New creates the proposers api, the liveness endpoints are mounted only if liveness is not nil.
+func New(
+	repo *chain.Repository,
+	stater *state.Stater,
+	seeder *poa.Seeder,
+	forkConfig thor.ForkConfig,
+	bft bft.Committer,
+	liveness LivenessReporter,
+) *Proposers {
+	return &Proposers{
+		repo,
+		stater,
+		seeder,
+		forkConfig,
+		bft,
+		liveness,
+	}
+}
+
+func (p *Proposers) handleGetSchedule(w http.ResponseWriter, req *http.Request) error {
+	revision, err := utils.ParseRevision(req.URL.Query().Get("revision"), false)
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "revision"))
+	}
+	slots := defaultScheduleSlots
+	if s := req.URL.Query().Get("slots"); s != "" {
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return utils.BadRequest(errors.WithMessage(err, "slots"))
+		}
+		if v > maxScheduleSlots {
+			return utils.BadRequest(errors.Errorf("slots: exceeds maximum %d", maxScheduleSlots))
+		}
+		slots = int(v)
+	}
+
+	summary, err := utils.GetSummary(revision, p.repo, p.bft)
+	if err != nil {
+		if p.repo.IsNotFound(err) {
+			return utils.BadRequest(errors.WithMessage(err, "revision"))
+		}
+		return err
+	}
+	parent := summary.Header
+	if parent.Number()+1 < p.forkConfig.VIP214 {
+		return utils.BadRequest(errors.New("revision: schedule unavailable before VIP214"))
+	}
+
+	st := p.stater.NewState(parent.StateRoot(), parent.Number(), summary.Conflicts, summary.SteadyNum)
+	proposers, err := loadProposers(st)
+	if err != nil {
+		return err
+	}
+	predicted, err := poa.Predict(p.seeder, parent, proposers, slots)
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, convertSchedule(parent, predicted))
+}
+
+func (p *Proposers) handleGetSeed(w http.ResponseWriter, req *http.Request) error {
+	epoch, err := strconv.ParseUint(mux.Vars(req)["epoch"], 10, 32)
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "epoch"))
+	}
+
+	seedID, seed, err := p.seeder.EpochSeed(p.repo.NewBestChain(), uint32(epoch))
+	if err != nil {
+		if p.repo.IsNotFound(err) {
+			return utils.BadRequest(errors.New("epoch: seed not available yet"))
+		}
+		return err
+	}
+	return utils.WriteJSON(w, convertSeed(uint32(epoch), seedID, seed))
+}
+
+func (p *Proposers) handleGetLivenessRecords(w http.ResponseWriter, _ *http.Request) error {
+	records, err := p.liveness.Records()
+	if err != nil {
+		return err
+	}
+	return utils.WriteJSON(w, convertLivenessRecords(records))
+}
+
+func (p *Proposers) handleGetLivenessRecord(w http.ResponseWriter, req *http.Request) error {
+	addr, err := thor.ParseAddress(mux.Vars(req)["address"])
+	if err != nil {
+		return utils.BadRequest(errors.WithMessage(err, "address"))
+	}
+	record, err := p.liveness.Record(addr)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return utils.WriteJSON(w, nil)
+	}
+	return utils.WriteJSON(w, convertLivenessRecord(record))
+}
+
+// loadProposers loads proposers with status from the state.
+func loadProposers(st *state.State) ([]poa.Proposer, error) {
+	list, err := builtin.Authority.Native(st).AllCandidates()
+	if err != nil {
+		return nil, err
+	}
+	proposers, _, err := poa.NewCandidates(list).Pick(st)
+	return proposers, err
+}
+
+func (p *Proposers) Mount(root *mux.Router, pathPrefix string) {
+	sub := root.PathPrefix(pathPrefix).Subrouter()
+
+	sub.Path("/schedule").
+		Methods(http.MethodGet).
+		Name("proposers_get_schedule").
+		HandlerFunc(utils.WrapHandlerFunc(p.handleGetSchedule))
+	sub.Path("/seeds/{epoch}").
+		Methods(http.MethodGet).
+		Name("proposers_get_seed").
+		HandlerFunc(utils.WrapHandlerFunc(p.handleGetSeed))
+
+	if p.liveness != nil {
+		sub.Path("/liveness").
+			Methods(http.MethodGet).
+			Name("proposers_get_liveness_records").
+			HandlerFunc(utils.WrapHandlerFunc(p.handleGetLivenessRecords))
+		sub.Path("/liveness/{address}").
+			Methods(http.MethodGet).
+			Name("proposers_get_liveness_record").
+			HandlerFunc(utils.WrapHandlerFunc(p.handleGetLivenessRecord))
+	}
+}
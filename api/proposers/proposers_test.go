@@ -0,0 +1,186 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package proposers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/genesis"
+	"github.com/ashkanabbasii/thor/liveness"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type committer struct{}
+
+func (committer) Finalized() thor.Bytes32          { return thor.Bytes32{} }
+func (committer) Justified() (thor.Bytes32, error) { return thor.Bytes32{}, nil }
+
+type livenessReporter []*liveness.Record
+
+func (l livenessReporter) Record(addr thor.Address) (*liveness.Record, error) {
+	for _, r := range l {
+		if r.Address == addr {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (l livenessReporter) Records() ([]*liveness.Record, error) { return l, nil }
+
+var (
+	masters = []thor.Address{
+		thor.BytesToAddress([]byte("m1")),
+		thor.BytesToAddress([]byte("m2")),
+		thor.BytesToAddress([]byte("m3")),
+	}
+	// endorsor of the unfunded master has no balance
+	unfunded = thor.BytesToAddress([]byte("m4"))
+)
+
+func newTestRouter(t *testing.T, forkConfig thor.ForkConfig, liveness LivenessReporter) (*chain.Repository, *mux.Router) {
+	balance := (*math.HexOrDecimal256)(new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e9)))
+	gen := &genesis.CustomGenesis{
+		LaunchTime: 1526400000,
+		GasLimit:   thor.InitialGasLimit,
+		ForkConfig: &thor.ForkConfig{},
+	}
+	for _, m := range masters {
+		gen.Authority = append(gen.Authority, genesis.Authority{MasterAddress: m, EndorsorAddress: m})
+		gen.Accounts = append(gen.Accounts, genesis.Account{Address: m, Balance: balance, Energy: balance})
+	}
+	gen.Authority = append(gen.Authority, genesis.Authority{MasterAddress: unfunded, EndorsorAddress: unfunded})
+
+	g, err := genesis.NewCustomNet(gen)
+	assert.Nil(t, err)
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+	b0, _, _, err := g.Build(stater)
+	assert.Nil(t, err)
+	repo, err := chain.NewRepository(db, b0)
+	assert.Nil(t, err)
+
+	router := mux.NewRouter()
+	New(repo, stater, poa.NewSeeder(repo, db), forkConfig, committer{}, liveness).Mount(router, "/proposers")
+	return repo, router
+}
+
+func get(t *testing.T, router *mux.Router, path string) (int, []byte) {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	return rr.Code, rr.Body.Bytes()
+}
+
+func TestGetSchedule(t *testing.T) {
+	repo, router := newTestRouter(t, thor.ForkConfig{}, nil)
+	genesisHeader := repo.GenesisBlock().Header()
+
+	var proposers []poa.Proposer
+	for _, m := range masters {
+		proposers = append(proposers, poa.Proposer{Address: m, Active: true})
+	}
+
+	code, res := get(t, router, "/proposers/schedule?revision=best&slots=5")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var schedule Schedule
+	assert.Nil(t, json.Unmarshal(res, &schedule))
+	assert.Equal(t, genesisHeader.ID(), schedule.ParentID)
+	assert.Equal(t, uint32(0), schedule.ParentNumber)
+	assert.Len(t, schedule.Slots, 5)
+	predicted, err := poa.Predict(poa.NewSeeder(repo, nil), genesisHeader, proposers, 5)
+	assert.Nil(t, err)
+	for i, slot := range predicted {
+		assert.Equal(t, slot.Time, schedule.Slots[i].Timestamp)
+		assert.Equal(t, slot.Proposer, schedule.Slots[i].Proposer)
+		assert.NotEqual(t, unfunded, schedule.Slots[i].Proposer)
+	}
+
+	code, res = get(t, router, "/proposers/schedule")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Nil(t, json.Unmarshal(res, &schedule))
+	assert.Len(t, schedule.Slots, defaultScheduleSlots)
+
+	for _, path := range []string{
+		"/proposers/schedule?slots=1001",
+		"/proposers/schedule?slots=abc",
+		"/proposers/schedule?revision=abc",
+		"/proposers/schedule?revision=1",
+	} {
+		code, _ := get(t, router, path)
+		assert.Equal(t, http.StatusBadRequest, code, path)
+	}
+}
+
+func TestGetScheduleBeforeVIP214(t *testing.T) {
+	_, router := newTestRouter(t, thor.ForkConfig{VIP214: 10}, nil)
+
+	code, res := get(t, router, "/proposers/schedule")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, string(res), "before VIP214")
+}
+
+func TestGetLiveness(t *testing.T) {
+	_, router := newTestRouter(t, thor.ForkConfig{}, livenessReporter{
+		{Address: masters[0], Produced: 3, Missed: 1, LastSeen: 10, LastSeenTime: 100, Active: true, Activations: 1, Deactivations: 1},
+		{Address: masters[1], Missed: 1, Deactivations: 1},
+	})
+
+	code, res := get(t, router, "/proposers/liveness")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var records []*LivenessRecord
+	assert.Nil(t, json.Unmarshal(res, &records))
+	assert.Equal(t, []*LivenessRecord{
+		{Address: masters[0], Produced: 3, Missed: 1, LastSeen: &LastSeen{10, 100}, Active: true, Activations: 1, Deactivations: 1},
+		{Address: masters[1], Missed: 1, Deactivations: 1},
+	}, records)
+
+	code, res = get(t, router, "/proposers/liveness/"+masters[0].String())
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var record LivenessRecord
+	assert.Nil(t, json.Unmarshal(res, &record))
+	assert.Equal(t, uint64(3), record.Produced)
+
+	code, res = get(t, router, "/proposers/liveness/"+unfunded.String())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "null", string(bytes.TrimSpace(res)))
+
+	code, _ = get(t, router, "/proposers/liveness/abc")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// not mounted without the tracker
+	_, router = newTestRouter(t, thor.ForkConfig{}, nil)
+	code, _ = get(t, router, "/proposers/liveness")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestGetSeed(t *testing.T) {
+	_, router := newTestRouter(t, thor.ForkConfig{}, nil)
+
+	code, res := get(t, router, "/proposers/seeds/1")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var seed Seed
+	assert.Nil(t, json.Unmarshal(res, &seed))
+	assert.Equal(t, Seed{Epoch: 1, FirstBlock: thor.SeederInterval}, seed)
+
+	code, res = get(t, router, "/proposers/seeds/2")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, string(res), "seed not available yet")
+
+	code, _ = get(t, router, "/proposers/seeds/abc")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
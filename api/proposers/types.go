@@ -0,0 +1,108 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package proposers
+
+import (
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/liveness"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Schedule is the predicted proposers of slots following the parent block.
+type Schedule struct {
+	ParentID     thor.Bytes32 `json:"parentID"`
+	ParentNumber uint32       `json:"parentNumber"`
+	Slots        []Slot       `json:"slots"`
+}
+
+type Slot struct {
+	Timestamp uint64       `json:"timestamp"`
+	Proposer  thor.Address `json:"proposer"`
+}
+
+func convertSchedule(parent *block.Header, predicted []poa.Slot) *Schedule {
+	schedule := &Schedule{
+		ParentID:     parent.ID(),
+		ParentNumber: parent.Number(),
+		Slots:        make([]Slot, 0, len(predicted)),
+	}
+	for _, s := range predicted {
+		schedule.Slots = append(schedule.Slots, Slot{
+			Timestamp: s.Time,
+			Proposer:  s.Proposer,
+		})
+	}
+	return schedule
+}
+
+// Seed is the seed of an epoch, which shuffles proposers in SchedulerV2.
+type Seed struct {
+	Epoch      uint32         `json:"epoch"`
+	FirstBlock uint32         `json:"firstBlock"`
+	SeedBlock  *thor.Bytes32  `json:"seedBlock"`
+	Seed       *hexutil.Bytes `json:"seed"`
+}
+
+func convertSeed(epoch uint32, seedID thor.Bytes32, seed []byte) *Seed {
+	s := &Seed{
+		Epoch:      epoch,
+		FirstBlock: epoch * thor.SeederInterval,
+	}
+	// epochs before 2 have no seed block
+	if !seedID.IsZero() {
+		s.SeedBlock = &seedID
+	}
+	// seed blocks before VIP214 have no beta
+	if len(seed) > 0 {
+		s.Seed = (*hexutil.Bytes)(&seed)
+	}
+	return s
+}
+
+// LivenessRecord is the liveness record of a proposer.
+type LivenessRecord struct {
+	Address       thor.Address `json:"address"`
+	Produced      uint64       `json:"produced"`
+	Missed        uint64       `json:"missed"`
+	LastSeen      *LastSeen    `json:"lastSeen"`
+	Active        bool         `json:"active"`
+	Activations   uint64       `json:"activations"`
+	Deactivations uint64       `json:"deactivations"`
+}
+
+type LastSeen struct {
+	Number    uint32 `json:"number"`
+	Timestamp uint64 `json:"timestamp"`
+}
+
+func convertLivenessRecord(r *liveness.Record) *LivenessRecord {
+	record := &LivenessRecord{
+		Address:       r.Address,
+		Produced:      r.Produced,
+		Missed:        r.Missed,
+		Active:        r.Active,
+		Activations:   r.Activations,
+		Deactivations: r.Deactivations,
+	}
+	// never produced since tracked
+	if r.Produced > 0 {
+		record.LastSeen = &LastSeen{
+			Number:    r.LastSeen,
+			Timestamp: r.LastSeenTime,
+		}
+	}
+	return record
+}
+
+func convertLivenessRecords(records []*liveness.Record) []*LivenessRecord {
+	converted := make([]*LivenessRecord, 0, len(records))
+	for _, r := range records {
+		converted = append(converted, convertLivenessRecord(r))
+	}
+	return converted
+}
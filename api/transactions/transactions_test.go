@@ -0,0 +1,260 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package transactions
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/txpool"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	richKey, _ = crypto.GenerateKey()
+	poorKey, _ = crypto.GenerateKey()
+)
+
+type testEnv struct {
+	router *mux.Router
+	repo   *chain.Repository
+	pool   *txpool.TxPool
+	packed *tx.Transaction
+	block  *block.Block
+}
+
+func newTx(chainTag byte, key *ecdsa.PrivateKey, nonce uint64) *tx.Transaction {
+	to := thor.BytesToAddress([]byte("to"))
+	return tx.MustSign(new(tx.Builder).
+		ChainTag(chainTag).
+		Clause(tx.NewClause(&to).WithValue(big.NewInt(10))).
+		Gas(21000).
+		Expiration(100).
+		Nonce(nonce).
+		Build(), key)
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+
+	now := uint64(time.Now().Unix())
+	rich := thor.Address(crypto.PubkeyToAddress(richKey.PublicKey))
+	st := stater.NewState(thor.Bytes32{}, 0, 0, 0)
+	st.SetEnergy(rich, new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6)), now)
+	// the storage of an empty account is not committed
+	st.SetCode(builtin.Params.Address, builtin.Params.RuntimeBytecodes())
+	builtin.Params.Native(st).Set(thor.KeyBaseGasPrice, thor.InitialBaseGasPrice)
+	stage, err := st.Stage(0, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		Timestamp(now).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+
+	// pack a tx into block 1
+	packed := newTx(repo.ChainTag(), richKey, 1)
+	receipts := tx.Receipts{{
+		GasUsed:  21000,
+		GasPayer: rich,
+		Paid:     big.NewInt(21),
+		Reward:   big.NewInt(7),
+		Outputs: []*tx.Output{{
+			Transfers: tx.Transfers{{
+				Sender:    rich,
+				Recipient: *packed.Clauses()[0].To(),
+				Amount:    big.NewInt(10),
+			}},
+		}},
+	}}
+	blk := new(block.Builder).
+		ParentID(genesis.Header().ID()).
+		Timestamp(now + thor.BlockInterval).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(root).
+		Transaction(packed).
+		ReceiptsRoot(receipts.RootHash()).
+		Build()
+	sig, err := crypto.Sign(blk.Header().SigningHash().Bytes(), richKey)
+	assert.Nil(t, err)
+	blk = blk.WithSignature(sig)
+	assert.Nil(t, repo.AddBlock(blk, receipts, 0))
+	assert.Nil(t, repo.SetBestBlockID(blk.Header().ID()))
+
+	pool := txpool.New(repo, stater, txpool.Options{Limit: 10, LimitPerAccount: 10, MaxLifetime: time.Hour})
+	t.Cleanup(pool.Close)
+
+	router := mux.NewRouter()
+	New(repo, pool).Mount(router, "/transactions")
+	return &testEnv{router, repo, pool, packed, blk}
+}
+
+func (env *testEnv) get(t *testing.T, path string) (int, []byte) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.Bytes()
+}
+
+func (env *testEnv) post(t *testing.T, path string, body interface{}) (int, []byte) {
+	data, err := json.Marshal(body)
+	assert.Nil(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	rr := httptest.NewRecorder()
+	env.router.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.Bytes()
+}
+
+func rawTxOf(t *testing.T, trx *tx.Transaction) *RawTx {
+	raw, err := rlp.EncodeToBytes(trx)
+	assert.Nil(t, err)
+	return &RawTx{hexutil.Encode(raw)}
+}
+
+func TestSendTransaction(t *testing.T) {
+	env := newTestEnv(t)
+
+	trx := newTx(env.repo.ChainTag(), richKey, 2)
+	code, res := env.post(t, "/transactions", rawTxOf(t, trx))
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var result SendTxResult
+	assert.Nil(t, json.Unmarshal(res, &result))
+	assert.Equal(t, trx.ID(), *result.ID)
+	assert.Equal(t, trx.ID(), env.pool.Get(trx.ID()).ID())
+
+	// malformed
+	code, _ = env.post(t, "/transactions", &RawTx{"0xzz"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = env.post(t, "/transactions", &RawTx{"0x01"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = env.post(t, "/transactions", map[string]string{"unknown": ""})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// bad tx
+	code, _ = env.post(t, "/transactions", rawTxOf(t, newTx(env.repo.ChainTag()+1, richKey, 3)))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// rejected, no energy to pay
+	code, _ = env.post(t, "/transactions", rawTxOf(t, newTx(env.repo.ChainTag(), poorKey, 3)))
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestGetTransaction(t *testing.T) {
+	env := newTestEnv(t)
+
+	code, res := env.get(t, "/transactions/"+env.packed.ID().String())
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var trx Transaction
+	assert.Nil(t, json.Unmarshal(res, &trx))
+	assert.Equal(t, env.packed.ID(), trx.ID)
+	assert.Equal(t, thor.Address(crypto.PubkeyToAddress(richKey.PublicKey)), trx.Origin)
+	assert.Equal(t, &TxMeta{env.block.Header().ID(), 1, env.block.Header().Timestamp()}, trx.Meta)
+
+	code, res = env.get(t, "/transactions/"+env.packed.ID().String()+"?raw=true")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var raw RawTransaction
+	assert.Nil(t, json.Unmarshal(res, &raw))
+	assert.Equal(t, rawTxOf(t, env.packed).Raw, raw.Raw)
+	assert.Equal(t, uint32(1), raw.Meta.BlockNumber)
+
+	// not in the chain of the given head
+	code, res = env.get(t, "/transactions/"+env.packed.ID().String()+"?head="+env.repo.GenesisBlock().Header().ID().String())
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Equal(t, "null", string(bytes.TrimSpace(res)))
+
+	// bad params
+	code, _ = env.get(t, "/transactions/0x01zz")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = env.get(t, "/transactions/"+env.packed.ID().String()+"?head=0xzz")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = env.get(t, "/transactions/"+env.packed.ID().String()+"?head="+thor.Bytes32{1}.String())
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = env.get(t, "/transactions/"+env.packed.ID().String()+"?raw=yes")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = env.get(t, "/transactions/"+env.packed.ID().String()+"?pending=yes")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGetPendingTransaction(t *testing.T) {
+	env := newTestEnv(t)
+
+	pending := newTx(env.repo.ChainTag(), richKey, 2)
+	assert.Nil(t, env.pool.AddLocal(pending))
+
+	// not found without pending option
+	code, res := env.get(t, "/transactions/"+pending.ID().String())
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Equal(t, "null", string(bytes.TrimSpace(res)))
+
+	code, res = env.get(t, "/transactions/"+pending.ID().String()+"?pending=true")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var trx Transaction
+	assert.Nil(t, json.Unmarshal(res, &trx))
+	assert.Equal(t, pending.ID(), trx.ID)
+	assert.Nil(t, trx.Meta)
+
+	code, res = env.get(t, "/transactions/"+pending.ID().String()+"?pending=true&raw=true")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var raw RawTransaction
+	assert.Nil(t, json.Unmarshal(res, &raw))
+	assert.Equal(t, rawTxOf(t, pending).Raw, raw.Raw)
+	assert.Nil(t, raw.Meta)
+
+	// no receipt for pending tx
+	code, res = env.get(t, "/transactions/"+pending.ID().String()+"/receipt")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Equal(t, "null", string(bytes.TrimSpace(res)))
+}
+
+func TestGetTransactionReceipt(t *testing.T) {
+	env := newTestEnv(t)
+
+	code, res := env.get(t, "/transactions/"+env.packed.ID().String()+"/receipt")
+	assert.Equal(t, http.StatusOK, code, string(res))
+	var receipt Receipt
+	assert.Nil(t, json.Unmarshal(res, &receipt))
+	assert.Equal(t, uint64(21000), receipt.GasUsed)
+	assert.Equal(t, big.NewInt(21), (*big.Int)(receipt.Paid))
+	assert.Equal(t, big.NewInt(7), (*big.Int)(receipt.Reward))
+	assert.Equal(t, env.packed.ID(), receipt.Meta.TxID)
+	assert.Equal(t, env.block.Header().ID(), receipt.Meta.BlockID)
+	assert.Equal(t, 1, len(receipt.Outputs))
+	assert.Equal(t, big.NewInt(10), (*big.Int)(receipt.Outputs[0].Transfers[0].Amount))
+
+	code, res = env.get(t, "/transactions/"+env.packed.ID().String()+"/receipt?head="+env.repo.GenesisBlock().Header().ID().String())
+	assert.Equal(t, http.StatusOK, code, string(res))
+	assert.Equal(t, "null", string(bytes.TrimSpace(res)))
+
+	code, _ = env.get(t, "/transactions/0x01zz/receipt")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = env.get(t, "/transactions/"+env.packed.ID().String()+"/receipt?head="+thor.Bytes32{1}.String())
+	assert.Equal(t, http.StatusBadRequest, code)
+}
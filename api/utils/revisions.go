@@ -10,6 +10,10 @@ import (
 	"math"
 	"strconv"
 
+	"github.com/ashkanabbasii/thor/bft"
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/state"
 	"github.com/ashkanabbasii/thor/thor"
 )
 
@@ -68,86 +72,86 @@ func ParseRevision(revision string, allowNext bool) (*Revision, error) {
 
 // GetSummary returns the block summary for the given revision,
 // revision required to be a deterministic block other than "next".
-//func GetSummary(rev *Revision, repo *chain.Repository, bft bft.Committer) (sum *chain.BlockSummary, err error) {
-//	var id thor.Bytes32
-//	switch rev := rev.val.(type) {
-//	case thor.Bytes32:
-//		id = rev
-//	case uint32:
-//		//id, err = repo.NewBestChain().GetBlockID(rev)
-//		//if err != nil {
-//		//	return
-//		//}
-//	case int64:
-//		switch rev {
-//		case revBest:
-//			//id = repo.BestBlockSummary().Header.ID()
-//		case revFinalized:
-//			id = bft.Finalized()
-//		case revJustified:
-//			id, err = bft.Justified()
-//			if err != nil {
-//				return nil, err
-//			}
-//		}
-//	}
-//	if id.IsZero() {
-//		return nil, errors.New("invalid revision")
-//	}
-//	summary, err := repo.GetBlockSummary(id)
-//	if err != nil {
-//		return nil, err
-//	}
-//	return summary, nil
-//}
+func GetSummary(rev *Revision, repo *chain.Repository, bft bft.Committer) (sum *chain.BlockSummary, err error) {
+	var id thor.Bytes32
+	switch rev := rev.val.(type) {
+	case thor.Bytes32:
+		id = rev
+	case uint32:
+		id, err = repo.NewBestChain().GetBlockID(rev)
+		if err != nil {
+			return
+		}
+	case int64:
+		switch rev {
+		case revBest:
+			id = repo.BestBlockSummary().Header.ID()
+		case revFinalized:
+			id = bft.Finalized()
+		case revJustified:
+			id, err = bft.Justified()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if id.IsZero() {
+		return nil, errors.New("invalid revision")
+	}
+	summary, err := repo.GetBlockSummary(id)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
 
 // GetSummaryAndState returns the block summary and state for the given revision,
 // this function supports the "next" revision.
-//func GetSummaryAndState(rev *Revision, repo *chain.Repository, bft bft.Committer, stater *state.Stater) (*chain.BlockSummary, *state.State, error) {
-//	if rev.IsNext() {
-//		best := repo.BestBlockSummary()
-//
-//		// here we create a fake(no signature) "next" block header which reused most part of the parent block
-//		// but set the timestamp and number to the next block. The following parameters will be used in the evm
-//		// number, timestamp, total score, gas limit, beneficiary and "signer"
-//		// since the fake block is not signed, the signer is the zero address, it is important that the subsequent
-//		// call to header.Signer(), the error should be ignored.
-//		builder := new(block.Builder).
-//			ParentID(best.Header.ID()).
-//			Timestamp(best.Header.Timestamp() + thor.BlockInterval).
-//			TotalScore(best.Header.TotalScore()).
-//			GasLimit(best.Header.GasLimit()).
-//			GasUsed(best.Header.GasUsed()).
-//			Beneficiary(best.Header.Beneficiary()).
-//			StateRoot(best.Header.StateRoot()).
-//			ReceiptsRoot(best.Header.ReceiptsRoot()).
-//			TransactionFeatures(best.Header.TxsFeatures()).
-//			Alpha(best.Header.Alpha())
-//
-//		// here we skipped the block's tx list thus header.txRoot will be an empty root
-//		// since txRoot won't be supplied into the evm, it's safe to skip it.
-//		if best.Header.COM() {
-//			builder.COM()
-//		}
-//		mocked := builder.Build()
-//
-//		// state is also reused from the parent block
-//		st := stater.NewState(best.Header.StateRoot(), best.Header.Number(), best.Conflicts, best.SteadyNum)
-//
-//		// rebuild the block summary with the next header (mocked) AND the best block status
-//		return &chain.BlockSummary{
-//			Header:    mocked.Header(),
-//			Txs:       best.Txs,
-//			Size:      uint64(mocked.Size()),
-//			Conflicts: best.Conflicts,
-//			SteadyNum: best.SteadyNum,
-//		}, st, nil
-//	}
-//	sum, err := GetSummary(rev, repo, bft)
-//	if err != nil {
-//		return nil, nil, err
-//	}
-//
-//	st := stater.NewState(sum.Header.StateRoot(), sum.Header.Number(), sum.Conflicts, sum.SteadyNum)
-//	return sum, st, nil
-//}
+func GetSummaryAndState(rev *Revision, repo *chain.Repository, bft bft.Committer, stater *state.Stater) (*chain.BlockSummary, *state.State, error) {
+	if rev.IsNext() {
+		best := repo.BestBlockSummary()
+
+		// here we create a fake(no signature) "next" block header which reused most part of the parent block
+		// but set the timestamp and number to the next block. The following parameters will be used in the evm
+		// number, timestamp, total score, gas limit, beneficiary and "signer"
+		// since the fake block is not signed, the signer is the zero address, it is important that the subsequent
+		// call to header.Signer(), the error should be ignored.
+		builder := new(block.Builder).
+			ParentID(best.Header.ID()).
+			Timestamp(best.Header.Timestamp() + thor.BlockInterval).
+			TotalScore(best.Header.TotalScore()).
+			GasLimit(best.Header.GasLimit()).
+			GasUsed(best.Header.GasUsed()).
+			Beneficiary(best.Header.Beneficiary()).
+			StateRoot(best.Header.StateRoot()).
+			ReceiptsRoot(best.Header.ReceiptsRoot()).
+			TransactionFeatures(best.Header.TxsFeatures()).
+			Alpha(best.Header.Alpha())
+
+		// here we skipped the block's tx list thus header.txRoot will be an empty root
+		// since txRoot won't be supplied into the evm, it's safe to skip it.
+		if best.Header.COM() {
+			builder.COM()
+		}
+		mocked := builder.Build()
+
+		// state is also reused from the parent block
+		st := stater.NewState(best.Header.StateRoot(), best.Header.Number(), best.Conflicts, best.SteadyNum)
+
+		// rebuild the block summary with the next header (mocked) AND the best block status
+		return &chain.BlockSummary{
+			Header:    mocked.Header(),
+			Txs:       best.Txs,
+			Size:      uint64(mocked.Size()),
+			Conflicts: best.Conflicts,
+			SteadyNum: best.SteadyNum,
+		}, st, nil
+	}
+	sum, err := GetSummary(rev, repo, bft)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	st := stater.NewState(sum.Header.StateRoot(), sum.Header.Number(), sum.Conflicts, sum.SteadyNum)
+	return sum, st, nil
+}
@@ -15,7 +15,49 @@ import (
 // casts stores the master's overall casts, maintaining the map of quality to checkpoint.
 type casts map[thor.Bytes32]uint32
 
+// newCasts rebuilds the master's casts since the finalized checkpoint, from the COM
+// blocks signed by the master on the best chain.
 func (engine *Engine) newCasts() error {
+	ca := make(casts)
+	if engine.master.IsZero() {
+		engine.casts = ca
+		return nil
+	}
+
+	var (
+		best  = engine.repo.BestBlockSummary().Header
+		chain = engine.repo.NewChain(best.ID())
+		start = block.Number(engine.Finalized())
+	)
+	if start < engine.forkConfig.FINALITY {
+		start = engine.forkConfig.FINALITY
+	}
+
+	for num := start; num <= best.Number(); num++ {
+		sum, err := chain.GetBlockSummary(num)
+		if err != nil {
+			return err
+		}
+		if !sum.Header.COM() {
+			continue
+		}
+		if signer, _ := sum.Header.Signer(); signer != engine.master {
+			continue
+		}
+
+		checkpoint, err := chain.GetBlockID(getCheckPoint(num))
+		if err != nil {
+			return err
+		}
+		st, err := engine.computeState(sum.Header)
+		if err != nil {
+			return err
+		}
+		// the later cast in a round overrides
+		ca.Mark(checkpoint, st.Quality)
+	}
+
+	engine.casts = ca
 	return nil
 }
 
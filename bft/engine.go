@@ -5,14 +5,20 @@
 package bft
 
 import (
+	"math"
+	"sort"
+	"sync/atomic"
+
 	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
 	"github.com/ashkanabbasii/thor/cache"
 	"github.com/ashkanabbasii/thor/chain"
 	"github.com/ashkanabbasii/thor/kv"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
 	"github.com/ashkanabbasii/thor/thor"
-	"sync/atomic"
-
 	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
 )
 
 const dataStoreName = "bft.engine"
@@ -32,9 +38,9 @@ type justified struct {
 // Engine tracks all votes of blocks, computes the finalized checkpoint.
 // Not thread-safe!
 type Engine struct {
-	//repo *chain.Repository
-	data kv.Store
-	//stater     *state.Stater
+	repo       *chain.Repository
+	data       kv.Store
+	stater     *state.Stater
 	forkConfig thor.ForkConfig
 	master     thor.Address
 	casts      casts
@@ -48,31 +54,31 @@ type Engine struct {
 }
 
 // NewEngine creates a new bft engine.
-//func NewEngine(repo *chain.Repository, mainDB *muxdb.MuxDB, forkConfig thor.ForkConfig, master thor.Address) (*Engine, error) {
-//	engine := Engine{
-//		repo:       repo,
-//		//data:       mainDB.NewStore(dataStoreName),
-//		//stater:     state.NewStater(mainDB),
-//		forkConfig: forkConfig,
-//		master:     master,
-//	}
-//
-//	engine.caches.state, _ = lru.New(256)
-//	engine.caches.quality, _ = lru.New(16)
-//	engine.caches.justifier = cache.NewPrioCache(16)
-//
-//	// Restore finalized block, if any
-//	if val, err := engine.data.Get(finalizedKey); err != nil {
-//		if !engine.data.IsNotFound(err) {
-//			return nil, err
-//		}
-//		engine.finalized.Store(engine.repo.GenesisBlock().Header().ID())
-//	} else {
-//		engine.finalized.Store(thor.BytesToBytes32(val))
-//	}
-//
-//	return &engine, nil
-//}
+func NewEngine(repo *chain.Repository, mainDB *muxdb.MuxDB, forkConfig thor.ForkConfig, master thor.Address) (*Engine, error) {
+	engine := Engine{
+		repo:       repo,
+		data:       mainDB.NewStore(dataStoreName),
+		stater:     state.NewStater(mainDB),
+		forkConfig: forkConfig,
+		master:     master,
+	}
+
+	engine.caches.state, _ = lru.New(256)
+	engine.caches.quality, _ = lru.New(16)
+	engine.caches.justifier = cache.NewPrioCache(16)
+
+	// Restore finalized block, if any
+	if val, err := engine.data.Get(finalizedKey); err != nil {
+		if !engine.data.IsNotFound(err) {
+			return nil, err
+		}
+		engine.finalized.Store(engine.repo.GenesisBlock().Header().ID())
+	} else {
+		engine.finalized.Store(thor.BytesToBytes32(val))
+	}
+
+	return &engine, nil
+}
 
 // Finalized returns the finalized checkpoint.
 func (engine *Engine) Finalized() thor.Bytes32 {
@@ -81,232 +87,357 @@ func (engine *Engine) Finalized() thor.Bytes32 {
 
 // Justified returns the justified checkpoint.
 func (engine *Engine) Justified() (thor.Bytes32, error) {
-	return thor.Bytes32{}, nil
+	head := engine.repo.BestBlockSummary().Header
+	finalized := engine.Finalized()
+
+	// no round concluded since finality activated
+	firstStorePoint := getStorePoint(engine.forkConfig.FINALITY)
+	if engine.forkConfig.FINALITY == math.MaxUint32 || head.Number() < firstStorePoint {
+		return finalized, nil
+	}
+
+	// the store point of the most recent concluded round
+	storeNum := getStorePoint(head.Number())
+	if storeNum != head.Number() {
+		storeNum -= thor.CheckpointInterval
+	}
+	storePoint, err := engine.repo.NewChain(head.ID()).GetBlockID(storeNum)
+	if err != nil {
+		return thor.Bytes32{}, err
+	}
+
+	if cached, ok := engine.justified.Load().(justified); ok && cached.search == storePoint {
+		return cached.value, nil
+	}
+
+	quality, err := engine.getQuality(storePoint)
+	if err != nil {
+		return thor.Bytes32{}, err
+	}
+
+	value := finalized
+	if quality > 0 {
+		// the checkpoint of the round that justified last
+		if value, err = engine.findCheckpointByQuality(quality, finalized, storePoint); err != nil {
+			return thor.Bytes32{}, err
+		}
+	}
+
+	engine.justified.Store(justified{search: storePoint, value: value})
+	return value, nil
 }
 
 // Accepts checks if the given block is on the same branch of finalized checkpoint.
-//func (engine *Engine) Accepts(parentID thor.Bytes32) (bool, error) {
-//	finalized := engine.Finalized()
-//
-//	if block.Number(finalized) != 0 {
-//		return engine.repo.NewChain(parentID).HasBlock(finalized)
-//	}
-//
-//	return true, nil
-//}
+func (engine *Engine) Accepts(parentID thor.Bytes32) (bool, error) {
+	finalized := engine.Finalized()
+
+	if block.Number(finalized) != 0 {
+		return engine.repo.NewChain(parentID).HasBlock(finalized)
+	}
+
+	return true, nil
+}
 
 // Select selects between the new block and the current best, return true if new one is better.
-//func (engine *Engine) Select(header *block.Header) (bool, error) {
-//	newSt, err := engine.computeState(header)
-//	if err != nil {
-//		return false, err
-//	}
-//
-//	best := engine.repo.BestBlockSummary().Header
-//	bestSt, err := engine.computeState(best)
-//	if err != nil {
-//		return false, err
-//	}
-//
-//	if newSt.Quality != bestSt.Quality {
-//		return newSt.Quality > bestSt.Quality, nil
-//	}
-//
-//	return header.BetterThan(best), nil
-//}
+func (engine *Engine) Select(header *block.Header) (bool, error) {
+	newSt, err := engine.computeState(header)
+	if err != nil {
+		return false, err
+	}
+
+	best := engine.repo.BestBlockSummary().Header
+	bestSt, err := engine.computeState(best)
+	if err != nil {
+		return false, err
+	}
+
+	if newSt.Quality != bestSt.Quality {
+		return newSt.Quality > bestSt.Quality, nil
+	}
+
+	return header.BetterThan(best), nil
+}
 
 // CommitBlock commits bft state to storage.
 func (engine *Engine) CommitBlock(header *block.Header, isPacking bool) error {
 	// save quality and finalized at the end of each round
+	if getStorePoint(header.Number()) == header.Number() {
+		st, err := engine.computeState(header)
+		if err != nil {
+			return err
+		}
+
+		if err := saveQuality(engine.data, header.ID(), st.Quality); err != nil {
+			return err
+		}
+		engine.caches.quality.Add(header.ID(), st.Quality)
+
+		if st.Committed && st.Quality > 1 {
+			// the round justified before the committed one is finalized
+			id, err := engine.findCheckpointByQuality(st.Quality-1, engine.Finalized(), header.ParentID())
+			if err != nil {
+				return err
+			}
+
+			if err := engine.data.Put(finalizedKey, id[:]); err != nil {
+				return err
+			}
+			engine.finalized.Store(id)
+			metricBlocksCommitted().Add(1)
+		}
+	}
+
+	// mark the master's vote
+	if isPacking && header.COM() {
+		if engine.casts == nil {
+			if err := engine.newCasts(); err != nil {
+				return err
+			}
+		}
+
+		checkpoint := header.ID()
+		if !isCheckPoint(header.Number()) {
+			var err error
+			if checkpoint, err = engine.repo.NewChain(header.ParentID()).GetBlockID(getCheckPoint(header.Number())); err != nil {
+				return err
+			}
+		}
+
+		st, err := engine.computeState(header)
+		if err != nil {
+			return err
+		}
+		engine.casts.Mark(checkpoint, st.Quality)
+	}
 
 	return nil
 }
 
 // ShouldVote decides if vote COM for a given parent block ID.
 // Packer only.
-//func (engine *Engine) ShouldVote(parentID thor.Bytes32) (bool, error) {
-//	// laze init casts
-//	if engine.casts == nil {
-//		if err := engine.newCasts(); err != nil {
-//			return false, err
-//		}
-//	}
-//
-//	// do not vote COM at the first round
-//	if absRound := (block.Number(parentID)+1)/thor.CheckpointInterval - engine.forkConfig.FINALITY/thor.CheckpointInterval; absRound == 0 {
-//		return false, nil
-//	}
-//
-//	sum, err := engine.repo.GetBlockSummary(parentID)
-//	if err != nil {
-//		return false, err
-//	}
-//	st, err := engine.computeState(sum.Header)
-//	if err != nil {
-//		return false, err
-//	}
-//	if st.Quality == 0 {
-//		return false, nil
-//	}
-//
-//	headQuality := st.Quality
-//	finalized := engine.Finalized()
-//	chain := engine.repo.NewChain(parentID)
-//	// most recent justified checkpoint
-//	var recentJC thor.Bytes32
-//	if st.Justified {
-//		// if justified in this round, use this round's checkpoint
-//		checkpoint, err := chain.GetBlockID(getCheckPoint(block.Number(parentID)))
-//		if err != nil {
-//			return false, err
-//		}
-//		recentJC = checkpoint
-//	} else {
-//		// if current round is not justified, find the most recent justified checkpoint
-//		prev, err := chain.GetBlockID(getStorePoint(block.Number(parentID) - thor.CheckpointInterval))
-//		if err != nil {
-//			return false, err
-//		}
-//		checkpoint, err := engine.findCheckpointByQuality(headQuality, finalized, prev)
-//		if err != nil {
-//			return false, err
-//		}
-//		recentJC = checkpoint
-//	}
-//
-//	// see https://github.com/vechain/VIPs/blob/master/vips/VIP-220.md
-//	for _, cast := range engine.casts.Slice(finalized) {
-//		if cast.quality >= headQuality-1 {
-//			x, y := recentJC, cast.checkpoint
-//			if block.Number(cast.checkpoint) > block.Number(recentJC) {
-//				x, y = cast.checkpoint, recentJC
-//			}
-//			// checks if the voted checkpoint belongs to the head chain
-//			includes, err := engine.repo.NewChain(x).HasBlock(y)
-//			if err != nil {
-//				return false, err
-//			}
-//
-//			// if one votes a checkpoint was within [headQuality-1, +∞) and conflict with head
-//			// should not vote COM
-//			if !includes {
-//				return false, nil
-//			}
-//		}
-//	}
-//
-//	return true, nil
-//}
+func (engine *Engine) ShouldVote(parentID thor.Bytes32) (bool, error) {
+	// laze init casts
+	if engine.casts == nil {
+		if err := engine.newCasts(); err != nil {
+			return false, err
+		}
+	}
+
+	// do not vote COM at the first round
+	if absRound := (block.Number(parentID)+1)/thor.CheckpointInterval - engine.forkConfig.FINALITY/thor.CheckpointInterval; absRound == 0 {
+		return false, nil
+	}
+
+	sum, err := engine.repo.GetBlockSummary(parentID)
+	if err != nil {
+		return false, err
+	}
+	st, err := engine.computeState(sum.Header)
+	if err != nil {
+		return false, err
+	}
+	if st.Quality == 0 {
+		return false, nil
+	}
+
+	headQuality := st.Quality
+	finalized := engine.Finalized()
+	chain := engine.repo.NewChain(parentID)
+	// most recent justified checkpoint
+	var recentJC thor.Bytes32
+	if st.Justified {
+		// if justified in this round, use this round's checkpoint
+		checkpoint, err := chain.GetBlockID(getCheckPoint(block.Number(parentID)))
+		if err != nil {
+			return false, err
+		}
+		recentJC = checkpoint
+	} else {
+		// if current round is not justified, find the most recent justified checkpoint
+		prev, err := chain.GetBlockID(getStorePoint(block.Number(parentID) - thor.CheckpointInterval))
+		if err != nil {
+			return false, err
+		}
+		checkpoint, err := engine.findCheckpointByQuality(headQuality, finalized, prev)
+		if err != nil {
+			return false, err
+		}
+		recentJC = checkpoint
+	}
+
+	// see https://github.com/vechain/VIPs/blob/master/vips/VIP-220.md
+	for _, cast := range engine.casts.Slice(finalized) {
+		if cast.quality >= headQuality-1 {
+			x, y := recentJC, cast.checkpoint
+			if block.Number(cast.checkpoint) > block.Number(recentJC) {
+				x, y = cast.checkpoint, recentJC
+			}
+			// checks if the voted checkpoint belongs to the head chain
+			includes, err := engine.repo.NewChain(x).HasBlock(y)
+			if err != nil {
+				return false, err
+			}
+
+			// if one votes a checkpoint was within [headQuality-1, +∞) and conflict with head
+			// should not vote COM
+			if !includes {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
 
 // computeState computes the bft state regarding the given block header to the closest checkpoint.
-//func (engine *Engine) computeState(header *block.Header) (*bftState, error) {
-//	if cached, ok := engine.caches.state.Get(header.ID()); ok {
-//		return cached.(*bftState), nil
-//	}
-//
-//	if header.Number() == 0 || header.Number() < engine.forkConfig.FINALITY {
-//		return &bftState{}, nil
-//	}
-//
-//	var (
-//		js  *justifier
-//		end uint32
-//	)
-//
-//	if entry := engine.caches.justifier.Remove(header.ParentID()); !isCheckPoint(header.Number()) && entry != nil {
-//		js = (entry.Entry.Value).(*justifier)
-//		end = header.Number()
-//	} else {
-//		// create a new vote set if cache missed or new block is checkpoint
-//		var err error
-//		js, err = engine.newJustifier(header.ParentID())
-//		if err != nil {
-//			return nil, errors.Wrap(err, "failed to create vote set")
-//		}
-//		end = js.checkpoint
-//	}
-//
-//	h := header
-//	for {
-//		if h.Number() < engine.forkConfig.FINALITY {
-//			break
-//		}
-//
-//		signer, _ := h.Signer()
-//		js.AddBlock(signer, h.COM())
-//
-//		if h.Number() <= end {
-//			break
-//		}
-//
-//		sum, err := engine.repo.GetBlockSummary(h.ParentID())
-//		if err != nil {
-//			return nil, err
-//		}
-//		h = sum.Header
-//	}
-//
-//	st := js.Summarize()
-//	engine.caches.state.Add(header.ID(), st)
-//	engine.caches.justifier.Set(header.ID(), js, float64(header.Number()))
-//	return st, nil
-//}
+func (engine *Engine) computeState(header *block.Header) (*bftState, error) {
+	if cached, ok := engine.caches.state.Get(header.ID()); ok {
+		return cached.(*bftState), nil
+	}
+
+	if header.Number() == 0 || header.Number() < engine.forkConfig.FINALITY {
+		return &bftState{}, nil
+	}
+
+	var (
+		js  *justifier
+		end uint32
+	)
+
+	if entry := engine.caches.justifier.Remove(header.ParentID()); !isCheckPoint(header.Number()) && entry != nil {
+		js = (entry.Entry.Value).(*justifier)
+		end = header.Number()
+	} else {
+		// create a new vote set if cache missed or new block is checkpoint
+		var err error
+		js, err = engine.newJustifier(header.ParentID())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create vote set")
+		}
+		end = js.checkpoint
+	}
+
+	if err := engine.collectVotes(js, header, end); err != nil {
+		return nil, err
+	}
+
+	st := js.Summarize()
+	engine.caches.state.Add(header.ID(), st)
+	engine.caches.justifier.Set(header.ID(), js, float64(header.Number()))
+	return st, nil
+}
+
+// peekState computes the bft state like computeState, but leaves the caches untouched,
+// so that it's safe to be called concurrently.
+func (engine *Engine) peekState(header *block.Header) (*bftState, error) {
+	if cached, ok := engine.caches.state.Get(header.ID()); ok {
+		return cached.(*bftState), nil
+	}
+
+	if header.Number() == 0 || header.Number() < engine.forkConfig.FINALITY {
+		return &bftState{}, nil
+	}
+
+	js, err := engine.newJustifier(header.ParentID())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create vote set")
+	}
+	if err := engine.collectVotes(js, header, js.checkpoint); err != nil {
+		return nil, err
+	}
+	return js.Summarize(), nil
+}
+
+// collectVotes adds votes of blocks from header back to the block numbered end into the justifier.
+func (engine *Engine) collectVotes(js *justifier, header *block.Header, end uint32) error {
+	h := header
+	for {
+		if js.isCommitted() || h.Number() < end || h.Number() < engine.forkConfig.FINALITY {
+			return nil
+		}
+
+		signer, _ := h.Signer()
+		js.AddBlock(signer, h.COM())
+
+		if h.Number() <= end {
+			return nil
+		}
+
+		sum, err := engine.repo.GetBlockSummary(h.ParentID())
+		if err != nil {
+			return err
+		}
+		h = sum.Header
+	}
+}
 
 // findCheckpointByQuality finds the first checkpoint reaches the given quality.
 // It is caller's responsibility to ensure the epoch that headID belongs to is concluded.
-//func (engine *Engine) findCheckpointByQuality(target uint32, finalized, headID thor.Bytes32) (blockID thor.Bytes32, err error) {
-//	defer func() {
-//		if e := recover(); e != nil {
-//			err = e.(error)
-//			return
-//		}
-//	}()
-//
-//	searchStart := block.Number(finalized)
-//	if searchStart == 0 {
-//		searchStart = getCheckPoint(engine.forkConfig.FINALITY)
-//	}
-//
-//	c := engine.repo.NewChain(headID)
-//	get := func(i int) (uint32, error) {
-//		id, err := c.GetBlockID(getStorePoint(searchStart + uint32(i)*thor.CheckpointInterval))
-//		if err != nil {
-//			return 0, err
-//		}
-//		return engine.getQuality(id)
-//	}
-//
-//	// sort.Search searches from [0, n)
-//	n := int((block.Number(headID)-searchStart)/thor.CheckpointInterval) + 1
-//	num := sort.Search(n, func(i int) bool {
-//		quality, err := get(i)
-//		if err != nil {
-//			panic(err)
-//		}
-//
-//		return quality >= target
-//	})
-//
-//	// n means not found for sort.Search
-//	if num == n {
-//		return thor.Bytes32{}, errors.New("failed find the block by quality")
-//	}
-//
-//	quality, err := get(num)
-//	if err != nil {
-//		return thor.Bytes32{}, err
-//	}
-//
-//	if quality != target {
-//		return thor.Bytes32{}, errors.New("failed to find the block by quality")
-//	}
-//
-//	return c.GetBlockID(searchStart + uint32(num)*thor.CheckpointInterval)
-//}
+func (engine *Engine) findCheckpointByQuality(target uint32, finalized, headID thor.Bytes32) (blockID thor.Bytes32, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = e.(error)
+			return
+		}
+	}()
+
+	searchStart := block.Number(finalized)
+	if searchStart == 0 {
+		searchStart = getCheckPoint(engine.forkConfig.FINALITY)
+	}
+
+	c := engine.repo.NewChain(headID)
+	get := func(i int) (uint32, error) {
+		id, err := c.GetBlockID(getStorePoint(searchStart + uint32(i)*thor.CheckpointInterval))
+		if err != nil {
+			return 0, err
+		}
+		return engine.getQuality(id)
+	}
+
+	// sort.Search searches from [0, n), n is the count of rounds concluded on the chain of headID
+	if block.Number(headID)+1 < searchStart {
+		return thor.Bytes32{}, errors.New("failed find the block by quality")
+	}
+	n := int((block.Number(headID) + 1 - searchStart) / thor.CheckpointInterval)
+	num := sort.Search(n, func(i int) bool {
+		quality, err := get(i)
+		if err != nil {
+			panic(err)
+		}
+
+		return quality >= target
+	})
+
+	// n means not found for sort.Search
+	if num == n {
+		return thor.Bytes32{}, errors.New("failed find the block by quality")
+	}
+
+	quality, err := get(num)
+	if err != nil {
+		return thor.Bytes32{}, err
+	}
+
+	if quality != target {
+		return thor.Bytes32{}, errors.New("failed to find the block by quality")
+	}
+
+	return c.GetBlockID(searchStart + uint32(num)*thor.CheckpointInterval)
+}
 
 func (engine *Engine) getMaxBlockProposers(sum *chain.BlockSummary) (uint64, error) {
-	//state := engine.stater.NewState(sum.Header.StateRoot(), sum.Header.Number(), sum.Conflicts, sum.SteadyNum)
-	return 2, nil
+	state := engine.stater.NewState(sum.Header.StateRoot(), sum.Header.Number(), sum.Conflicts, sum.SteadyNum)
+	params, err := builtin.Params.Native(state).Get(thor.KeyMaxBlockProposers)
+	if err != nil {
+		return 0, err
+	}
+	mbp := params.Uint64()
+	if mbp == 0 || mbp > thor.InitialMaxBlockProposers {
+		mbp = thor.InitialMaxBlockProposers
+	}
+
+	return mbp, nil
 }
 
 func (engine *Engine) getQuality(id thor.Bytes32) (quality uint32, err error) {
@@ -0,0 +1,358 @@
+// Copyright (c) 2022 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+package bft
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var keys = func() []*ecdsa.PrivateKey {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+	}
+	return keys
+}()
+
+func keyAddr(key *ecdsa.PrivateKey) thor.Address {
+	return thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+}
+
+type testChain struct {
+	t      *testing.T
+	db     *muxdb.MuxDB
+	repo   *chain.Repository
+	engine *Engine
+}
+
+// newTestChain creates a chain with finality activated at the second round,
+// 3 votes are required to justify a round. All keys are listed and endorsed proposers.
+func newTestChain(t *testing.T, master thor.Address) *testChain {
+	db := muxdb.NewMem()
+	st := state.NewStater(db).NewState(thor.Bytes32{}, 0, 0, 0)
+	assert.Nil(t, st.SetCode(builtin.Params.Address, builtin.Params.RuntimeBytecodes()))
+	assert.Nil(t, builtin.Params.Native(st).Set(thor.KeyMaxBlockProposers, big.NewInt(4)))
+	assert.Nil(t, builtin.Params.Native(st).Set(thor.KeyProposerEndorsement, big.NewInt(1e18)))
+	assert.Nil(t, st.SetCode(builtin.Authority.Address, builtin.Authority.RuntimeBytecodes()))
+	for _, key := range keys {
+		addr := keyAddr(key)
+		ok, err := builtin.Authority.Native(st).Add(addr, addr, thor.Bytes32{})
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Nil(t, st.SetBalance(addr, big.NewInt(1e18)))
+	}
+	stage, err := st.Stage(0, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+
+	forkConfig := thor.NoFork
+	forkConfig.FINALITY = thor.CheckpointInterval
+	engine, err := NewEngine(repo, db, forkConfig, master)
+	assert.Nil(t, err)
+
+	return &testChain{t, db, repo, engine}
+}
+
+// newBlock builds a block upon the parent, signed by the given key.
+func (c *testChain) newBlock(parent *block.Header, key *ecdsa.PrivateKey, com bool, score uint64) *block.Block {
+	builder := new(block.Builder).
+		ParentID(parent.ID()).
+		Timestamp(parent.Timestamp() + thor.BlockInterval).
+		TotalScore(parent.TotalScore() + score).
+		GasLimit(parent.GasLimit()).
+		StateRoot(parent.StateRoot()).
+		ReceiptsRoot(tx.Receipts(nil).RootHash())
+	if com {
+		builder.COM()
+	}
+	blk := builder.Build()
+	sig, err := crypto.Sign(blk.Header().SigningHash().Bytes(), key)
+	assert.Nil(c.t, err)
+	return blk.WithSignature(sig)
+}
+
+// extend appends n blocks upon the given head, signed in turn by the keys, and commits them.
+func (c *testChain) extend(head *block.Header, n int, keys []*ecdsa.PrivateKey, com bool) *block.Header {
+	for i := 0; i < n; i++ {
+		blk := c.newBlock(head, keys[int(head.Number()+1)%len(keys)], com, 1)
+		conflicts, err := c.repo.ScanConflicts(blk.Header().Number())
+		assert.Nil(c.t, err)
+		assert.Nil(c.t, c.repo.AddBlock(blk, nil, conflicts))
+
+		best := c.repo.BestBlockSummary().Header
+		if blk.Header().BetterThan(best) {
+			assert.Nil(c.t, c.repo.SetBestBlockID(blk.Header().ID()))
+		}
+		assert.Nil(c.t, c.engine.CommitBlock(blk.Header(), false))
+		head = blk.Header()
+	}
+	return head
+}
+
+func (c *testChain) blockID(num uint32) thor.Bytes32 {
+	id, err := c.repo.NewBestChain().GetBlockID(num)
+	assert.Nil(c.t, err)
+	return id
+}
+
+func TestNewEngine(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	genesisID := c.repo.GenesisBlock().Header().ID()
+	assert.Equal(t, genesisID, c.engine.Finalized())
+
+	justified, err := c.engine.Justified()
+	assert.Nil(t, err)
+	assert.Equal(t, genesisID, justified)
+
+	// finalized is restored from storage
+	assert.Nil(t, c.engine.data.Put(finalizedKey, thor.Bytes32{1}.Bytes()))
+	engine, err := NewEngine(c.repo, c.db, c.engine.forkConfig, thor.Address{})
+	assert.Nil(t, err)
+	assert.Equal(t, thor.Bytes32{1}, engine.Finalized())
+}
+
+func TestFinalize(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	genesisID := c.repo.GenesisBlock().Header().ID()
+	const interval = thor.CheckpointInterval
+
+	// before finality
+	head := c.extend(c.repo.GenesisBlock().Header(), interval*2-1, keys, true)
+	st, err := c.engine.computeState(head)
+	assert.Nil(t, err)
+	assert.Equal(t, &bftState{Quality: 1, Justified: true, Committed: true, Votes: 3, COMVotes: 3, Threshold: 2}, st)
+	assert.Equal(t, genesisID, c.engine.Finalized())
+	justified, err := c.engine.Justified()
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval), justified)
+
+	// the first justified round is finalized
+	head = c.extend(head, interval, keys, true)
+	assert.Equal(t, c.blockID(interval), c.engine.Finalized())
+	justified, err = c.engine.Justified()
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval*2), justified)
+
+	// justified changes only when a round concluded
+	head = c.extend(head, interval/2, keys, true)
+	justified, err = c.engine.Justified()
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval*2), justified)
+
+	head = c.extend(head, interval/2, keys, true)
+	assert.Equal(t, c.blockID(interval*2), c.engine.Finalized())
+	justified, err = c.engine.Justified()
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval*3), justified)
+
+	// justified but not committed, finalized not moved
+	head = c.extend(head, interval, keys, false)
+	assert.Equal(t, c.blockID(interval*2), c.engine.Finalized())
+	justified, err = c.engine.Justified()
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval*4), justified)
+
+	quality, err := c.engine.getQuality(head.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), quality)
+	// persisted
+	saved, err := loadQuality(c.engine.data, head.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), saved)
+
+	engine, err := NewEngine(c.repo, c.db, c.engine.forkConfig, thor.Address{})
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval*2), engine.Finalized())
+}
+
+func TestCommittedRound(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+
+	// all signers voted COM in the round
+	head := c.extend(c.repo.GenesisBlock().Header(), thor.CheckpointInterval-1, keys, true)
+	head = c.extend(head, 3, keys, true)
+	st, err := c.engine.computeState(head)
+	assert.Nil(t, err)
+	assert.True(t, st.Committed)
+
+	// then one of them votes non-COM and COM again in the same round
+	head = c.extend(head, 1, keys[:1], false)
+	st, err = c.engine.computeState(head)
+	assert.Nil(t, err)
+	assert.True(t, st.Committed)
+
+	head = c.extend(head, 1, keys[:1], true)
+	st, err = c.engine.computeState(head)
+	assert.Nil(t, err)
+	assert.True(t, st.Committed)
+	assert.Equal(t, uint64(3), st.COMVotes)
+}
+
+func TestStatus(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	genesisID := c.repo.GenesisBlock().Header().ID()
+	const interval = thor.CheckpointInterval
+
+	// before finality
+	head := c.extend(c.repo.GenesisBlock().Header(), interval-1, keys, true)
+	status, err := c.engine.Status(5)
+	assert.Nil(t, err)
+	assert.Equal(t, &Status{
+		Finalized:   genesisID,
+		Justified:   genesisID,
+		Checkpoints: []CheckpointStatus{},
+	}, status)
+
+	// in the middle of the fourth round, only 1 signer voted so far
+	head = c.extend(head, interval*2, keys, true)
+	c.extend(head, interval/2, keys[:1], false)
+	status, err = c.engine.Status(5)
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval), status.Finalized)
+	assert.Equal(t, c.blockID(interval*2), status.Justified)
+	assert.Equal(t, RoundStatus{
+		Checkpoint: interval * 3,
+		Quality:    2,
+		Votes:      1,
+		Threshold:  2,
+	}, status.Round)
+	assert.Equal(t, []CheckpointStatus{
+		{c.blockID(interval * 2), 2},
+		{c.blockID(interval), 1},
+	}, status.Checkpoints)
+
+	// limited rounds
+	status, err = c.engine.Status(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []CheckpointStatus{{c.blockID(interval * 2), 2}}, status.Checkpoints)
+}
+
+func TestNotJustified(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	genesisID := c.repo.GenesisBlock().Header().ID()
+
+	// 2 votes are not enough
+	head := c.extend(c.repo.GenesisBlock().Header(), thor.CheckpointInterval*3, keys[:2], true)
+	st, err := c.engine.computeState(head)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), st.Quality)
+	assert.False(t, st.Justified)
+
+	assert.Equal(t, genesisID, c.engine.Finalized())
+	justified, err := c.engine.Justified()
+	assert.Nil(t, err)
+	assert.Equal(t, genesisID, justified)
+}
+
+func TestAccepts(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	const interval = thor.CheckpointInterval
+
+	ok, err := c.engine.Accepts(c.repo.GenesisBlock().Header().ID())
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	head := c.extend(c.repo.GenesisBlock().Header(), interval*3, keys, true)
+	finalized := c.engine.Finalized()
+	assert.Equal(t, uint32(interval), block.Number(finalized))
+
+	ok, err = c.engine.Accepts(head.ID())
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// a branch forked before the finalized checkpoint
+	sum, err := c.repo.NewBestChain().GetBlockSummary(interval - 1)
+	assert.Nil(t, err)
+	fork := c.newBlock(sum.Header, keys[0], true, 2)
+	assert.Nil(t, c.repo.AddBlock(fork, nil, 1))
+	ok, err = c.engine.Accepts(fork.Header().ID())
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestSelect(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	const interval = thor.CheckpointInterval
+
+	head := c.extend(c.repo.GenesisBlock().Header(), interval-1, keys, true)
+	forkPoint := head
+
+	// best chain justifies round 1
+	head = c.extend(head, interval, keys, true)
+	// a heavier branch with only 2 voters can't justify it
+	fork := forkPoint
+	for i := 0; i < interval; i++ {
+		blk := c.newBlock(fork, keys[i%2], true, 2)
+		conflicts, err := c.repo.ScanConflicts(blk.Header().Number())
+		assert.Nil(t, err)
+		assert.Nil(t, c.repo.AddBlock(blk, nil, conflicts))
+		fork = blk.Header()
+	}
+	assert.True(t, fork.BetterThan(head))
+
+	better, err := c.engine.Select(fork)
+	assert.Nil(t, err)
+	assert.False(t, better)
+
+	// same quality, falls back to total score
+	better, err = c.engine.Select(c.newBlock(head, keys[0], true, 2).Header())
+	assert.Nil(t, err)
+	assert.True(t, better)
+	better, err = c.engine.Select(c.newBlock(forkPoint, keys[0], true, 1).Header())
+	assert.Nil(t, err)
+	assert.False(t, better)
+}
+
+func TestShouldVote(t *testing.T) {
+	master := keys[0]
+	c := newTestChain(t, keyAddr(master))
+	const interval = thor.CheckpointInterval
+
+	// not in the first round of finality
+	head := c.extend(c.repo.GenesisBlock().Header(), interval+10, keys, true)
+	vote, err := c.engine.ShouldVote(head.ID())
+	assert.Nil(t, err)
+	assert.False(t, vote)
+
+	head = c.extend(head, interval, keys, true)
+	vote, err = c.engine.ShouldVote(head.ID())
+	assert.Nil(t, err)
+	assert.True(t, vote)
+
+	// casts rebuilt from the master's COM blocks
+	assert.NotEmpty(t, c.engine.casts)
+	for checkpoint := range c.engine.casts {
+		assert.True(t, isCheckPoint(block.Number(checkpoint)))
+	}
+
+	// marked when packing
+	blk := c.newBlock(head, master, true, 1)
+	assert.Nil(t, c.repo.AddBlock(blk, nil, 0))
+	assert.Nil(t, c.engine.CommitBlock(blk.Header(), true))
+	assert.Equal(t, uint32(2), c.engine.casts[c.blockID(getCheckPoint(blk.Header().Number()))])
+}
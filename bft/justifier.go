@@ -14,6 +14,10 @@ type bftState struct {
 	Quality   uint32 // accumulated justified block count
 	Justified bool
 	Committed bool
+
+	Votes     uint64 // count of distinct signers in the round
+	COMVotes  uint64 // count of distinct signers voted COM in the round
+	Threshold uint64 // votes should exceed the threshold to justify or commit the round
 }
 
 // justifier tracks all block vote in one bft round and justify the round.
@@ -29,40 +33,40 @@ type justifier struct {
 func (engine *Engine) newJustifier(parentID thor.Bytes32) (*justifier, error) {
 	blockNum := block.Number(parentID) + 1
 
-	//var lastOfParentRound uint32
+	var lastOfParentRound uint32
 	checkpoint := getCheckPoint(blockNum)
-	//if checkpoint > 0 {
-	//	lastOfParentRound = checkpoint - 1
-	//} else {
-	//	lastOfParentRound = 0
-	//}
-
-	//sum, err := engine.repo.NewChain(parentID).GetBlockSummary(lastOfParentRound)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//mbp, err := engine.getMaxBlockProposers(sum)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//threshold := mbp * 2 / 3
+	if checkpoint > 0 {
+		lastOfParentRound = checkpoint - 1
+	} else {
+		lastOfParentRound = 0
+	}
+
+	sum, err := engine.repo.NewChain(parentID).GetBlockSummary(lastOfParentRound)
+	if err != nil {
+		return nil, err
+	}
+	mbp, err := engine.getMaxBlockProposers(sum)
+	if err != nil {
+		return nil, err
+	}
+	threshold := mbp * 2 / 3
 
 	var parentQuality uint32 // quality of last round
 	if absRound := blockNum/thor.CheckpointInterval - engine.forkConfig.FINALITY/thor.CheckpointInterval; absRound == 0 {
 		parentQuality = 0
 	} else {
-		//var err error
-		//parentQuality, err = engine.getQuality(sum.Header)
-		//if err != nil {
-		//	return nil, err
-		//}
+		var err error
+		parentQuality, err = engine.getQuality(sum.Header.ID())
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &justifier{
 		votes:         make(map[thor.Address]bool),
 		parentQuality: parentQuality,
 		checkpoint:    checkpoint,
-		//threshold:     threshold,
+		threshold:     threshold,
 	}, nil
 }
 
@@ -82,6 +86,11 @@ func (js *justifier) AddBlock(signer thor.Address, isCOM bool) {
 	}
 }
 
+// isCommitted returns whether the round is committed, votes collected afterwards are ignored.
+func (js *justifier) isCommitted() bool {
+	return js.comVotes > js.threshold
+}
+
 // Summarize summarizes the state of vote set.
 func (js *justifier) Summarize() *bftState {
 	justified := len(js.votes) > int(js.threshold)
@@ -96,6 +105,9 @@ func (js *justifier) Summarize() *bftState {
 	return &bftState{
 		Quality:   quality,
 		Justified: justified,
-		Committed: js.comVotes > js.threshold,
+		Committed: js.isCommitted(),
+		Votes:     uint64(len(js.votes)),
+		COMVotes:  js.comVotes,
+		Threshold: js.threshold,
 	}
 }
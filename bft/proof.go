@@ -0,0 +1,406 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+package bft
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/trie"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+)
+
+// ErrNotFinalized is returned when proving a block not on the finalized chain.
+var ErrNotFinalized = errors.New("block not finalized")
+
+// maxProofHeaders limits the headers of a finality proof, which allows one unjustified round
+// between the block and the justified round.
+const maxProofHeaders = thor.CheckpointInterval * 4
+
+// authorityHeadKey mirrors the storage key of the head of the authority list.
+var authorityHeadKey = thor.Blake2b([]byte("head"))
+
+// authorityEntry mirrors the storage layout of the authority entry.
+type authorityEntry struct {
+	Endorsor thor.Address
+	Identity thor.Bytes32
+	Active   bool
+	Prev     *thor.Address `rlp:"nil"`
+	Next     *thor.Address `rlp:"nil"`
+}
+
+// FinalityProof proves that a block is finalized, by showing that the round containing or following
+// the block is justified, and the next round is committed.
+//
+// Headers are contiguous, from the block or the last block before the justified round, whichever
+// is lower, to the last block of the committed round. Proposers of each round are authenticated by
+// the trie nodes against the state root of the last block of the previous round. So the proof is
+// anchored at its first header, which is expected to be trusted by the verifier. A proof spans
+// at most maxProofHeaders headers.
+type FinalityProof struct {
+	BlockID thor.Bytes32
+	Headers []*block.Header
+	Nodes   [][]byte // trie nodes of the states of proposers, params and authority
+}
+
+// Verify verifies the proof and returns the header of the proved block.
+func (p *FinalityProof) Verify() (*block.Header, error) {
+	if len(p.Headers) == 0 {
+		return nil, errors.New("no headers")
+	}
+	if len(p.Headers) > maxProofHeaders {
+		return nil, errors.New("too many headers")
+	}
+	signers := make([]thor.Address, len(p.Headers))
+	for i, h := range p.Headers {
+		if i > 0 && (h.ParentID() != p.Headers[i-1].ID() || h.Number() != p.Headers[i-1].Number()+1) {
+			return nil, errors.Errorf("header %v: not contiguous", h.ID())
+		}
+		signer, err := h.Signer()
+		if err != nil {
+			return nil, errors.Wrapf(err, "header %v: signer", h.ID())
+		}
+		signers[i] = signer
+	}
+
+	base, last := p.Headers[0].Number(), p.Headers[len(p.Headers)-1].Number()
+	if getStorePoint(last) != last {
+		return nil, errors.New("last header is not at the end of round")
+	}
+	committed := getCheckPoint(last)
+	if committed < thor.CheckpointInterval*2 {
+		return nil, errors.New("no justified round before the committed round")
+	}
+	justified := committed - thor.CheckpointInterval
+	if base >= justified {
+		return nil, errors.New("missing the last block before the justified round")
+	}
+
+	db := newProofDB(p.Nodes)
+	for _, checkpoint := range []uint32{justified, committed} {
+		anchor := p.Headers[checkpoint-1-base]
+		st, err := db.tally(anchor.StateRoot(), p.Headers[checkpoint-base:checkpoint-base+thor.CheckpointInterval], signers[checkpoint-base:checkpoint-base+thor.CheckpointInterval])
+		if err != nil {
+			return nil, errors.Wrapf(err, "round %v", checkpoint)
+		}
+		if !st.Justified {
+			return nil, errors.Errorf("round %v: not justified", checkpoint)
+		}
+		if checkpoint == committed && !st.Committed {
+			return nil, errors.Errorf("round %v: not committed", checkpoint)
+		}
+	}
+
+	num := block.Number(p.BlockID)
+	if num < base || num > justified {
+		return nil, errors.New("block not covered")
+	}
+	if h := p.Headers[num-base]; h.ID() == p.BlockID {
+		return h, nil
+	}
+	return nil, errors.New("block not covered")
+}
+
+// ProveFinality generates the finality proof of the given block, which should be on the finalized chain.
+// The block is not provable if no finalizing rounds follow it within the span of a proof.
+// It's safe to be called concurrently.
+func (engine *Engine) ProveFinality(blockID thor.Bytes32) (*FinalityProof, error) {
+	finalized := engine.Finalized()
+	if has, err := engine.repo.NewChain(finalized).HasBlock(blockID); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrNotFinalized
+	}
+
+	best := engine.repo.NewBestChain()
+	num := block.Number(blockID)
+	checkpoint := getCheckPoint(num)
+	if checkpoint < num {
+		checkpoint += thor.CheckpointInterval
+	}
+	// rounds started before finality activated are not provable
+	if checkpoint < engine.forkConfig.FINALITY {
+		checkpoint = getCheckPoint(engine.forkConfig.FINALITY)
+		if checkpoint < engine.forkConfig.FINALITY {
+			checkpoint += thor.CheckpointInterval
+		}
+	}
+
+	// search for a justified round followed by a committed round, within the span of a proof
+	for ; checkpoint <= block.Number(finalized); checkpoint += thor.CheckpointInterval {
+		base := checkpoint - 1
+		if num < base {
+			base = num
+		}
+		end := getStorePoint(checkpoint + thor.CheckpointInterval)
+		if end-base+1 > maxProofHeaders {
+			break
+		}
+
+		ok, err := engine.isFinalizingRound(best, checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		proof := &FinalityProof{BlockID: blockID}
+		for n := base; n <= end; n++ {
+			header, err := best.GetBlockHeader(n)
+			if err != nil {
+				return nil, err
+			}
+			proof.Headers = append(proof.Headers, header)
+		}
+
+		nodes := make(map[thor.Bytes32][]byte)
+		for _, cp := range []uint32{checkpoint, checkpoint + thor.CheckpointInterval} {
+			if err := engine.proveProposers(proof.Headers[cp-1-base], nodes); err != nil {
+				return nil, err
+			}
+		}
+		for _, node := range nodes {
+			proof.Nodes = append(proof.Nodes, node)
+		}
+		sort.Slice(proof.Nodes, func(i, j int) bool {
+			return bytes.Compare(proof.Nodes[i], proof.Nodes[j]) < 0
+		})
+
+		if _, err := proof.Verify(); err != nil {
+			return nil, errors.Wrap(err, "verify proof")
+		}
+		return proof, nil
+	}
+	return nil, errors.New("no finalizing rounds within the proof span")
+}
+
+// isFinalizingRound checks whether the round starting at the checkpoint is justified, and the next round is committed.
+func (engine *Engine) isFinalizingRound(best *chain.Chain, checkpoint uint32) (bool, error) {
+	header, err := best.GetBlockHeader(getStorePoint(checkpoint))
+	if err != nil {
+		return false, err
+	}
+	st, err := engine.peekState(header)
+	if err != nil {
+		return false, err
+	}
+	if !st.Justified {
+		return false, nil
+	}
+
+	if header, err = best.GetBlockHeader(getStorePoint(checkpoint + thor.CheckpointInterval)); err != nil {
+		return false, err
+	}
+	if st, err = engine.peekState(header); err != nil {
+		return false, err
+	}
+	return st.Committed, nil
+}
+
+// proveProposers collects trie nodes proving the proposers picked at the state of the anchor, which are
+// the endorsed candidates in the order of the authority list, up to the max block proposers.
+func (engine *Engine) proveProposers(anchor *block.Header, nodes map[thor.Bytes32][]byte) error {
+	sum, err := engine.repo.GetBlockSummary(anchor.ID())
+	if err != nil {
+		return err
+	}
+	st := engine.stater.NewState(anchor.StateRoot(), anchor.Number(), sum.Conflicts, sum.SteadyNum)
+
+	collect := func(proof [][]byte, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, node := range proof {
+			nodes[thor.Blake2b(node)] = node
+		}
+		return nil
+	}
+
+	if err := collect(st.ProveAccount(builtin.Params.Address)); err != nil {
+		return err
+	}
+	for _, key := range []thor.Bytes32{thor.KeyMaxBlockProposers, thor.KeyProposerEndorsement} {
+		if err := collect(st.ProveStorage(builtin.Params.Address, key)); err != nil {
+			return err
+		}
+	}
+	if err := collect(st.ProveAccount(builtin.Authority.Address)); err != nil {
+		return err
+	}
+	if err := collect(st.ProveStorage(builtin.Authority.Address, authorityHeadKey)); err != nil {
+		return err
+	}
+
+	mbp, err := engine.getMaxBlockProposers(sum)
+	if err != nil {
+		return err
+	}
+	endorsement, err := builtin.Params.Native(st).Get(thor.KeyProposerEndorsement)
+	if err != nil {
+		return err
+	}
+	aut := builtin.Authority.Native(st)
+	next, err := aut.First()
+	if err != nil {
+		return err
+	}
+	for picked := uint64(0); next != nil && picked < mbp; {
+		master := *next
+		if err := collect(st.ProveStorage(builtin.Authority.Address, thor.BytesToBytes32(master[:]))); err != nil {
+			return err
+		}
+		_, endorsor, _, _, err := aut.Get(master)
+		if err != nil {
+			return err
+		}
+		if err := collect(st.ProveAccount(endorsor)); err != nil {
+			return err
+		}
+		bal, err := st.GetBalance(endorsor)
+		if err != nil {
+			return err
+		}
+		if bal.Cmp(endorsement) >= 0 {
+			picked++
+		}
+		if next, err = aut.Next(master); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type proofDB map[thor.Bytes32][]byte
+
+func newProofDB(nodes [][]byte) proofDB {
+	db := make(proofDB, len(nodes))
+	for _, node := range nodes {
+		db[thor.Blake2b(node)] = node
+	}
+	return db
+}
+
+// Get implements trie.DatabaseReader.
+func (db proofDB) Get(key []byte) ([]byte, error) {
+	return db[thor.BytesToBytes32(key)], nil
+}
+
+func (db proofDB) account(root thor.Bytes32, addr thor.Address) (*state.Account, error) {
+	enc, err, _ := trie.VerifyProof(root, thor.Blake2b(addr[:]).Bytes(), db)
+	if err != nil {
+		return nil, errors.Wrapf(err, "account %v", addr)
+	}
+	acc := state.Account{Balance: &big.Int{}, Energy: &big.Int{}}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &acc); err != nil {
+			return nil, err
+		}
+	}
+	return &acc, nil
+}
+
+func (db proofDB) storage(acc *state.Account, key thor.Bytes32) ([]byte, error) {
+	if len(acc.StorageRoot) == 0 {
+		return nil, nil
+	}
+	enc, err, _ := trie.VerifyProof(thor.BytesToBytes32(acc.StorageRoot), thor.Blake2b(key[:]).Bytes(), db)
+	if err != nil {
+		return nil, errors.Wrapf(err, "storage %v", key)
+	}
+	return enc, nil
+}
+
+func (db proofDB) param(params *state.Account, key thor.Bytes32) (*big.Int, error) {
+	raw, err := db.storage(params, key)
+	if err != nil {
+		return nil, err
+	}
+	value := &big.Int{}
+	if len(raw) > 0 {
+		if err := rlp.DecodeBytes(raw, &value); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// tally summarizes votes of the round like the justifier, but only votes of proposers picked at the
+// state root are counted. Proposers are picked the same way as poa.Candidates, the endorsed candidates
+// in the order of the authority list, up to the max block proposers.
+func (db proofDB) tally(root thor.Bytes32, round []*block.Header, signers []thor.Address) (*bftState, error) {
+	params, err := db.account(root, builtin.Params.Address)
+	if err != nil {
+		return nil, err
+	}
+	mbp, err := db.param(params, thor.KeyMaxBlockProposers)
+	if err != nil {
+		return nil, err
+	}
+	maxBlockProposers := mbp.Uint64()
+	if maxBlockProposers == 0 || maxBlockProposers > thor.InitialMaxBlockProposers {
+		maxBlockProposers = thor.InitialMaxBlockProposers
+	}
+	endorsement, err := db.param(params, thor.KeyProposerEndorsement)
+	if err != nil {
+		return nil, err
+	}
+
+	authority, err := db.account(root, builtin.Authority.Address)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := db.storage(authority, authorityHeadKey)
+	if err != nil {
+		return nil, err
+	}
+	var next *thor.Address
+	if len(raw) > 0 {
+		if err := rlp.DecodeBytes(raw, &next); err != nil {
+			return nil, err
+		}
+	}
+
+	proposers := make(map[thor.Address]bool)
+	for next != nil && uint64(len(proposers)) < maxBlockProposers {
+		master := *next
+		raw, err := db.storage(authority, thor.BytesToBytes32(master[:]))
+		if err != nil {
+			return nil, err
+		}
+		var entry authorityEntry
+		if len(raw) > 0 {
+			if err := rlp.DecodeBytes(raw, &entry); err != nil {
+				return nil, err
+			}
+		}
+		endorsor, err := db.account(root, entry.Endorsor)
+		if err != nil {
+			return nil, err
+		}
+		if endorsor.Balance.Cmp(endorsement) >= 0 {
+			proposers[master] = true
+		}
+		next = entry.Next
+	}
+
+	js := &justifier{
+		votes:     make(map[thor.Address]bool),
+		threshold: maxBlockProposers * 2 / 3,
+	}
+	for i, h := range round {
+		if proposers[signers[i]] {
+			js.AddBlock(signers[i], h.COM())
+		}
+	}
+	return js.Summarize(), nil
+}
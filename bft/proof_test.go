@@ -0,0 +1,193 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+package bft
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProveFinality(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	const interval = thor.CheckpointInterval
+
+	head := c.extend(c.repo.GenesisBlock().Header(), interval*2-1, keys, true)
+	// nothing finalized yet
+	_, err := c.engine.ProveFinality(c.blockID(5))
+	assert.EqualError(t, err, "block not finalized")
+
+	c.extend(head, interval, keys, true)
+	assert.Equal(t, c.blockID(interval), c.engine.Finalized())
+
+	// the finalized checkpoint
+	proof, err := c.engine.ProveFinality(c.blockID(interval))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(interval-1), proof.Headers[0].Number())
+	assert.Equal(t, uint32(interval*3-1), proof.Headers[len(proof.Headers)-1].Number())
+	header, err := proof.Verify()
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval), header.ID())
+
+	// a block before the checkpoint, survives rlp encoding
+	proof, err = c.engine.ProveFinality(c.blockID(5))
+	assert.Nil(t, err)
+	data, err := rlp.EncodeToBytes(proof)
+	assert.Nil(t, err)
+	var decoded FinalityProof
+	assert.Nil(t, rlp.DecodeBytes(data, &decoded))
+	header, err = decoded.Verify()
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(5), header.ID())
+
+	_, err = c.engine.ProveFinality(c.blockID(interval + 1))
+	assert.EqualError(t, err, "block not finalized")
+}
+
+func TestVerifyFinalityProof(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	const interval = thor.CheckpointInterval
+	c.extend(c.repo.GenesisBlock().Header(), interval*3-1, keys, true)
+
+	newProof := func() *FinalityProof {
+		proof, err := c.engine.ProveFinality(c.blockID(interval))
+		assert.Nil(t, err)
+		return proof
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(p *FinalityProof)
+		errStr string
+	}{
+		{"no headers", func(p *FinalityProof) { p.Headers = nil }, "no headers"},
+		{"too many headers", func(p *FinalityProof) { p.Headers = append(p.Headers, p.Headers...) }, "too many headers"},
+		{"not contiguous", func(p *FinalityProof) {
+			p.Headers = append(p.Headers[:10], p.Headers[11:]...)
+		}, "not contiguous"},
+		{"incomplete round", func(p *FinalityProof) { p.Headers = p.Headers[:len(p.Headers)-1] }, "last header is not at the end of round"},
+		{"missing anchor", func(p *FinalityProof) { p.Headers = p.Headers[1:] }, "missing the last block before the justified round"},
+		{"missing state", func(p *FinalityProof) { p.Nodes = p.Nodes[1:] }, "missing"},
+		{"block not covered", func(p *FinalityProof) { p.BlockID = c.blockID(interval + 1) }, "block not covered"},
+		{"unknown block", func(p *FinalityProof) { p.BlockID = thor.Bytes32{} }, "block not covered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof := newProof()
+			tt.tamper(proof)
+			_, err := proof.Verify()
+			assert.ErrorContains(t, err, tt.errStr)
+		})
+	}
+}
+
+func TestVerifyFinalityProofNotCommitted(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	const interval = thor.CheckpointInterval
+	c.extend(c.repo.GenesisBlock().Header(), interval*3-1, keys, true)
+	proof, err := c.engine.ProveFinality(c.blockID(interval))
+	assert.Nil(t, err)
+
+	// replace the committed round with non-COM votes
+	base := proof.Headers[0].Number()
+	parent := proof.Headers[interval*2-1-base]
+	headers := append([]*block.Header(nil), proof.Headers[:interval*2-base]...)
+	for i := 0; i < int(interval); i++ {
+		blk := c.newBlock(parent, keys[int(parent.Number()+1)%len(keys)], false, 2)
+		headers = append(headers, blk.Header())
+		parent = blk.Header()
+	}
+	proof.Headers = headers
+	_, err = proof.Verify()
+	assert.ErrorContains(t, err, "not committed")
+}
+
+func TestProveFinalitySpan(t *testing.T) {
+	c := newTestChain(t, thor.Address{})
+	const interval = thor.CheckpointInterval
+
+	// the first two rounds are not justified
+	head := c.extend(c.repo.GenesisBlock().Header(), interval-1, keys, true)
+	head = c.extend(head, interval*2, keys[:2], true)
+	c.extend(head, interval*2, keys, true)
+	assert.Equal(t, c.blockID(interval*3), c.engine.Finalized())
+
+	// too far before the justified round
+	_, err := c.engine.ProveFinality(c.blockID(5))
+	assert.EqualError(t, err, "no finalizing rounds within the proof span")
+
+	proof, err := c.engine.ProveFinality(c.blockID(interval + 5))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(interval+5), proof.Headers[0].Number())
+	header, err := proof.Verify()
+	assert.Nil(t, err)
+	assert.Equal(t, c.blockID(interval+5), header.ID())
+}
+
+func TestTallyMaxBlockProposers(t *testing.T) {
+	db := muxdb.NewMem()
+	st := state.NewStater(db).NewState(thor.Bytes32{}, 0, 0, 0)
+	assert.Nil(t, st.SetCode(builtin.Params.Address, builtin.Params.RuntimeBytecodes()))
+	// 2 proposers at most, 2 votes required to justify
+	assert.Nil(t, builtin.Params.Native(st).Set(thor.KeyMaxBlockProposers, big.NewInt(2)))
+	assert.Nil(t, builtin.Params.Native(st).Set(thor.KeyProposerEndorsement, big.NewInt(1e18)))
+	assert.Nil(t, st.SetCode(builtin.Authority.Address, builtin.Authority.RuntimeBytecodes()))
+	// the first candidate is underfunded, then the last one exceeds the max block proposers
+	masters := []thor.Address{{1}, {2}, {3}, {4}}
+	for i, master := range masters {
+		ok, err := builtin.Authority.Native(st).Add(master, master, thor.Bytes32{})
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		if i > 0 {
+			assert.Nil(t, st.SetBalance(master, big.NewInt(1e18)))
+		}
+	}
+	stage, err := st.Stage(0, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	st = state.NewStater(db).NewState(root, 0, 0, 0)
+	var nodes [][]byte
+	collect := func(proof [][]byte, err error) {
+		assert.Nil(t, err)
+		nodes = append(nodes, proof...)
+	}
+	collect(st.ProveAccount(builtin.Params.Address))
+	collect(st.ProveStorage(builtin.Params.Address, thor.KeyMaxBlockProposers))
+	collect(st.ProveStorage(builtin.Params.Address, thor.KeyProposerEndorsement))
+	collect(st.ProveAccount(builtin.Authority.Address))
+	collect(st.ProveStorage(builtin.Authority.Address, authorityHeadKey))
+	for _, master := range masters {
+		collect(st.ProveStorage(builtin.Authority.Address, thor.BytesToBytes32(master[:])))
+		collect(st.ProveAccount(master))
+	}
+	proofDB := newProofDB(nodes)
+
+	round := make([]*block.Header, 2)
+	for i := range round {
+		round[i] = new(block.Builder).COM().Build().Header()
+	}
+	tests := []struct {
+		signers   []thor.Address
+		committed bool
+	}{
+		{[]thor.Address{masters[1], masters[2]}, true},
+		{[]thor.Address{masters[0], masters[1]}, false},
+		{[]thor.Address{masters[2], masters[3]}, false},
+	}
+	for _, tt := range tests {
+		st, err := proofDB.tally(root, round, tt.signers)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.committed, st.Committed, "%v", tt.signers)
+	}
+}
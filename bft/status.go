@@ -0,0 +1,90 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+package bft
+
+import (
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/pkg/errors"
+)
+
+// Status is the finality status regarding the best block.
+type Status struct {
+	Finalized   thor.Bytes32
+	Justified   thor.Bytes32
+	Round       RoundStatus        // the round that the best block belongs to
+	Checkpoints []CheckpointStatus // concluded rounds before the current round, latest first
+}
+
+// RoundStatus is the vote statistics of a bft round.
+type RoundStatus struct {
+	Checkpoint uint32 // number of the checkpoint of the round
+	Quality    uint32
+	Justified  bool
+	Committed  bool
+	Votes      uint64 // count of distinct signers
+	COMVotes   uint64 // count of distinct signers voted COM
+	Threshold  uint64 // votes should exceed the threshold to justify or commit the round
+}
+
+// CheckpointStatus is the quality of a concluded round.
+type CheckpointStatus struct {
+	ID      thor.Bytes32 // id of the checkpoint
+	Quality uint32       // quality saved at the store point of the round
+}
+
+// Status reports the finality status regarding the best block, including the qualities of
+// at most the given count of recent concluded rounds. It's safe to be called concurrently.
+func (engine *Engine) Status(rounds int) (*Status, error) {
+	best := engine.repo.BestBlockSummary().Header
+
+	justified, err := engine.Justified()
+	if err != nil {
+		return nil, errors.Wrap(err, "justified")
+	}
+
+	st, err := engine.peekState(best)
+	if err != nil {
+		return nil, errors.Wrap(err, "compute state")
+	}
+
+	status := &Status{
+		Finalized: engine.Finalized(),
+		Justified: justified,
+		Round: RoundStatus{
+			Checkpoint: getCheckPoint(best.Number()),
+			Quality:    st.Quality,
+			Justified:  st.Justified,
+			Committed:  st.Committed,
+			Votes:      st.Votes,
+			COMVotes:   st.COMVotes,
+			Threshold:  st.Threshold,
+		},
+		Checkpoints: []CheckpointStatus{},
+	}
+
+	chain := engine.repo.NewChain(best.ID())
+	for checkpoint := status.Round.Checkpoint; checkpoint >= thor.CheckpointInterval && len(status.Checkpoints) < rounds; {
+		checkpoint -= thor.CheckpointInterval
+		// rounds concluded before finality activated have no quality
+		if getStorePoint(checkpoint) < engine.forkConfig.FINALITY {
+			break
+		}
+
+		storePoint, err := chain.GetBlockID(getStorePoint(checkpoint))
+		if err != nil {
+			return nil, err
+		}
+		quality, err := engine.getQuality(storePoint)
+		if err != nil {
+			return nil, err
+		}
+		id, err := chain.GetBlockID(checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		status.Checkpoints = append(status.Checkpoints, CheckpointStatus{ID: id, Quality: quality})
+	}
+	return status, nil
+}
@@ -1,75 +1,73 @@
-// // Copyright (c) 2018 The VeChainThor developers
-//
-// // Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
-// // file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
 package builtin
 
-//
-//import (
-//	"github.com/ashkanabbasii/thor/abi"
-//	"github.com/ashkanabbasii/thor/builtin/authority"
-//	"github.com/ashkanabbasii/thor/builtin/energy"
-//	"github.com/ashkanabbasii/thor/builtin/gen"
-//	"github.com/ashkanabbasii/thor/builtin/params"
-//	"github.com/ashkanabbasii/thor/builtin/prototype"
-//	//"github.com/ashkanabbasii/thor/state"
-//	"github.com/ashkanabbasii/thor/thor"
-//	//"github.com/ashkanabbasii/thor/xenv"
-//	"github.com/pkg/errors"
-//)
-//
-//// Builtin contracts binding.
-//var (
-//	Params    = &paramsContract{mustLoadContract("Params")}
-//	Authority = &authorityContract{mustLoadContract("Authority")}
-//	Energy    = &energyContract{mustLoadContract("Energy")}
-//	Executor  = &executorContract{mustLoadContract("Executor")}
-//	Prototype = &prototypeContract{mustLoadContract("Prototype")}
-//	Extension = &extensionContract{
-//		mustLoadContract("Extension"),
-//		mustLoadContract("ExtensionV2"),
-//	}
-//	Measure = mustLoadContract("Measure")
-//)
-//
-//type (
-//	paramsContract    struct{ *contract }
-//	authorityContract struct{ *contract }
-//	energyContract    struct{ *contract }
-//	executorContract  struct{ *contract }
-//	prototypeContract struct{ *contract }
-//	extensionContract struct {
-//		*contract
-//		V2 *contract
-//	}
-//)
-//
-//func (p *paramsContract) Native(state *state.State) *params.Params {
-//	return params.New(p.Address, state)
-//}
-//
-//func (a *authorityContract) Native(state *state.State) *authority.Authority {
-//	return authority.New(a.Address, state)
-//}
-//
-//func (e *energyContract) Native(state *state.State, blockTime uint64) *energy.Energy {
-//	return energy.New(e.Address, state, blockTime)
-//}
-//
-//func (p *prototypeContract) Native(state *state.State) *prototype.Prototype {
-//	return prototype.New(p.Address, state)
-//}
-//
-//func (p *prototypeContract) Events() *abi.ABI {
-//	asset := "compiled/PrototypeEvent.abi"
-//	data := gen.MustAsset(asset)
-//	abi, err := abi.New(data)
-//	if err != nil {
-//		panic(errors.Wrap(err, "load ABI for "+asset))
-//	}
-//	return abi
-//}
-//
+import (
+	"github.com/ashkanabbasii/thor/abi"
+	"github.com/ashkanabbasii/thor/builtin/authority"
+	"github.com/ashkanabbasii/thor/builtin/energy"
+	"github.com/ashkanabbasii/thor/builtin/gen"
+	"github.com/ashkanabbasii/thor/builtin/params"
+	"github.com/ashkanabbasii/thor/builtin/prototype"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/pkg/errors"
+)
+
+// Builtin contracts binding.
+var (
+	Params    = &paramsContract{mustLoadContract("Params")}
+	Authority = &authorityContract{mustLoadContract("Authority")}
+	Energy    = &energyContract{mustLoadContract("Energy")}
+	Executor  = &executorContract{mustLoadContract("Executor")}
+	Prototype = &prototypeContract{mustLoadContract("Prototype")}
+	Extension = &extensionContract{
+		mustLoadContract("Extension"),
+		mustLoadContract("ExtensionV2"),
+	}
+	Measure = mustLoadContract("Measure")
+)
+
+type (
+	paramsContract    struct{ *contract }
+	authorityContract struct{ *contract }
+	energyContract    struct{ *contract }
+	executorContract  struct{ *contract }
+	prototypeContract struct{ *contract }
+	extensionContract struct {
+		*contract
+		V2 *contract
+	}
+)
+
+func (p *paramsContract) Native(state *state.State) *params.Params {
+	return params.New(p.Address, state)
+}
+
+func (a *authorityContract) Native(state *state.State) *authority.Authority {
+	return authority.New(a.Address, state)
+}
+
+func (e *energyContract) Native(state *state.State, blockTime uint64) *energy.Energy {
+	return energy.New(e.Address, state, blockTime)
+}
+
+func (p *prototypeContract) Native(state *state.State) *prototype.Prototype {
+	return prototype.New(p.Address, state)
+}
+
+func (p *prototypeContract) Events() *abi.ABI {
+	asset := "compiled/PrototypeEvent.abi"
+	data := gen.MustAsset(asset)
+	abi, err := abi.New(data)
+	if err != nil {
+		panic(errors.Wrap(err, "load ABI for "+asset))
+	}
+	return abi
+}
+
 //type nativeMethod struct {
 //	abi *abi.Method
 //	run func(env *xenv.Environment) []interface{}
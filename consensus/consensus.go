@@ -1,101 +1,147 @@
-// // Copyright (c) 2018 The VeChainThor developers
-//
-// // Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
-// // file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
 package consensus
 
-//
-//import (
-//	"errors"
-//	"fmt"
-//
-//	"github.com/ashkanabbasii/thor/block"
-//	"github.com/ashkanabbasii/thor/chain"
-//	"github.com/ashkanabbasii/thor/poa"
-//	"github.com/hashicorp/golang-lru/simplelru"
-//
-//	"github.com/ashkanabbasii/thor/runtime"
-//	"github.com/ashkanabbasii/thor/state"
-//	"github.com/ashkanabbasii/thor/thor"
-//	"github.com/ashkanabbasii/thor/tx"
-//	"github.com/ashkanabbasii/thor/xenv"
-//)
-//
-//// Consensus check whether the block is verified,
-//// and predicate which trunk it belong to.
-//type Consensus struct {
-//	repo                 *chain.Repository
-//	stater               *state.Stater
-//	seeder               *poa.Seeder
-//	forkConfig           thor.ForkConfig
-//	correctReceiptsRoots map[string]string
-//	candidatesCache      *simplelru.LRU
-//}
-//
-//// New create a Consensus instance.
-//func New(repo *chain.Repository, stater *state.Stater, forkConfig thor.ForkConfig) *Consensus {
-//	candidatesCache, _ := simplelru.NewLRU(16, nil)
-//	return &Consensus{
-//		repo:                 repo,
-//		stater:               stater,
-//		seeder:               poa.NewSeeder(repo),
-//		forkConfig:           forkConfig,
-//		correctReceiptsRoots: thor.LoadCorrectReceiptsRoots(),
-//		candidatesCache:      candidatesCache,
-//	}
-//}
-//
-//// Process process a block.
-//func (c *Consensus) Process(parentSummary *chain.BlockSummary, blk *block.Block, nowTimestamp uint64, blockConflicts uint32) (*state.Stage, tx.Receipts, error) {
-//	header := blk.Header()
-//	state := c.stater.NewState(parentSummary.Header.StateRoot(), parentSummary.Header.Number(), parentSummary.Conflicts, parentSummary.SteadyNum)
-//
-//	var features tx.Features
-//	if header.Number() >= c.forkConfig.VIP191 {
-//		features |= tx.DelegationFeature
-//	}
-//
-//	if header.TxsFeatures() != features {
-//		return nil, nil, consensusError(fmt.Sprintf("block txs features invalid: want %v, have %v", features, header.TxsFeatures()))
-//	}
-//
-//	stage, receipts, err := c.validate(state, blk, parentSummary.Header, nowTimestamp, blockConflicts)
-//	if err != nil {
-//		return nil, nil, err
-//	}
-//
-//	return stage, receipts, nil
-//}
-//
-//func (c *Consensus) NewRuntimeForReplay(header *block.Header, skipPoA bool) (*runtime.Runtime, error) {
-//	signer, err := header.Signer()
-//	if err != nil {
-//		return nil, err
-//	}
-//	parentSummary, err := c.repo.GetBlockSummary(header.ParentID())
-//	if err != nil {
-//		if !c.repo.IsNotFound(err) {
-//			return nil, err
-//		}
-//		return nil, errors.New("parent block is missing")
-//	}
-//	state := c.stater.NewState(parentSummary.Header.StateRoot(), parentSummary.Header.Number(), parentSummary.Conflicts, parentSummary.SteadyNum)
-//	if !skipPoA {
-//		if _, err := c.validateProposer(header, parentSummary.Header, state); err != nil {
-//			return nil, err
-//		}
-//	}
-//
-//	return runtime.New(
-//		c.repo.NewChain(header.ParentID()),
-//		state,
-//		&xenv.BlockContext{
-//			Beneficiary: header.Beneficiary(),
-//			Signer:      signer,
-//			Number:      header.Number(),
-//			Time:        header.Timestamp(),
-//			GasLimit:    header.GasLimit(),
-//			TotalScore:  header.TotalScore(),
-//		},
-//		c.forkConfig), nil
-//}
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/log"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/runtime"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/xenv"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+var logger = log.WithContext("pkg", "consensus")
+
+// Consensus check whether the block is verified,
+// and predicate which trunk it belong to.
+type Consensus struct {
+	repo                 *chain.Repository
+	stater               *state.Stater
+	seeder               *poa.Seeder
+	forkConfig           thor.ForkConfig
+	correctReceiptsRoots map[string]string
+	candidatesCache      *simplelru.LRU
+}
+
+// New create a Consensus instance.
+func New(repo *chain.Repository, stater *state.Stater, seeder *poa.Seeder, forkConfig thor.ForkConfig) *Consensus {
+	candidatesCache, _ := simplelru.NewLRU(16, nil)
+	return &Consensus{
+		repo:                 repo,
+		stater:               stater,
+		seeder:               seeder,
+		forkConfig:           forkConfig,
+		correctReceiptsRoots: thor.GetCorrectReceiptsRoots(repo.GenesisBlock().Header().ID()),
+		candidatesCache:      candidatesCache,
+	}
+}
+
+// Process process a block.
+func (c *Consensus) Process(parentSummary *chain.BlockSummary, blk *block.Block, nowTimestamp uint64, blockConflicts uint32) (*state.Stage, tx.Receipts, error) {
+	header := blk.Header()
+	state := c.stater.NewState(parentSummary.Header.StateRoot(), parentSummary.Header.Number(), parentSummary.Conflicts, parentSummary.SteadyNum)
+
+	// Before process hook of VIP-191, update builtin extension contract's code to V2
+	vip191 := c.forkConfig.VIP191
+	if vip191 == 0 {
+		vip191 = 1
+	}
+	if header.Number() == vip191 {
+		if err := state.SetCode(builtin.Extension.Address, builtin.Extension.V2.RuntimeBytecodes()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var features tx.Features
+	if header.Number() >= c.forkConfig.VIP191 {
+		features |= tx.DelegationFeature
+	}
+
+	if header.TxsFeatures() != features {
+		return nil, nil, consensusError(fmt.Sprintf("block txs features invalid: want %v, have %v", features, header.TxsFeatures()))
+	}
+
+	stage, receipts, err := c.validate(state, blk, parentSummary.Header, nowTimestamp, blockConflicts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stage, receipts, nil
+}
+
+// NewRuntimeForReplay creates a runtime to replay the txs of the given block.
+func (c *Consensus) NewRuntimeForReplay(header *block.Header, skipPoA bool) (*runtime.Runtime, error) {
+	signer, err := header.Signer()
+	if err != nil {
+		return nil, err
+	}
+	parentSummary, err := c.repo.GetBlockSummary(header.ParentID())
+	if err != nil {
+		if !c.repo.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, errors.New("parent block is missing")
+	}
+	state := c.stater.NewState(parentSummary.Header.StateRoot(), parentSummary.Header.Number(), parentSummary.Conflicts, parentSummary.SteadyNum)
+	if !skipPoA {
+		if _, err := c.validateProposer(header, parentSummary.Header, state); err != nil {
+			return nil, err
+		}
+	}
+
+	return runtime.New(
+		c.repo.NewChain(header.ParentID()),
+		state,
+		&xenv.BlockContext{
+			Beneficiary: header.Beneficiary(),
+			Signer:      signer,
+			Number:      header.Number(),
+			Time:        header.Timestamp(),
+			GasLimit:    header.GasLimit(),
+			TotalScore:  header.TotalScore(),
+		},
+		c.forkConfig), nil
+}
+
+// CheckReceiptsRootCorrections checks whether blocks with corrected receipts root are reached by the given chain.
+// It returns the number of reached ones, and ids of the ones not in the chain while the chain has passed their height.
+func (c *Consensus) CheckReceiptsRootCorrections(chain *chain.Chain) (reached int, missing []thor.Bytes32, err error) {
+	headNum := block.Number(chain.HeadID())
+	for str := range c.correctReceiptsRoots {
+		id, err := thor.ParseBytes32(str)
+		if err != nil {
+			return 0, nil, err
+		}
+		if block.Number(id) > headNum {
+			// not synced yet
+			continue
+		}
+		has, err := chain.HasBlock(id)
+		if err != nil {
+			return 0, nil, err
+		}
+		if has {
+			reached++
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return bytes.Compare(missing[i].Bytes(), missing[j].Bytes()) < 0
+	})
+	return reached, missing, nil
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/packer"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	masterKey, _ = crypto.GenerateKey()
+	senderKey, _ = crypto.GenerateKey()
+	endorsor     = thor.BytesToAddress([]byte("endorsor"))
+	recipient    = thor.BytesToAddress([]byte("recipient"))
+)
+
+func keyAddr(key *ecdsa.PrivateKey) thor.Address {
+	return thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+}
+
+type testEnv struct {
+	repo       *chain.Repository
+	stater     *state.Stater
+	forkConfig thor.ForkConfig
+	cons       *Consensus
+}
+
+func newTestEnv(t *testing.T, forkConfig thor.ForkConfig) *testEnv {
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+
+	st := stater.NewState(thor.Bytes32{}, 0, 0, 0)
+	assert.Nil(t, st.SetCode(builtin.Authority.Address, builtin.Authority.RuntimeBytecodes()))
+	_, err := builtin.Authority.Native(st).Add(keyAddr(masterKey), endorsor, thor.Bytes32{})
+	assert.Nil(t, err)
+	assert.Nil(t, st.SetBalance(keyAddr(senderKey), big.NewInt(1e18)))
+	assert.Nil(t, st.SetEnergy(keyAddr(senderKey), new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6)), 0))
+	stage, err := st.Stage(0, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+
+	return &testEnv{repo, stater, forkConfig, New(repo, stater, poa.NewSeeder(repo, db), forkConfig)}
+}
+
+func newTransferTx(chainTag byte, nonce uint64) *tx.Transaction {
+	return tx.MustSign(new(tx.Builder).
+		ChainTag(chainTag).
+		Clause(tx.NewClause(&recipient).WithValue(big.NewInt(100))).
+		Gas(21000).
+		Expiration(100).
+		Nonce(nonce).
+		Build(), senderKey)
+}
+
+// pack packs a valid block with a transfer tx upon the best block.
+func (env *testEnv) pack(t *testing.T) *block.Block {
+	best := env.repo.BestBlockSummary()
+	flow, err := packer.New(env.repo, env.stater, poa.NewSeeder(env.repo, nil), keyAddr(masterKey), nil, env.forkConfig).Schedule(best, best.Header.Timestamp())
+	assert.Nil(t, err)
+	assert.Nil(t, flow.Adopt(newTransferTx(env.repo.ChainTag(), uint64(best.Header.Number()))))
+	blk, _, _, err := flow.Pack(masterKey, 0, false)
+	assert.Nil(t, err)
+	return blk
+}
+
+func (env *testEnv) process(blk *block.Block) (*state.Stage, tx.Receipts, error) {
+	return env.cons.Process(env.repo.BestBlockSummary(), blk, blk.Header().Timestamp(), 0)
+}
+
+// rebuild builds a block like the base one, with fields overridden by the given func, and signs it.
+func rebuild(t *testing.T, base *block.Block, key *ecdsa.PrivateKey, override func(b *block.Builder)) *block.Block {
+	h := base.Header()
+	builder := new(block.Builder).
+		ParentID(h.ParentID()).
+		Timestamp(h.Timestamp()).
+		TotalScore(h.TotalScore()).
+		GasLimit(h.GasLimit()).
+		GasUsed(h.GasUsed()).
+		Beneficiary(h.Beneficiary()).
+		StateRoot(h.StateRoot()).
+		ReceiptsRoot(h.ReceiptsRoot()).
+		TransactionFeatures(h.TxsFeatures())
+	for _, trx := range base.Transactions() {
+		builder.Transaction(trx)
+	}
+	override(builder)
+
+	blk := builder.Build()
+	sig, err := crypto.Sign(blk.Header().SigningHash().Bytes(), key)
+	assert.Nil(t, err)
+	return blk.WithSignature(sig)
+}
+
+func TestProcess(t *testing.T) {
+	for _, forkConfig := range []thor.ForkConfig{thor.NoFork, {}} {
+		env := newTestEnv(t, forkConfig)
+
+		blk := env.pack(t)
+		stage, receipts, err := env.process(blk)
+		assert.Nil(t, err)
+		assert.Equal(t, blk.Header().StateRoot(), stage.Hash())
+		assert.Equal(t, blk.Header().ReceiptsRoot(), receipts.RootHash())
+
+		// next block
+		_, err = stage.Commit()
+		assert.Nil(t, err)
+		assert.Nil(t, env.repo.AddBlock(blk, receipts, 0))
+		assert.Nil(t, env.repo.SetBestBlockID(blk.Header().ID()))
+
+		blk = env.pack(t)
+		_, _, err = env.process(blk)
+		assert.Nil(t, err)
+
+		// replay
+		rt, err := env.cons.NewRuntimeForReplay(blk.Header(), false)
+		assert.Nil(t, err)
+		assert.Equal(t, blk.Header().Number(), rt.Context().Number)
+	}
+}
+
+func TestFutureBlock(t *testing.T) {
+	env := newTestEnv(t, thor.NoFork)
+	blk := env.pack(t)
+
+	_, _, err := env.cons.Process(env.repo.BestBlockSummary(), blk, blk.Header().Timestamp()-thor.BlockInterval-1, 0)
+	assert.True(t, IsFutureBlock(err), err)
+	assert.False(t, IsCritical(err))
+}
+
+func TestInvalidBlocks(t *testing.T) {
+	env := newTestEnv(t, thor.NoFork)
+	blk := env.pack(t)
+	h := blk.Header()
+	otherKey, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		name     string
+		key      *ecdsa.PrivateKey
+		override func(b *block.Builder)
+	}{
+		{"timestamp behind parent", masterKey, func(b *block.Builder) { b.Timestamp(env.repo.GenesisBlock().Header().Timestamp()) }},
+		{"timestamp not rounded", masterKey, func(b *block.Builder) { b.Timestamp(h.Timestamp() + 1) }},
+		{"timestamp unscheduled", masterKey, func(b *block.Builder) { b.Timestamp(h.Timestamp() + thor.BlockInterval) }},
+		{"gas limit invalid", masterKey, func(b *block.Builder) { b.GasLimit(h.GasLimit() * 2) }},
+		{"gas used exceeds limit", masterKey, func(b *block.Builder) { b.GasUsed(h.GasLimit() + 1) }},
+		{"gas used mismatch", masterKey, func(b *block.Builder) { b.GasUsed(h.GasUsed() + 1) }},
+		{"total score invalid", masterKey, func(b *block.Builder) { b.TotalScore(h.TotalScore() + 1) }},
+		{"signer invalid", otherKey, func(b *block.Builder) {}},
+		{"txs features invalid", masterKey, func(b *block.Builder) { b.TransactionFeatures(tx.DelegationFeature) }},
+		{"tx chain tag mismatch", masterKey, func(b *block.Builder) { b.Transaction(newTransferTx(env.repo.ChainTag()+1, 1)) }},
+		{"receipts root mismatch", masterKey, func(b *block.Builder) { b.ReceiptsRoot(thor.Bytes32{}) }},
+		{"state root mismatch", masterKey, func(b *block.Builder) { b.StateRoot(thor.Bytes32{}) }},
+		{"COM before finality", masterKey, func(b *block.Builder) { b.COM() }},
+		{"alpha before VIP214", masterKey, func(b *block.Builder) { b.Alpha([]byte("alpha")) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := env.process(rebuild(t, blk, tt.key, tt.override))
+			assert.True(t, IsCritical(err), err)
+		})
+	}
+}
+
+func TestInvalidVRF(t *testing.T) {
+	env := newTestEnv(t, thor.ForkConfig{})
+	blk := env.pack(t)
+
+	// plain signature after VIP214
+	_, _, err := env.process(rebuild(t, blk, masterKey, func(b *block.Builder) { b.Alpha(blk.Header().Alpha()) }))
+	assert.True(t, IsCritical(err), err)
+}
+
+func TestReceiptsRootCorrection(t *testing.T) {
+	env := newTestEnv(t, thor.NoFork)
+	blk := env.pack(t)
+
+	// packed with a wrong receipts root
+	bad := rebuild(t, blk, masterKey, func(b *block.Builder) { b.ReceiptsRoot(thor.Bytes32{1}) })
+	_, _, err := env.process(bad)
+	assert.True(t, IsCritical(err), err)
+
+	env.cons.correctReceiptsRoots = map[string]string{
+		bad.Header().ID().String(): blk.Header().ReceiptsRoot().String(),
+	}
+	stage, receipts, err := env.process(bad)
+	assert.Nil(t, err)
+	assert.Nil(t, env.cons.VerifyReceiptsRoot(bad.Header(), receipts))
+	// correction not matched
+	assert.True(t, IsCritical(env.cons.VerifyReceiptsRoot(bad.Header(), nil)))
+
+	_, err = stage.Commit()
+	assert.Nil(t, err)
+	assert.Nil(t, env.repo.AddBlock(bad, receipts, 0))
+	assert.Nil(t, env.repo.SetBestBlockID(bad.Header().ID()))
+
+	var (
+		forked = thor.Bytes32{0, 0, 0, 1, 1}
+		future = thor.Bytes32{0, 0, 0, 2, 1}
+	)
+	env.cons.correctReceiptsRoots[forked.String()] = thor.Bytes32{}.String()
+	env.cons.correctReceiptsRoots[future.String()] = thor.Bytes32{}.String()
+
+	reached, missing, err := env.cons.CheckReceiptsRootCorrections(env.repo.NewBestChain())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, reached)
+	assert.Equal(t, []thor.Bytes32{forked}, missing)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package equivocation
+
+import (
+	"sync"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/kv"
+	"github.com/ashkanabbasii/thor/log"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const dataStoreName = "equivocation"
+
+var logger = log.WithContext("pkg", "equivocation")
+
+type slotKey struct {
+	signer    thor.Address
+	timestamp uint64
+}
+
+type roundKey struct {
+	signer     thor.Address
+	checkpoint uint32
+}
+
+// Detector detects equivocations of block signers from the observed headers.
+// Found evidences are persisted, keyed by signer, and sent to subscribers.
+type Detector struct {
+	repo  *chain.Repository
+	data  kv.Store
+	lock  sync.Mutex
+	slots *lru.Cache // slotKey -> the first observed header
+	votes *lru.Cache // roundKey -> observed COM headers
+	feed  event.Feed
+	scope event.SubscriptionScope
+}
+
+// New creates a new detector.
+func New(repo *chain.Repository, mainDB *muxdb.MuxDB) *Detector {
+	d := &Detector{
+		repo: repo,
+		data: mainDB.NewStore(dataStoreName),
+	}
+	d.slots, _ = lru.New(8192)
+	d.votes, _ = lru.New(8192)
+	return d
+}
+
+// Observe indexes the header by its signer and returns the newly found evidences.
+// It should be fed with every imported header, whose parent must be in the repository.
+func (d *Detector) Observe(header *block.Header) ([]*Evidence, error) {
+	signer, err := header.Signer()
+	if err != nil {
+		return nil, errors.WithMessage(err, "signer")
+	}
+	id := header.ID()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var found []*Evidence
+
+	slot := slotKey{signer, header.Timestamp()}
+	if v, ok := d.slots.Get(slot); !ok {
+		d.slots.Add(slot, header)
+	} else if prev := v.(*block.Header); prev.ID() != id {
+		found = append(found, newEvidence(DoubleSign, signer, prev, header))
+	}
+
+	if header.COM() {
+		round := roundKey{signer, getCheckpoint(header.Number())}
+		var voted []*block.Header
+		if v, ok := d.votes.Get(round); ok {
+			voted = v.([]*block.Header)
+		}
+
+		seen := false
+		for _, prev := range voted {
+			if prev.ID() == id {
+				seen = true
+				break
+			}
+			conflicting, err := d.isConflicting(prev, header)
+			if err != nil {
+				return nil, err
+			}
+			if conflicting {
+				found = append(found, newEvidence(ConflictingVote, signer, prev, header))
+			}
+		}
+		if !seen {
+			d.votes.Add(round, append(voted[:len(voted):len(voted)], header))
+		}
+	}
+
+	var evidences []*Evidence
+	for _, ev := range found {
+		saved, err := d.save(ev)
+		if err != nil {
+			return nil, err
+		}
+		if !saved {
+			continue
+		}
+		logger.Warn("equivocation detected", "kind", ev.Kind, "signer", signer, "a", ev.Headers[0].ID(), "b", ev.Headers[1].ID())
+		metricEvidences().AddWithLabel(1, map[string]string{"kind": ev.Kind.String()})
+		d.feed.Send(ev)
+		evidences = append(evidences, ev)
+	}
+	return evidences, nil
+}
+
+// Evidences returns all saved evidences of the signer.
+func (d *Detector) Evidences(signer thor.Address) ([]*Evidence, error) {
+	iter := d.data.Iterate(kv.Range(*util.BytesPrefix(signer[:])))
+	defer iter.Release()
+
+	var evidences []*Evidence
+	for iter.Next() {
+		var ev Evidence
+		if err := rlp.DecodeBytes(iter.Value(), &ev); err != nil {
+			return nil, errors.WithMessage(err, "decode evidence")
+		}
+		evidences = append(evidences, &ev)
+	}
+	return evidences, iter.Error()
+}
+
+// Subscribe subscribes to newly found evidences.
+func (d *Detector) Subscribe(ch chan *Evidence) event.Subscription {
+	return d.scope.Track(d.feed.Subscribe(ch))
+}
+
+// Close closes all subscriptions.
+func (d *Detector) Close() {
+	d.scope.Close()
+}
+
+// isConflicting checks whether the two blocks are on different branches.
+func (d *Detector) isConflicting(a, b *block.Header) (bool, error) {
+	if a.Number() == b.Number() {
+		return true, nil
+	}
+	if a.Number() > b.Number() {
+		a, b = b, a
+	}
+	// ensure the branch is known, or HasBlock reports false
+	if _, err := d.repo.GetBlockSummary(b.ParentID()); err != nil {
+		return false, errors.WithMessage(err, "parent")
+	}
+	has, err := d.repo.NewChain(b.ParentID()).HasBlock(a.ID())
+	if err != nil {
+		return false, err
+	}
+	return !has, nil
+}
+
+// save persists the evidence, returns false if it's already saved.
+func (d *Detector) save(ev *Evidence) (bool, error) {
+	id := ev.ID()
+	key := append(ev.Signer.Bytes(), id[:]...)
+	if has, err := d.data.Has(key); err != nil {
+		return false, err
+	} else if has {
+		return false, nil
+	}
+
+	data, err := rlp.EncodeToBytes(ev)
+	if err != nil {
+		return false, err
+	}
+	if err := d.data.Put(key, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package equivocation
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRepo(t *testing.T) (*chain.Repository, *muxdb.MuxDB) {
+	db := muxdb.NewMem()
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+	return repo, db
+}
+
+func newBlock(t *testing.T, parent *block.Header, timestamp uint64, key *ecdsa.PrivateKey, com bool) *block.Block {
+	return newBlockWithScore(t, parent, timestamp, key, com, 1)
+}
+
+func newBlockWithScore(t *testing.T, parent *block.Header, timestamp uint64, key *ecdsa.PrivateKey, com bool, score uint64) *block.Block {
+	builder := new(block.Builder).
+		ParentID(parent.ID()).
+		Timestamp(timestamp).
+		TotalScore(parent.TotalScore() + score).
+		GasLimit(parent.GasLimit()).
+		ReceiptsRoot(tx.Receipts(nil).RootHash())
+	if com {
+		builder.COM()
+	}
+	blk := builder.Build()
+	sig, err := crypto.Sign(blk.Header().SigningHash().Bytes(), key)
+	assert.Nil(t, err)
+	return blk.WithSignature(sig)
+}
+
+func addBlock(t *testing.T, repo *chain.Repository, blk *block.Block) {
+	conflicts, err := repo.ScanConflicts(blk.Header().Number())
+	assert.Nil(t, err)
+	assert.Nil(t, repo.AddBlock(blk, nil, conflicts))
+}
+
+func TestDoubleSign(t *testing.T) {
+	repo, db := newTestRepo(t)
+	d := New(repo, db)
+	defer d.Close()
+
+	ch := make(chan *Evidence, 1)
+	sub := d.Subscribe(ch)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	signer := thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+	genesis := repo.GenesisBlock().Header()
+	a := newBlock(t, genesis, genesis.Timestamp()+thor.BlockInterval, key, false).Header()
+	b := newBlockWithScore(t, genesis, genesis.Timestamp()+thor.BlockInterval, key, false, 2).Header()
+
+	evidences, err := d.Observe(a)
+	assert.Nil(t, err)
+	assert.Empty(t, evidences)
+	// observed again
+	evidences, err = d.Observe(a)
+	assert.Nil(t, err)
+	assert.Empty(t, evidences)
+
+	evidences, err = d.Observe(b)
+	assert.Nil(t, err)
+	assert.Len(t, evidences, 1)
+	ev := evidences[0]
+	assert.Equal(t, DoubleSign, ev.Kind)
+	assert.Equal(t, signer, ev.Signer)
+	assert.ElementsMatch(t, []thor.Bytes32{a.ID(), b.ID()}, []thor.Bytes32{ev.Headers[0].ID(), ev.Headers[1].ID()})
+	assert.Nil(t, ev.Verify())
+	assert.Equal(t, ev, <-ch)
+
+	// evidence is emitted only once
+	evidences, err = d.Observe(b)
+	assert.Nil(t, err)
+	assert.Empty(t, evidences)
+
+	saved, err := d.Evidences(signer)
+	assert.Nil(t, err)
+	assert.Len(t, saved, 1)
+	assert.Equal(t, ev.ID(), saved[0].ID())
+	assert.Equal(t, signer, saved[0].Signer)
+	assert.Nil(t, saved[0].Verify())
+
+	saved, err = d.Evidences(thor.Address{})
+	assert.Nil(t, err)
+	assert.Empty(t, saved)
+}
+
+func TestConflictingVote(t *testing.T) {
+	repo, db := newTestRepo(t)
+	d := New(repo, db)
+	defer d.Close()
+
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	genesis := repo.GenesisBlock().Header()
+	ts := genesis.Timestamp()
+
+	// branch a: a1(key1, COM) <- a2(key2) <- a3(key1, COM)
+	a1 := newBlock(t, genesis, ts+thor.BlockInterval, key1, true)
+	a2 := newBlock(t, a1.Header(), ts+thor.BlockInterval*2, key2, false)
+	a3 := newBlock(t, a2.Header(), ts+thor.BlockInterval*3, key1, true)
+	// branch b: b1(key2) <- b2(key1, COM)
+	b1 := newBlock(t, genesis, ts+thor.BlockInterval*4, key2, false)
+	b2 := newBlock(t, b1.Header(), ts+thor.BlockInterval*5, key1, true).Header()
+
+	for _, blk := range []*block.Block{a1, a2, a3, b1} {
+		addBlock(t, repo, blk)
+		evidences, err := d.Observe(blk.Header())
+		assert.Nil(t, err)
+		assert.Empty(t, evidences, "votes on the same branch")
+	}
+
+	evidences, err := d.Observe(b2)
+	assert.Nil(t, err)
+	assert.Len(t, evidences, 2)
+	for i, other := range []*block.Header{a1.Header(), a3.Header()} {
+		assert.Equal(t, ConflictingVote, evidences[i].Kind)
+		assert.ElementsMatch(t, []thor.Bytes32{other.ID(), b2.ID()}, []thor.Bytes32{evidences[i].Headers[0].ID(), evidences[i].Headers[1].ID()})
+		assert.Nil(t, evidences[i].Verify())
+	}
+
+	// unknown parent
+	orphan := newBlock(t, b2, ts+thor.BlockInterval*6, key1, true).Header()
+	_, err = d.Observe(orphan)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyEvidence(t *testing.T) {
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	signer := thor.Address(crypto.PubkeyToAddress(key1.PublicKey))
+	genesis := new(block.Builder).Build().Header()
+
+	a := newBlock(t, genesis, 10, key1, true).Header()
+	b := newBlockWithScore(t, genesis, 10, key1, false, 2).Header()
+	c := newBlock(t, genesis, 20, key1, true).Header()
+	d := newBlock(t, genesis, 10, key2, true).Header()
+
+	ev := newEvidence(DoubleSign, signer, a, b)
+	data, err := rlp.EncodeToBytes(ev)
+	assert.Nil(t, err)
+	var decoded Evidence
+	assert.Nil(t, rlp.DecodeBytes(data, &decoded))
+	assert.Equal(t, ev.ID(), decoded.ID())
+	assert.Equal(t, signer, decoded.Signer)
+
+	tests := []struct {
+		ev     *Evidence
+		errStr string
+	}{
+		{newEvidence(DoubleSign, signer, a, b), ""},
+		{newEvidence(DoubleSign, signer, a, a), "identical headers"},
+		{newEvidence(DoubleSign, signer, a, c), "different slots"},
+		{newEvidence(DoubleSign, signer, a, d), "signer mismatch"},
+		{newEvidence(ConflictingVote, signer, a, c), ""},
+		{newEvidence(ConflictingVote, signer, a, b), "not COM votes"},
+		{newEvidence(Kind(0), signer, a, b), "unknown kind 0"},
+	}
+	for _, tt := range tests {
+		err := tt.ev.Verify()
+		if tt.errStr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.ErrorContains(t, err, tt.errStr)
+		}
+	}
+}
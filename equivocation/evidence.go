@@ -0,0 +1,133 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package equivocation
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+)
+
+// Kind is the kind of equivocation.
+type Kind uint8
+
+const (
+	// DoubleSign means two different blocks signed for the same slot.
+	DoubleSign Kind = iota + 1
+	// ConflictingVote means COM votes cast on conflicting branches in the same bft round.
+	ConflictingVote
+)
+
+func (k Kind) String() string {
+	switch k {
+	case DoubleSign:
+		return "double-sign"
+	case ConflictingVote:
+		return "conflicting-vote"
+	default:
+		return "unknown"
+	}
+}
+
+// Evidence proves the equivocation of a signer with the two headers it signed.
+type Evidence struct {
+	Kind    Kind
+	Signer  thor.Address
+	Headers [2]*block.Header // ordered by block id
+}
+
+func newEvidence(kind Kind, signer thor.Address, a, b *block.Header) *Evidence {
+	if aID, bID := a.ID(), b.ID(); bytes.Compare(aID[:], bID[:]) > 0 {
+		a, b = b, a
+	}
+	return &Evidence{
+		Kind:    kind,
+		Signer:  signer,
+		Headers: [2]*block.Header{a, b},
+	}
+}
+
+// ID returns the identifier of the evidence.
+func (e *Evidence) ID() thor.Bytes32 {
+	aID, bID := e.Headers[0].ID(), e.Headers[1].ID()
+	return thor.Blake2b([]byte{byte(e.Kind)}, aID[:], bID[:])
+}
+
+// Verify checks whether the evidence is self-consistent. For the ConflictingVote kind, that
+// the two blocks are on conflicting branches can only be checked against the chain.
+func (e *Evidence) Verify() error {
+	a, b := e.Headers[0], e.Headers[1]
+	if a == nil || b == nil {
+		return errors.New("missing header")
+	}
+	if a.ID() == b.ID() {
+		return errors.New("identical headers")
+	}
+	for _, h := range e.Headers {
+		signer, err := h.Signer()
+		if err != nil {
+			return errors.WithMessage(err, "signer")
+		}
+		if signer != e.Signer {
+			return errors.Errorf("signer mismatch: want %v, have %v", e.Signer, signer)
+		}
+	}
+
+	switch e.Kind {
+	case DoubleSign:
+		if a.Timestamp() != b.Timestamp() {
+			return errors.New("different slots")
+		}
+	case ConflictingVote:
+		if !a.COM() || !b.COM() {
+			return errors.New("not COM votes")
+		}
+		if getCheckpoint(a.Number()) != getCheckpoint(b.Number()) {
+			return errors.New("different rounds")
+		}
+	default:
+		return errors.Errorf("unknown kind %d", e.Kind)
+	}
+	return nil
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (e *Evidence) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, []interface{}{
+		e.Kind,
+		e.Headers[0],
+		e.Headers[1],
+	})
+}
+
+// DecodeRLP implements rlp.Decoder, the signer is recovered from the headers.
+func (e *Evidence) DecodeRLP(s *rlp.Stream) error {
+	var obj struct {
+		Kind Kind
+		A, B *block.Header
+	}
+	if err := s.Decode(&obj); err != nil {
+		return err
+	}
+	signer, err := obj.A.Signer()
+	if err != nil {
+		return err
+	}
+	*e = Evidence{
+		Kind:    obj.Kind,
+		Signer:  signer,
+		Headers: [2]*block.Header{obj.A, obj.B},
+	}
+	return nil
+}
+
+func getCheckpoint(blockNum uint32) uint32 {
+	return blockNum / thor.CheckpointInterval * thor.CheckpointInterval
+}
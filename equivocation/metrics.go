@@ -0,0 +1,14 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package equivocation
+
+import (
+	"github.com/ashkanabbasii/thor/metrics"
+)
+
+var (
+	metricEvidences = metrics.LazyLoadCounterVec("equivocation_evidences_count", []string{"kind"})
+)
@@ -0,0 +1,249 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/pkg/errors"
+)
+
+// CustomGenesis is user customized genesis.
+type CustomGenesis struct {
+	LaunchTime uint64           `json:"launchTime"`
+	GasLimit   uint64           `json:"gasLimit"`
+	ExtraData  string           `json:"extraData"`
+	Accounts   []Account        `json:"accounts"`
+	Authority  []Authority      `json:"authority"`
+	Params     Params           `json:"params"`
+	Executor   *thor.Address    `json:"executor"`
+	ForkConfig *thor.ForkConfig `json:"forkConfig"`
+}
+
+// Account is the account that will be set in the genesis block.
+type Account struct {
+	Address thor.Address            `json:"address"`
+	Balance *math.HexOrDecimal256   `json:"balance"`
+	Energy  *math.HexOrDecimal256   `json:"energy"`
+	Code    string                  `json:"code"`
+	Storage map[string]thor.Bytes32 `json:"storage"`
+}
+
+// Authority is the authority node info.
+type Authority struct {
+	MasterAddress   thor.Address `json:"masterAddress"`
+	EndorsorAddress thor.Address `json:"endorsorAddress"`
+	Identity        thor.Bytes32 `json:"identity"`
+}
+
+// Params means the chain params for params contract, the initial values are used if absent.
+type Params struct {
+	RewardRatio         *math.HexOrDecimal256 `json:"rewardRatio"`
+	BaseGasPrice        *math.HexOrDecimal256 `json:"baseGasPrice"`
+	ProposerEndorsement *math.HexOrDecimal256 `json:"proposerEndorsement"`
+	MaxBlockProposers   *uint64               `json:"maxBlockProposers"`
+}
+
+// LoadCustomNet loads custom genesis from the given JSON file and creates the network.
+// Unknown fields are rejected.
+func LoadCustomNet(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var gen CustomGenesis
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&gen); err != nil {
+		return nil, errors.Wrap(err, "decode custom genesis")
+	}
+	return NewCustomNet(&gen)
+}
+
+// NewCustomNet create custom network genesis.
+// The fork config is registered under the resulting genesis ID, forks absent
+// from it, or all of them if it's absent, are activated at genesis.
+func NewCustomNet(gen *CustomGenesis) (*Genesis, error) {
+	return newCustomNet(gen, "customnet")
+}
+
+func newCustomNet(gen *CustomGenesis, name string) (*Genesis, error) {
+	if gen.LaunchTime == 0 {
+		return nil, errors.New("launchTime required")
+	}
+	if len(gen.Authority) == 0 {
+		return nil, errors.New("at least one authority node required")
+	}
+	if len(gen.ExtraData) > 28 {
+		return nil, errors.New("extraData should be no longer than 28 bytes")
+	}
+
+	var forkConfig thor.ForkConfig
+	if gen.ForkConfig != nil {
+		forkConfig = *gen.ForkConfig
+	}
+	if err := forkConfig.Validate(); err != nil {
+		return nil, errors.Wrap(err, "forkConfig")
+	}
+
+	gasLimit := gen.GasLimit
+	if gasLimit == 0 {
+		gasLimit = thor.InitialGasLimit
+	}
+	if gasLimit < thor.MinGasLimit {
+		return nil, errors.Errorf("gasLimit should be no less than %v", thor.MinGasLimit)
+	}
+
+	executor := builtin.Executor.Address
+	if gen.Executor != nil {
+		executor = *gen.Executor
+	}
+
+	params := map[thor.Bytes32]*big.Int{
+		thor.KeyExecutorAddress:     new(big.Int).SetBytes(executor[:]),
+		thor.KeyRewardRatio:         thor.InitialRewardRatio,
+		thor.KeyBaseGasPrice:        thor.InitialBaseGasPrice,
+		thor.KeyProposerEndorsement: thor.InitialProposerEndorsement,
+		thor.KeyMaxBlockProposers:   new(big.Int).SetUint64(thor.InitialMaxBlockProposers),
+	}
+	if v := gen.Params.RewardRatio; v != nil {
+		params[thor.KeyRewardRatio] = (*big.Int)(v)
+	}
+	if v := gen.Params.BaseGasPrice; v != nil {
+		params[thor.KeyBaseGasPrice] = (*big.Int)(v)
+	}
+	if v := gen.Params.ProposerEndorsement; v != nil {
+		params[thor.KeyProposerEndorsement] = (*big.Int)(v)
+	}
+	if v := gen.Params.MaxBlockProposers; v != nil {
+		if *v == 0 {
+			return nil, errors.New("maxBlockProposers should be greater than 0")
+		}
+		params[thor.KeyMaxBlockProposers] = new(big.Int).SetUint64(*v)
+	}
+
+	codes := make([][]byte, len(gen.Accounts))
+	for i, acc := range gen.Accounts {
+		if acc.Code == "" {
+			continue
+		}
+		code, err := hexutil.Decode(acc.Code)
+		if err != nil {
+			return nil, errors.Wrapf(err, "code of account %v", acc.Address)
+		}
+		codes[i] = code
+	}
+	storages := make([]map[thor.Bytes32]thor.Bytes32, len(gen.Accounts))
+	for i, acc := range gen.Accounts {
+		storages[i] = make(map[thor.Bytes32]thor.Bytes32, len(acc.Storage))
+		for k, v := range acc.Storage {
+			key, err := thor.ParseBytes32(k)
+			if err != nil {
+				return nil, errors.Wrapf(err, "storage key of account %v", acc.Address)
+			}
+			storages[i][key] = v
+		}
+	}
+
+	launchTime := gen.LaunchTime
+	builder := new(Builder).
+		Timestamp(launchTime).
+		GasLimit(gasLimit).
+		ForkConfig(forkConfig).
+		State(func(state *state.State) error {
+			// alloc builtin contracts
+			for _, c := range []struct {
+				addr thor.Address
+				code []byte
+			}{
+				{builtin.Authority.Address, builtin.Authority.RuntimeBytecodes()},
+				{builtin.Energy.Address, builtin.Energy.RuntimeBytecodes()},
+				{builtin.Params.Address, builtin.Params.RuntimeBytecodes()},
+				{builtin.Prototype.Address, builtin.Prototype.RuntimeBytecodes()},
+				{builtin.Extension.Address, builtin.Extension.RuntimeBytecodes()},
+				{builtin.Executor.Address, builtin.Executor.RuntimeBytecodes()},
+			} {
+				if err := state.SetCode(c.addr, c.code); err != nil {
+					return err
+				}
+			}
+
+			tokenSupply := new(big.Int)
+			energySupply := new(big.Int)
+			for i, acc := range gen.Accounts {
+				if acc.Balance != nil {
+					balance := (*big.Int)(acc.Balance)
+					if balance.Sign() < 0 {
+						return errors.Errorf("negative balance of account %v", acc.Address)
+					}
+					if err := state.SetBalance(acc.Address, balance); err != nil {
+						return err
+					}
+					tokenSupply.Add(tokenSupply, balance)
+				}
+				if acc.Energy != nil {
+					energy := (*big.Int)(acc.Energy)
+					if energy.Sign() < 0 {
+						return errors.Errorf("negative energy of account %v", acc.Address)
+					}
+					if err := state.SetEnergy(acc.Address, energy, launchTime); err != nil {
+						return err
+					}
+					energySupply.Add(energySupply, energy)
+				}
+				if len(codes[i]) > 0 {
+					if err := state.SetCode(acc.Address, codes[i]); err != nil {
+						return err
+					}
+				}
+				for k, v := range storages[i] {
+					state.SetStorage(acc.Address, k, v)
+				}
+			}
+			if err := builtin.Energy.Native(state, launchTime).SetInitialSupply(tokenSupply, energySupply); err != nil {
+				return err
+			}
+
+			for key, value := range params {
+				if err := builtin.Params.Native(state).Set(key, value); err != nil {
+					return err
+				}
+			}
+
+			for _, au := range gen.Authority {
+				ok, err := builtin.Authority.Native(state).Add(au.MasterAddress, au.EndorsorAddress, au.Identity)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errors.Errorf("duplicated authority node %v", au.MasterAddress)
+				}
+			}
+			return nil
+		})
+
+	var extra [28]byte
+	copy(extra[:], gen.ExtraData)
+	builder.ExtraData(extra)
+
+	id, err := builder.ComputeID()
+	if err != nil {
+		return nil, err
+	}
+	if err := thor.RegisterForkConfig(id, forkConfig); err != nil {
+		return nil, err
+	}
+	return &Genesis{builder, id, name, forkConfig}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package genesis
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DevAccount account for development.
+type DevAccount struct {
+	Address    thor.Address
+	PrivateKey *ecdsa.PrivateKey
+}
+
+var devAccounts = func() []DevAccount {
+	privKeys := []string{
+		"9c79e16124e0ebb1f20cbdadbe8cf2108064e5ef6f9806078240a33f4d19b1b4",
+		"4c632b1639dc62d044cb79f01479cef74ac827186809d8373256551f57006155",
+		"39c4775c341e8f3eec9cc0751b6d57c0314f43b235e1a2367f9af4da1ea219d4",
+		"cddc7bb3793dbdbda9b13fbddfb8dce614ebe6f12e2d0bd714125f4d37d7b038",
+		"9ed869238b4eb2c4d8c89718de48dd9ed4d6f7435d0bbe9247ae807bce7c3530",
+		"2d55e1548ecaa34ee9042c33b29e6b77d4139da80e3152d6b531b4f62ab958ee",
+		"556cd58ed57145f9df85142dde6138b0e01afa09f55aef4fd2e220bff110874f",
+		"e9057238494d023963ebe4794627a87f3c04156bb2c29a09f7dca9743549fc5e",
+		"1c88308f5b3cec98eb5c29c1fa4449f434fcfbde0b948944ac4cffd75edfd7c8",
+		"83cae026229bdfdf5c22e1135a46a66192624323c526a387835b963b95ad4ddf",
+	}
+	accs := make([]DevAccount, 0, len(privKeys))
+	for _, str := range privKeys {
+		pk, err := crypto.HexToECDSA(str)
+		if err != nil {
+			panic(err)
+		}
+		addr := crypto.PubkeyToAddress(pk.PublicKey)
+		accs = append(accs, DevAccount{thor.Address(addr), pk})
+	}
+	return accs
+}()
+
+// DevAccounts returns pre-alloced accounts for solo mode.
+func DevAccounts() []DevAccount {
+	return append([]DevAccount(nil), devAccounts...)
+}
+
+// NewDevnet create genesis for solo mode.
+// The first dev account is the only authority node and the executor, and all forks are activated at genesis.
+func NewDevnet() *Genesis {
+	const launchTime = 1526400000 // Default launch time 'Wed May 16 2018 00:00:00 GMT+0800 (CST)'
+
+	amount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e9))
+	master := devAccounts[0].Address
+
+	gen := &CustomGenesis{
+		LaunchTime: launchTime,
+		GasLimit:   thor.InitialGasLimit,
+		Authority: []Authority{{
+			MasterAddress:   master,
+			EndorsorAddress: master,
+			Identity:        thor.BytesToBytes32([]byte("Solo Block Signer")),
+		}},
+		Executor:   &master,
+		ForkConfig: &thor.ForkConfig{},
+	}
+	for _, acc := range devAccounts {
+		gen.Accounts = append(gen.Accounts, Account{
+			Address: acc.Address,
+			Balance: (*math.HexOrDecimal256)(amount),
+			Energy:  (*math.HexOrDecimal256)(amount),
+		})
+	}
+
+	g, err := newCustomNet(gen, "devnet")
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package genesis
+
+import (
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+)
+
+// Genesis to build genesis block.
+type Genesis struct {
+	builder    *Builder
+	id         thor.Bytes32
+	name       string
+	forkConfig thor.ForkConfig
+}
+
+// Build build the genesis block.
+func (g *Genesis) Build(stater *state.Stater) (blk *block.Block, events tx.Events, transfers tx.Transfers, err error) {
+	blk, events, transfers, err = g.builder.Build(stater)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if blk.Header().ID() != g.id {
+		panic("built genesis ID incorrect")
+	}
+	return blk, events, transfers, nil
+}
+
+// ID returns genesis block ID.
+func (g *Genesis) ID() thor.Bytes32 {
+	return g.id
+}
+
+// Name returns network name.
+func (g *Genesis) Name() string {
+	return g.name
+}
+
+// ChainTag returns chain tag of the network, which is the last byte of genesis ID.
+func (g *Genesis) ChainTag() byte {
+	return g.id[31]
+}
+
+// ForkConfig returns fork config of the network.
+func (g *Genesis) ForkConfig() thor.ForkConfig {
+	return g.forkConfig
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package genesis
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevnet(t *testing.T) {
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+
+	g := NewDevnet()
+	assert.Equal(t, "devnet", g.Name())
+	assert.Equal(t, thor.ForkConfig{}, g.ForkConfig())
+	assert.Equal(t, thor.ForkConfig{}, thor.GetForkConfig(g.ID()))
+
+	b0, _, _, err := g.Build(stater)
+	assert.Nil(t, err)
+	assert.Equal(t, g.ID(), b0.Header().ID())
+	assert.Equal(t, uint32(0), b0.Header().Number())
+
+	repo, err := chain.NewRepository(db, b0)
+	assert.Nil(t, err)
+	assert.Equal(t, g.ChainTag(), repo.ChainTag())
+
+	st := stater.NewState(b0.Header().StateRoot(), 0, 0, 0)
+	master := DevAccounts()[0].Address
+
+	listed, endorsor, _, active, err := builtin.Authority.Native(st).Get(master)
+	assert.Nil(t, err)
+	assert.True(t, listed)
+	assert.True(t, active)
+	assert.Equal(t, master, endorsor)
+
+	executor, err := builtin.Params.Native(st).Get(thor.KeyExecutorAddress)
+	assert.Nil(t, err)
+	assert.Equal(t, master, thor.BytesToAddress(executor.Bytes()))
+
+	for _, acc := range DevAccounts() {
+		bal, err := st.GetBalance(acc.Address)
+		assert.Nil(t, err)
+		assert.Equal(t, new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e9)), bal)
+	}
+	supply, err := builtin.Energy.Native(st, b0.Header().Timestamp()).TokenTotalSupply()
+	assert.Nil(t, err)
+	assert.Equal(t, new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e10)), supply)
+
+	// deterministic
+	assert.Equal(t, g.ID(), NewDevnet().ID())
+}
+
+func TestLoadCustomNet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	write := func(content string) {
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	write(`{
+		"launchTime": 1526400000,
+		"gasLimit": 20000000,
+		"extraData": "My Consortium",
+		"accounts": [{
+			"address": "0x0000000000000000000000000000000000000a01",
+			"balance": "0x3e8",
+			"energy": 2000,
+			"code": "0x6080",
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}
+		}],
+		"authority": [{
+			"masterAddress": "0x0000000000000000000000000000000000000b01",
+			"endorsorAddress": "0x0000000000000000000000000000000000000a01",
+			"identity": "0x0000000000000000000000000000000000000000000000000000000000000003"
+		}],
+		"params": {
+			"baseGasPrice": "1000000000000000",
+			"maxBlockProposers": 10
+		},
+		"forkConfig": {
+			"VIP191": 0,
+			"ETH_CONST": 0,
+			"ETH_IST": 100
+		}
+	}`)
+
+	g, err := LoadCustomNet(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "customnet", g.Name())
+
+	// forks not configured are activated at genesis
+	expectedForks := thor.ForkConfig{ETH_IST: 100}
+	assert.Equal(t, expectedForks, g.ForkConfig())
+	assert.Equal(t, expectedForks, thor.GetForkConfig(g.ID()))
+
+	stater := state.NewStater(muxdb.NewMem())
+	b0, _, _, err := g.Build(stater)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(20000000), b0.Header().GasLimit())
+	assert.Equal(t, uint64(1526400000), b0.Header().Timestamp())
+	assert.Equal(t, "My Consortium", string(b0.Header().ParentID().Bytes()[4:17]))
+
+	st := stater.NewState(b0.Header().StateRoot(), 0, 0, 0)
+	acc := thor.MustParseAddress("0x0000000000000000000000000000000000000a01")
+	bal, err := st.GetBalance(acc)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1000), bal)
+	energy, err := st.GetEnergy(acc, b0.Header().Timestamp())
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(2000), energy)
+	code, err := st.GetCode(acc)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x60, 0x80}, code)
+	storage, err := st.GetStorage(acc, thor.BytesToBytes32([]byte{1}))
+	assert.Nil(t, err)
+	assert.Equal(t, thor.BytesToBytes32([]byte{2}), storage)
+
+	executor, err := builtin.Params.Native(st).Get(thor.KeyExecutorAddress)
+	assert.Nil(t, err)
+	assert.Equal(t, builtin.Executor.Address, thor.BytesToAddress(executor.Bytes()))
+	mbp, err := builtin.Params.Native(st).Get(thor.KeyMaxBlockProposers)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(10), mbp)
+	reward, err := builtin.Params.Native(st).Get(thor.KeyRewardRatio)
+	assert.Nil(t, err)
+	assert.Equal(t, thor.InitialRewardRatio, reward)
+
+	listed, endorsor, _, _, err := builtin.Authority.Native(st).Get(thor.MustParseAddress("0x0000000000000000000000000000000000000b01"))
+	assert.Nil(t, err)
+	assert.True(t, listed)
+	assert.Equal(t, acc, endorsor)
+}
+
+func TestCustomNetForkConfigDefault(t *testing.T) {
+	newGen := func(fc *thor.ForkConfig) *CustomGenesis {
+		return &CustomGenesis{
+			LaunchTime: 1526400000,
+			Authority:  []Authority{{MasterAddress: thor.BytesToAddress([]byte("master"))}},
+			ForkConfig: fc,
+		}
+	}
+
+	absent, err := NewCustomNet(newGen(nil))
+	assert.Nil(t, err)
+	empty, err := NewCustomNet(newGen(&thor.ForkConfig{}))
+	assert.Nil(t, err)
+
+	assert.Equal(t, thor.ForkConfig{}, absent.ForkConfig())
+	assert.Equal(t, absent.ForkConfig(), empty.ForkConfig())
+
+	var fc thor.ForkConfig
+	assert.Nil(t, json.Unmarshal([]byte(`{}`), &fc))
+	assert.Equal(t, thor.ForkConfig{}, fc)
+}
+
+func TestLoadCustomNetInvalid(t *testing.T) {
+	const authority = `"authority": [{"masterAddress": "0x0000000000000000000000000000000000000b01", "endorsorAddress": "0x0000000000000000000000000000000000000a01"}]`
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", `{"launchTime": 1, "unknown": 1, ` + authority + `}`},
+		{"unknown fork", `{"launchTime": 1, "forkConfig": {"VIP999": 1}, ` + authority + `}`},
+		{"forks out of order", `{"launchTime": 1, "forkConfig": {"VIP214": 10, "FINALITY": 0}, ` + authority + `}`},
+		{"no launch time", `{` + authority + `}`},
+		{"no authority", `{"launchTime": 1}`},
+		{"extra data too long", `{"launchTime": 1, "extraData": "0123456789012345678901234567890", ` + authority + `}`},
+		{"gas limit too low", `{"launchTime": 1, "gasLimit": 1000, ` + authority + `}`},
+		{"bad code", `{"launchTime": 1, "accounts": [{"address": "0x0000000000000000000000000000000000000a01", "code": "zz"}], ` + authority + `}`},
+		{"negative balance", `{"launchTime": 1, "accounts": [{"address": "0x0000000000000000000000000000000000000a01", "balance": -1}], ` + authority + `}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "genesis.json")
+			assert.Nil(t, os.WriteFile(path, []byte(tt.content), 0644))
+			_, err := LoadCustomNet(path)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestBuilderCall(t *testing.T) {
+	from := thor.BytesToAddress([]byte("from"))
+	to := thor.BytesToAddress([]byte("to"))
+
+	b := new(Builder).
+		Timestamp(1526400000).
+		GasLimit(thor.InitialGasLimit).
+		State(func(state *state.State) error {
+			return state.SetBalance(from, big.NewInt(100))
+		}).
+		Call(tx.NewClause(&to).WithValue(big.NewInt(10)), from)
+
+	id, err := b.ComputeID()
+	assert.Nil(t, err)
+
+	stater := state.NewStater(muxdb.NewMem())
+	b0, _, transfers, err := b.Build(stater)
+	assert.Nil(t, err)
+	assert.Equal(t, id, b0.Header().ID())
+	assert.Equal(t, tx.Transfers{{Sender: from, Recipient: to, Amount: big.NewInt(10)}}, transfers)
+
+	st := stater.NewState(b0.Header().StateRoot(), 0, 0, 0)
+	bal, err := st.GetBalance(to)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(10), bal)
+
+	// failed call
+	b.Call(tx.NewClause(&to).WithValue(big.NewInt(1000)), from)
+	_, _, _, err = b.Build(state.NewStater(muxdb.NewMem()))
+	assert.NotNil(t, err)
+}
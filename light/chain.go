@@ -0,0 +1,392 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+// Package light implements a header-only light client. It follows block headers from a trusted anchor
+// and verifies signers, VRF proofs, proposer schedules, total scores and gas limits, without executing
+// blocks or keeping states.
+package light
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/kv"
+	"github.com/ashkanabbasii/thor/log"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+)
+
+var logger = log.WithContext("pkg", "light")
+
+var (
+	headKey      = []byte("head")
+	anchorKey    = []byte("anchor")
+	proposersKey = []byte("proposers")
+)
+
+func headerKey(num uint32) []byte {
+	var key [5]byte
+	key[0] = 'h'
+	binary.BigEndian.PutUint32(key[1:], num)
+	return key[:]
+}
+
+// undoKey is the key of proposer statuses before applying the block.
+func undoKey(num uint32) []byte {
+	var key [5]byte
+	key[0] = 'u'
+	binary.BigEndian.PutUint32(key[1:], num)
+	return key[:]
+}
+
+// Anchor is the trusted starting point of a light chain.
+type Anchor struct {
+	Header    *block.Header
+	Proposers []poa.Proposer // proposers with status right after the header
+	Seed      []byte         // seed of the epoch the header's child belongs to, nil if not required
+}
+
+type anchorMeta struct {
+	Number uint32
+	Seed   []byte
+}
+
+// Chain follows block headers of a single branch from the anchor.
+// The proposer set is trusted, and statuses of proposers are tracked along with headers.
+type Chain struct {
+	store      kv.Store
+	forkConfig thor.ForkConfig
+	lock       sync.RWMutex
+	anchor     anchorMeta
+	head       *block.Header
+	proposers  []poa.Proposer
+}
+
+// New creates a light chain upon the store. The anchor is used only if the store is empty.
+// The anchor should be the first or the last block of a seeder epoch, so that seeds of following
+// epochs are available from headers.
+func New(store kv.Store, forkConfig thor.ForkConfig, anchor *Anchor) (*Chain, error) {
+	c := &Chain{
+		store:      store,
+		forkConfig: forkConfig,
+	}
+
+	if data, err := store.Get(anchorKey); err != nil {
+		if !store.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		if err := rlp.DecodeBytes(data, &c.anchor); err != nil {
+			return nil, errors.Wrap(err, "decode anchor")
+		}
+		data, err := store.Get(headKey)
+		if err != nil {
+			return nil, err
+		}
+		if c.head, err = c.getHeader(binary.BigEndian.Uint32(data)); err != nil {
+			return nil, err
+		}
+		if data, err = store.Get(proposersKey); err != nil {
+			return nil, err
+		}
+		if err := rlp.DecodeBytes(data, &c.proposers); err != nil {
+			return nil, errors.Wrap(err, "decode proposers")
+		}
+		return c, nil
+	}
+
+	if anchor == nil || anchor.Header == nil {
+		return nil, errors.New("anchor required")
+	}
+	if len(anchor.Proposers) == 0 {
+		return nil, errors.New("empty proposers")
+	}
+	num := anchor.Header.Number()
+	if num%thor.SeederInterval != 0 && (num+1)%thor.SeederInterval != 0 {
+		return nil, errors.New("anchor not at the boundary of epoch")
+	}
+
+	c.anchor = anchorMeta{Number: num, Seed: anchor.Seed}
+	c.head = anchor.Header
+	c.proposers = append([]poa.Proposer(nil), anchor.Proposers...)
+
+	bulk := store.Bulk()
+	if err := putRLP(bulk, anchorKey, &c.anchor); err != nil {
+		return nil, err
+	}
+	if err := writeHead(bulk, anchor.Header, c.proposers); err != nil {
+		return nil, err
+	}
+	if err := bulk.Write(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Head returns the latest verified header.
+func (c *Chain) Head() *block.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.head
+}
+
+// Proposers returns proposers with status at the head.
+func (c *Chain) Proposers() []poa.Proposer {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return append([]poa.Proposer(nil), c.proposers...)
+}
+
+// GetHeader returns the verified header by number.
+func (c *Chain) GetHeader(num uint32) (*block.Header, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if num < c.anchor.Number || num > c.head.Number() {
+		return nil, errors.New("header not found")
+	}
+	return c.getHeader(num)
+}
+
+// Append verifies the header as the child of the head, and appends it.
+func (c *Chain) Append(header *block.Header) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if header.ParentID() != c.head.ID() {
+		return errParentMismatch
+	}
+	proposers, undo, err := c.verify(header, c.head, uint64(time.Now().Unix()))
+	if err != nil {
+		return err
+	}
+
+	bulk := c.store.Bulk()
+	if err := putRLP(bulk, undoKey(header.Number()), undo); err != nil {
+		return err
+	}
+	if err := writeHead(bulk, header, proposers); err != nil {
+		return err
+	}
+	if err := bulk.Write(); err != nil {
+		return err
+	}
+	c.head = header
+	c.proposers = proposers
+	return nil
+}
+
+// Rewind drops headers after the given number, and restores statuses of proposers.
+func (c *Chain) Rewind(num uint32) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if num < c.anchor.Number {
+		return errors.New("rewind beyond the anchor")
+	}
+	if num >= c.head.Number() {
+		return nil
+	}
+
+	proposers := append([]poa.Proposer(nil), c.proposers...)
+	bulk := c.store.Bulk()
+	for n := c.head.Number(); n > num; n-- {
+		data, err := c.store.Get(undoKey(n))
+		if err != nil {
+			return err
+		}
+		var undo []poa.Proposer
+		if err := rlp.DecodeBytes(data, &undo); err != nil {
+			return errors.Wrap(err, "decode undo")
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			setStatus(proposers, undo[i])
+		}
+		if err := bulk.Delete(headerKey(n)); err != nil {
+			return err
+		}
+		if err := bulk.Delete(undoKey(n)); err != nil {
+			return err
+		}
+	}
+
+	head, err := c.getHeader(num)
+	if err != nil {
+		return err
+	}
+	if err := writeHead(bulk, head, proposers); err != nil {
+		return err
+	}
+	if err := bulk.Write(); err != nil {
+		return err
+	}
+	c.head = head
+	c.proposers = proposers
+	return nil
+}
+
+// verify verifies the header against its parent, and returns proposers with updated statuses along with
+// the previous statuses of updated proposers.
+func (c *Chain) verify(header, parent *block.Header, now uint64) ([]poa.Proposer, []poa.Proposer, error) {
+	if header.Number() != parent.Number()+1 {
+		return nil, nil, verifyError(fmt.Sprintf("block number invalid: parent %v, current %v", parent.Number(), header.Number()))
+	}
+	if header.Timestamp() <= parent.Timestamp() {
+		return nil, nil, verifyError(fmt.Sprintf("block timestamp behind parents: parent %v, current %v", parent.Timestamp(), header.Timestamp()))
+	}
+	if (header.Timestamp()-parent.Timestamp())%thor.BlockInterval != 0 {
+		return nil, nil, verifyError(fmt.Sprintf("block interval not rounded: parent %v, current %v", parent.Timestamp(), header.Timestamp()))
+	}
+	if header.Timestamp() > now+thor.BlockInterval {
+		return nil, nil, errFutureBlock
+	}
+	if !block.GasLimit(header.GasLimit()).IsValid(parent.GasLimit()) {
+		return nil, nil, verifyError(fmt.Sprintf("block gas limit invalid: parent %v, current %v", parent.GasLimit(), header.GasLimit()))
+	}
+	if header.GasUsed() > header.GasLimit() {
+		return nil, nil, verifyError(fmt.Sprintf("block gas used exceeds limit: limit %v, used %v", header.GasLimit(), header.GasUsed()))
+	}
+	if header.TotalScore() <= parent.TotalScore() {
+		return nil, nil, verifyError(fmt.Sprintf("block total score invalid: parent %v, current %v", parent.TotalScore(), header.TotalScore()))
+	}
+	if header.Number() < c.forkConfig.FINALITY && header.COM() {
+		return nil, nil, verifyError("invalid block: COM should not set before fork FINALITY")
+	}
+
+	signature := header.Signature()
+	if header.Number() < c.forkConfig.VIP214 {
+		if len(header.Alpha()) > 0 {
+			return nil, nil, verifyError("invalid block, alpha should be empty before VIP214")
+		}
+		if len(signature) != 65 {
+			return nil, nil, verifyError(fmt.Sprintf("block signature length invalid: want 65 have %v", len(signature)))
+		}
+	} else {
+		if len(signature) != block.ComplexSigSize {
+			return nil, nil, verifyError(fmt.Sprintf("block signature length invalid: want %d have %v", block.ComplexSigSize, len(signature)))
+		}
+		parentBeta, err := parent.Beta()
+		if err != nil {
+			return nil, nil, verifyError(fmt.Sprintf("failed to verify parent block's VRF Signature: %v", err))
+		}
+		alpha := parentBeta
+		// initial value of chained VRF
+		if len(alpha) == 0 {
+			alpha = parent.StateRoot().Bytes()
+		}
+		if !bytes.Equal(header.Alpha(), alpha) {
+			return nil, nil, verifyError(fmt.Sprintf("block alpha invalid: want %v, have %v", hexutil.Encode(alpha), hexutil.Encode(header.Alpha())))
+		}
+		// the VRF proof is verified when computing beta
+		if _, err := header.Beta(); err != nil {
+			return nil, nil, verifyError(fmt.Sprintf("block VRF signature invalid: %v", err))
+		}
+	}
+
+	signer, err := header.Signer()
+	if err != nil {
+		return nil, nil, verifyError(fmt.Sprintf("block signer unavailable: %v", err))
+	}
+
+	var sched poa.Scheduler
+	if header.Number() < c.forkConfig.VIP214 {
+		sched, err = poa.NewSchedulerV1(signer, c.proposers, parent.Number(), parent.Timestamp())
+	} else {
+		var seed []byte
+		if seed, err = c.seed(parent.Number()); err != nil {
+			return nil, nil, err
+		}
+		sched, err = poa.NewSchedulerV2(signer, c.proposers, parent.Number(), parent.Timestamp(), seed)
+	}
+	if err != nil {
+		return nil, nil, verifyError(fmt.Sprintf("block signer invalid: %v %v", signer, err))
+	}
+	if !sched.IsTheTime(header.Timestamp()) {
+		return nil, nil, verifyError(fmt.Sprintf("block timestamp unscheduled: t %v, s %v", header.Timestamp(), signer))
+	}
+
+	updates, score := sched.Updates(header.Timestamp())
+	if parent.TotalScore()+score != header.TotalScore() {
+		return nil, nil, verifyError(fmt.Sprintf("block total score invalid: want %v, have %v", parent.TotalScore()+score, header.TotalScore()))
+	}
+
+	proposers := append([]poa.Proposer(nil), c.proposers...)
+	undo := make([]poa.Proposer, 0, len(updates))
+	for _, u := range updates {
+		undo = append(undo, setStatus(proposers, u))
+	}
+	return proposers, undo, nil
+}
+
+// seed returns the seed of the epoch, which the child of the given parent belongs to.
+func (c *Chain) seed(parentNum uint32) ([]byte, error) {
+	epoch := (parentNum + 1) / thor.SeederInterval
+	if epoch <= 1 {
+		return nil, nil
+	}
+	seedNum := (epoch - 1) * thor.SeederInterval
+	if seedNum < c.anchor.Number {
+		if epoch == (c.anchor.Number+1)/thor.SeederInterval {
+			return c.anchor.Seed, nil
+		}
+		return nil, errors.New("seed unavailable")
+	}
+	header, err := c.getHeader(seedNum)
+	if err != nil {
+		return nil, err
+	}
+	return header.Beta()
+}
+
+func (c *Chain) getHeader(num uint32) (*block.Header, error) {
+	data, err := c.store.Get(headerKey(num))
+	if err != nil {
+		return nil, err
+	}
+	var header block.Header
+	if err := rlp.DecodeBytes(data, &header); err != nil {
+		return nil, errors.Wrap(err, "decode header")
+	}
+	return &header, nil
+}
+
+func writeHead(putter kv.Putter, header *block.Header, proposers []poa.Proposer) error {
+	if err := putRLP(putter, headerKey(header.Number()), header); err != nil {
+		return err
+	}
+	if err := putRLP(putter, proposersKey, proposers); err != nil {
+		return err
+	}
+	var num [4]byte
+	binary.BigEndian.PutUint32(num[:], header.Number())
+	return putter.Put(headKey, num[:])
+}
+
+// setStatus sets the status of the proposer in the list, and returns the previous one.
+func setStatus(proposers []poa.Proposer, p poa.Proposer) poa.Proposer {
+	for i := range proposers {
+		if proposers[i].Address == p.Address {
+			prev := proposers[i]
+			proposers[i].Active = p.Active
+			return prev
+		}
+	}
+	// should never happen, updates come from the scheduler of the same list
+	panic("something wrong with proposers list")
+}
+
+func putRLP(putter kv.Putter, key []byte, val interface{}) error {
+	data, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+	return putter.Put(key, data)
+}
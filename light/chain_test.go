@@ -0,0 +1,318 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package light
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashkanabbasii/thor/api/blocks"
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/thorclient"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/vrf"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+var testForkConfig = func() thor.ForkConfig {
+	cfg := thor.NoFork
+	cfg.VIP214 = 0
+	return cfg
+}()
+
+type committer struct{}
+
+func (committer) Finalized() thor.Bytes32          { return thor.Bytes32{} }
+func (committer) Justified() (thor.Bytes32, error) { return thor.Bytes32{}, nil }
+
+// testNet produces properly scheduled and signed blocks.
+type testNet struct {
+	t         *testing.T
+	keys      []*ecdsa.PrivateKey
+	genesis   *block.Block
+	proposers map[thor.Bytes32][]poa.Proposer // proposers after the block
+}
+
+func newTestNet(t *testing.T) *testNet {
+	n := &testNet{
+		t:         t,
+		proposers: make(map[thor.Bytes32][]poa.Proposer),
+	}
+	var proposers []poa.Proposer
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		n.keys = append(n.keys, key)
+		proposers = append(proposers, poa.Proposer{Address: addressOf(key), Active: true})
+	}
+	n.genesis = new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		Timestamp(uint64(time.Now().Unix()) - 1000*thor.BlockInterval).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(thor.Bytes32{1}).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	n.proposers[n.genesis.Header().ID()] = proposers
+	return n
+}
+
+func addressOf(key *ecdsa.PrivateKey) thor.Address {
+	return thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+}
+
+func (n *testNet) anchor() *Anchor {
+	return &Anchor{
+		Header:    n.genesis.Header(),
+		Proposers: n.proposers[n.genesis.Header().ID()],
+	}
+}
+
+// schedule returns the key scheduled at the given time, and the score of the block.
+func (n *testNet) schedule(parent *block.Header, ts uint64) (*ecdsa.PrivateKey, uint64) {
+	proposers := n.proposers[parent.ID()]
+	for _, key := range n.keys {
+		sched, err := poa.NewSchedulerV2(addressOf(key), proposers, parent.Number(), parent.Timestamp(), nil)
+		assert.Nil(n.t, err)
+		if sched.IsTheTime(ts) {
+			_, score := sched.Updates(ts)
+			return key, score
+		}
+	}
+	n.t.Fatal("no proposer scheduled")
+	return nil, 0
+}
+
+// next produces the child block of the parent, skipping the given count of slots.
+func (n *testNet) next(parent *block.Header, skip uint64) *block.Block {
+	ts := parent.Timestamp() + thor.BlockInterval*(skip+1)
+	key, score := n.schedule(parent, ts)
+
+	blk := n.build(parent, ts, key, score, nil)
+	sched, _ := poa.NewSchedulerV2(addressOf(key), n.proposers[parent.ID()], parent.Number(), parent.Timestamp(), nil)
+	updates, _ := sched.Updates(ts)
+	proposers := append([]poa.Proposer(nil), n.proposers[parent.ID()]...)
+	for _, u := range updates {
+		setStatus(proposers, u)
+	}
+	n.proposers[blk.Header().ID()] = proposers
+	return blk
+}
+
+func (n *testNet) build(parent *block.Header, ts uint64, key *ecdsa.PrivateKey, score uint64, mutate func(*block.Builder)) *block.Block {
+	alpha, err := parent.Beta()
+	assert.Nil(n.t, err)
+	if len(alpha) == 0 {
+		alpha = parent.StateRoot().Bytes()
+	}
+	builder := new(block.Builder).
+		ParentID(parent.ID()).
+		Timestamp(ts).
+		TotalScore(parent.TotalScore() + score).
+		GasLimit(parent.GasLimit()).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Alpha(alpha)
+	if mutate != nil {
+		mutate(builder)
+	}
+	blk := builder.Build()
+
+	ec, err := crypto.Sign(blk.Header().SigningHash().Bytes(), key)
+	assert.Nil(n.t, err)
+	_, proof, err := vrf.Prove(key, blk.Header().Alpha())
+	assert.Nil(n.t, err)
+	sig, err := block.NewComplexSignature(ec, proof)
+	assert.Nil(n.t, err)
+	return blk.WithSignature(sig)
+}
+
+// branch produces count blocks upon the parent, skipping slots as given by skips.
+func (n *testNet) branch(parent *block.Header, count int, skips map[int]uint64) []*block.Block {
+	var blks []*block.Block
+	for i := 0; i < count; i++ {
+		blk := n.next(parent, skips[i])
+		blks = append(blks, blk)
+		parent = blk.Header()
+	}
+	return blks
+}
+
+func TestAppend(t *testing.T) {
+	n := newTestNet(t)
+	c, err := New(muxdb.NewMem().NewStore("light"), testForkConfig, n.anchor())
+	assert.Nil(t, err)
+
+	// skipped slots make proposers inactive
+	blks := n.branch(n.genesis.Header(), 8, map[int]uint64{3: 2})
+	for _, blk := range blks {
+		assert.Nil(t, c.Append(blk.Header()))
+	}
+
+	head := blks[len(blks)-1].Header()
+	assert.Equal(t, head.ID(), c.Head().ID())
+	assert.Equal(t, n.proposers[head.ID()], c.Proposers())
+
+	h, err := c.GetHeader(4)
+	assert.Nil(t, err)
+	assert.Equal(t, blks[3].Header().ID(), h.ID())
+	_, err = c.GetHeader(9)
+	assert.NotNil(t, err)
+
+	assert.True(t, IsParentMismatch(c.Append(blks[2].Header())))
+}
+
+func TestAppendInvalid(t *testing.T) {
+	n := newTestNet(t)
+	c, err := New(muxdb.NewMem().NewStore("light"), testForkConfig, n.anchor())
+	assert.Nil(t, err)
+
+	parent := n.genesis.Header()
+	ts := parent.Timestamp() + thor.BlockInterval
+	key, score := n.schedule(parent, ts)
+	var other *ecdsa.PrivateKey
+	for _, k := range n.keys {
+		if k != key {
+			other = k
+			break
+		}
+	}
+	stranger, _ := crypto.GenerateKey()
+
+	legacy := n.build(parent, ts, key, score, func(b *block.Builder) { b.Alpha(nil) })
+	sig, _ := crypto.Sign(legacy.Header().SigningHash().Bytes(), key)
+	legacy = legacy.WithSignature(sig)
+
+	now := uint64(time.Now().Unix())
+	future := parent.Timestamp() + (now-parent.Timestamp())/thor.BlockInterval*thor.BlockInterval + 10*thor.BlockInterval
+
+	tests := []struct {
+		name   string
+		header *block.Header
+		errStr string
+	}{
+		{"total score", n.build(parent, ts, key, score+1, nil).Header(), "block total score invalid"},
+		{"gas limit", n.build(parent, ts, key, score, func(b *block.Builder) { b.GasLimit(parent.GasLimit() * 2) }).Header(), "block gas limit invalid"},
+		{"gas used", n.build(parent, ts, key, score, func(b *block.Builder) { b.GasUsed(parent.GasLimit() + 1) }).Header(), "block gas used exceeds limit"},
+		{"alpha", n.build(parent, ts, key, score, func(b *block.Builder) { b.Alpha([]byte{1}) }).Header(), "block alpha invalid"},
+		{"legacy signature", legacy.Header(), "block signature length invalid"},
+		{"interval", n.build(parent, ts+1, key, score, nil).Header(), "block interval not rounded"},
+		{"unscheduled", n.build(parent, ts, other, score, nil).Header(), "block timestamp unscheduled"},
+		{"unknown signer", n.build(parent, ts, stranger, score, nil).Header(), "block signer invalid"},
+		{"com", n.build(parent, ts, key, score, func(b *block.Builder) { b.COM() }).Header(), "COM should not set before fork FINALITY"},
+	}
+	for _, tt := range tests {
+		err := c.Append(tt.header)
+		assert.True(t, IsInvalid(err), tt.name)
+		assert.ErrorContains(t, err, tt.errStr, tt.name)
+	}
+
+	err = c.Append(n.build(parent, future, key, score, nil).Header())
+	assert.True(t, IsFutureBlock(err))
+
+	assert.Equal(t, parent.ID(), c.Head().ID())
+	assert.Nil(t, c.Append(n.build(parent, ts, key, score, nil).Header()))
+}
+
+func TestRewind(t *testing.T) {
+	n := newTestNet(t)
+	db := muxdb.NewMem()
+	c, err := New(db.NewStore("light"), testForkConfig, n.anchor())
+	assert.Nil(t, err)
+
+	blks := n.branch(n.genesis.Header(), 6, map[int]uint64{4: 2})
+	for _, blk := range blks {
+		assert.Nil(t, c.Append(blk.Header()))
+	}
+	assert.NotEqual(t, n.proposers[blks[2].Header().ID()], c.Proposers())
+
+	assert.Nil(t, c.Rewind(3))
+	assert.Equal(t, blks[2].Header().ID(), c.Head().ID())
+	assert.Equal(t, n.proposers[blks[2].Header().ID()], c.Proposers())
+	_, err = c.GetHeader(4)
+	assert.NotNil(t, err)
+
+	// restored from the store
+	c, err = New(db.NewStore("light"), testForkConfig, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, blks[2].Header().ID(), c.Head().ID())
+	assert.Equal(t, n.proposers[blks[2].Header().ID()], c.Proposers())
+
+	// follow another branch
+	fork := n.branch(blks[2].Header(), 3, map[int]uint64{0: 1})
+	for _, blk := range fork {
+		assert.Nil(t, c.Append(blk.Header()))
+	}
+	assert.Equal(t, n.proposers[fork[2].Header().ID()], c.Proposers())
+}
+
+func TestNewInvalidAnchor(t *testing.T) {
+	n := newTestNet(t)
+	blk := n.next(n.genesis.Header(), 0)
+
+	_, err := New(muxdb.NewMem().NewStore("light"), testForkConfig, nil)
+	assert.ErrorContains(t, err, "anchor required")
+	_, err = New(muxdb.NewMem().NewStore("light"), testForkConfig, &Anchor{Header: n.genesis.Header()})
+	assert.ErrorContains(t, err, "empty proposers")
+	_, err = New(muxdb.NewMem().NewStore("light"), testForkConfig, &Anchor{Header: blk.Header(), Proposers: n.anchor().Proposers})
+	assert.ErrorContains(t, err, "anchor not at the boundary of epoch")
+}
+
+func newTestServer(t *testing.T, n *testNet, blks []*block.Block) *httptest.Server {
+	repo, err := chain.NewRepository(muxdb.NewMem(), n.genesis)
+	assert.Nil(t, err)
+	for _, blk := range blks {
+		assert.Nil(t, repo.AddBlock(blk, nil, 0))
+	}
+	assert.Nil(t, repo.SetBestBlockID(blks[len(blks)-1].Header().ID()))
+
+	router := mux.NewRouter()
+	blocks.New(repo, committer{}).Mount(router, "/blocks")
+	return httptest.NewServer(router)
+}
+
+func TestSync(t *testing.T) {
+	n := newTestNet(t)
+	c, err := New(muxdb.NewMem().NewStore("light"), testForkConfig, n.anchor())
+	assert.Nil(t, err)
+
+	blks := n.branch(n.genesis.Header(), 6, map[int]uint64{2: 1})
+	ts := newTestServer(t, n, blks)
+	defer ts.Close()
+
+	assert.Nil(t, c.Sync(context.Background(), thorclient.New(ts.URL)))
+	assert.Equal(t, blks[5].Header().ID(), c.Head().ID())
+
+	// the remote node switched to a longer branch
+	fork := append(append([]*block.Block(nil), blks[:3]...), n.branch(blks[2].Header(), 5, nil)...)
+	forkTS := newTestServer(t, n, fork)
+	defer forkTS.Close()
+
+	assert.Nil(t, c.Sync(context.Background(), thorclient.New(forkTS.URL)))
+	assert.Equal(t, fork[7].Header().ID(), c.Head().ID())
+	assert.Equal(t, n.proposers[fork[7].Header().ID()], c.Proposers())
+
+	// then to a branch shorter than the local one
+	short := append(append([]*block.Block(nil), blks[:3]...), n.branch(blks[2].Header(), 2, map[int]uint64{0: 1})...)
+	shortTS := newTestServer(t, n, short)
+	defer shortTS.Close()
+
+	assert.Nil(t, c.Sync(context.Background(), thorclient.New(shortTS.URL)))
+	assert.Equal(t, short[4].Header().ID(), c.Head().ID())
+	assert.Equal(t, n.proposers[short[4].Header().ID()], c.Proposers())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c, err = New(muxdb.NewMem().NewStore("light"), testForkConfig, n.anchor())
+	assert.Nil(t, err)
+	assert.Equal(t, context.Canceled, c.Sync(ctx, thorclient.New(ts.URL)))
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package light
+
+import (
+	"errors"
+)
+
+var (
+	errFutureBlock    = errors.New("block in the future")
+	errParentMismatch = errors.New("parent mismatch")
+)
+
+type verifyError string
+
+func (err verifyError) Error() string {
+	return string(err)
+}
+
+// IsFutureBlock returns if the error indicates that the header should be
+// appended later.
+func IsFutureBlock(err error) bool {
+	return err == errFutureBlock
+}
+
+// IsParentMismatch returns if the error indicates that the header is not
+// the child of the head.
+func IsParentMismatch(err error) bool {
+	return err == errParentMismatch
+}
+
+// IsInvalid returns if the error indicates that the header failed verification.
+func IsInvalid(err error) bool {
+	_, ok := err.(verifyError)
+	return ok
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package light
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/thorclient"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+)
+
+// Sync fetches headers from the remote node until the head catches up with the remote best block.
+// If the remote node is on another branch, the chain is rewound to the common ancestor, which
+// should not be before the anchor.
+func (c *Chain) Sync(ctx context.Context, client *thorclient.Client) error {
+	best, err := client.Block("best")
+	if err != nil {
+		return errors.WithMessage(err, "get best block")
+	}
+
+	// the remote node may have switched to another branch, even a shorter one
+	from := c.Head().Number()
+	if best.Number < from {
+		from = best.Number
+	}
+	if err := c.rewindToRemote(ctx, client, from); err != nil {
+		return err
+	}
+
+	for c.Head().Number() < best.Number {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		header, err := fetchHeader(client, c.Head().Number()+1)
+		if err != nil {
+			return err
+		}
+		if err := c.Append(header); err != nil {
+			if !IsParentMismatch(err) {
+				return errors.WithMessagef(err, "append header %v", header.Number())
+			}
+			if err := c.rewindToRemote(ctx, client, c.Head().Number()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rewindToRemote rewinds the chain to the latest header that the remote node also has,
+// searching back from the given number.
+func (c *Chain) rewindToRemote(ctx context.Context, client *thorclient.Client, from uint32) error {
+	for num := from; ; num-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if num < c.anchor.Number {
+			return errors.New("remote forked before the anchor")
+		}
+
+		local, err := c.GetHeader(num)
+		if err != nil {
+			return err
+		}
+		remote, err := fetchHeader(client, num)
+		if err != nil {
+			return err
+		}
+		if local.ID() == remote.ID() {
+			if num < c.Head().Number() {
+				logger.Debug("rewound to common ancestor", "num", num, "id", local.ID())
+			}
+			return c.Rewind(num)
+		}
+		if num == 0 {
+			return errors.New("remote forked before the anchor")
+		}
+	}
+}
+
+func fetchHeader(client *thorclient.Client, num uint32) (*block.Header, error) {
+	raw, err := client.RawBlock(strconv.FormatUint(uint64(num), 10))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "get raw block %v", num)
+	}
+	data, err := hexutil.Decode(raw.Raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode raw block")
+	}
+	var header block.Header
+	if err := rlp.DecodeBytes(data, &header); err != nil {
+		return nil, errors.Wrap(err, "decode header")
+	}
+	if header.Number() != num {
+		return nil, errors.Errorf("header number mismatch: want %v, have %v", num, header.Number())
+	}
+	return &header, nil
+}
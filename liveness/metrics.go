@@ -0,0 +1,29 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package liveness
+
+import (
+	"github.com/ashkanabbasii/thor/metrics"
+)
+
+var (
+	metricBlocksProduced = metrics.LazyLoadGaugeVec("proposer_blocks_produced", []string{"proposer"})
+	metricSlotsMissed    = metrics.LazyLoadGaugeVec("proposer_slots_missed", []string{"proposer"})
+	metricActive         = metrics.LazyLoadGaugeVec("proposer_active", []string{"proposer"})
+	metricLastSeen       = metrics.LazyLoadGaugeVec("proposer_last_seen_block", []string{"proposer"})
+)
+
+func updateMetrics(r *Record) {
+	labels := map[string]string{"proposer": r.Address.String()}
+	metricBlocksProduced().SetWithLabel(int64(r.Produced), labels)
+	metricSlotsMissed().SetWithLabel(int64(r.Missed), labels)
+	metricLastSeen().SetWithLabel(int64(r.LastSeen), labels)
+	active := int64(0)
+	if r.Active {
+		active = 1
+	}
+	metricActive().SetWithLabel(active, labels)
+}
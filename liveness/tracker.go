@@ -0,0 +1,374 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+// Package liveness tracks the liveness of block proposers along the best chain.
+package liveness
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/kv"
+	"github.com/ashkanabbasii/thor/log"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const dataStoreName = "liveness"
+
+var (
+	logger = log.WithContext("pkg", "liveness")
+
+	cursorKey    = []byte("cursor") // id of the last processed block
+	recordPrefix = []byte("r")
+	undoPrefix   = []byte("u") // records before processing the block, keyed by block number
+)
+
+// Record is the liveness record of a proposer.
+type Record struct {
+	Address       thor.Address
+	Produced      uint64 // count of blocks produced
+	Missed        uint64 // count of slots scheduled to the proposer but skipped, whether active or not
+	LastSeen      uint32 // number of the latest block produced
+	LastSeenTime  uint64 // timestamp of the latest block produced
+	Active        bool
+	Activations   uint64 // count of transitions from inactive to active
+	Deactivations uint64 // count of transitions from active to inactive
+}
+
+// Tracker follows the best chain and keeps liveness records of proposers, according to the status
+// updates computed by the scheduler and the actual signer of each block. Blocks replaced by a reorg
+// are rolled back with undo records, which are kept for recent blocks up to thor.MaxStateHistory.
+type Tracker struct {
+	repo       *chain.Repository
+	stater     *state.Stater
+	forkConfig thor.ForkConfig
+	data       kv.Store
+	seeder     *poa.Seeder
+	lock       sync.Mutex
+}
+
+// New creates a new tracker. At the first start, tracking begins after the current best block.
+func New(repo *chain.Repository, stater *state.Stater, seeder *poa.Seeder, mainDB *muxdb.MuxDB, forkConfig thor.ForkConfig) (*Tracker, error) {
+	t := &Tracker{
+		repo:       repo,
+		stater:     stater,
+		forkConfig: forkConfig,
+		data:       mainDB.NewStore(dataStoreName),
+		seeder:     seeder,
+	}
+
+	if _, err := t.data.Get(cursorKey); err != nil {
+		if !t.data.IsNotFound(err) {
+			return nil, err
+		}
+		if err := t.data.Put(cursorKey, repo.BestBlockSummary().Header.ID().Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Run syncs records with the best chain until the context is done.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := t.repo.NewTicker()
+	for {
+		if err := t.Sync(); err != nil {
+			logger.Warn("failed to sync liveness records", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Sync processes blocks of the best chain after the last processed one. Processed blocks no longer
+// on the best chain are rolled back first.
+func (t *Tracker) Sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	data, err := t.data.Get(cursorKey)
+	if err != nil {
+		return err
+	}
+	cursor := thor.BytesToBytes32(data)
+
+	best := t.repo.NewBestChain()
+	for {
+		has, err := best.HasBlock(cursor)
+		if err != nil {
+			return err
+		}
+		if has {
+			break
+		}
+		if cursor, err = t.undo(cursor); err != nil {
+			return errors.WithMessagef(err, "undo block %v", block.Number(cursor))
+		}
+	}
+
+	for num := block.Number(cursor) + 1; num <= block.Number(best.HeadID()); num++ {
+		summary, err := best.GetBlockSummary(num)
+		if err != nil {
+			return err
+		}
+		if err := t.process(summary.Header); err != nil {
+			return errors.WithMessagef(err, "process block %v", num)
+		}
+	}
+	return nil
+}
+
+func (t *Tracker) process(header *block.Header) error {
+	signer, err := header.Signer()
+	if err != nil {
+		return errors.WithMessage(err, "signer")
+	}
+	parent, err := t.repo.GetBlockSummary(header.ParentID())
+	if err != nil {
+		return err
+	}
+
+	st := t.stater.NewState(parent.Header.StateRoot(), parent.Header.Number(), parent.Conflicts, parent.SteadyNum)
+	list, err := builtin.Authority.Native(st).AllCandidates()
+	if err != nil {
+		return err
+	}
+	proposers, _, err := poa.NewCandidates(list).Pick(st)
+	if err != nil {
+		return err
+	}
+
+	var seed []byte
+	if header.Number() >= t.forkConfig.VIP214 {
+		if seed, err = t.seeder.Generate(header.ParentID()); err != nil {
+			return err
+		}
+	}
+	newScheduler := func(addr thor.Address) (poa.Scheduler, error) {
+		if header.Number() < t.forkConfig.VIP214 {
+			return poa.NewSchedulerV1(addr, proposers, parent.Header.Number(), parent.Header.Timestamp())
+		}
+		return poa.NewSchedulerV2(addr, proposers, parent.Header.Number(), parent.Header.Timestamp(), seed)
+	}
+
+	sched, err := newScheduler(signer)
+	if err != nil {
+		return err
+	}
+	updates, _ := sched.Updates(header.Timestamp())
+
+	var (
+		records = make(map[thor.Address]*Record)
+		undo    []Record
+	)
+	get := func(addr thor.Address) (*Record, error) {
+		if r, ok := records[addr]; ok {
+			return r, nil
+		}
+		r, err := t.getRecord(addr)
+		if err != nil {
+			return nil, err
+		}
+		records[addr] = r
+		undo = append(undo, *r)
+		return r, nil
+	}
+
+	// every listed proposer misses the slots it's scheduled to before the block,
+	// an inactive one is scheduled as well to bring itself back
+	if header.Timestamp() > parent.Header.Timestamp()+thor.BlockInterval {
+		for _, p := range proposers {
+			sched, err := newScheduler(p.Address)
+			if err != nil {
+				return err
+			}
+			var missed uint64
+			for ts := sched.Schedule(parent.Header.Timestamp() + thor.BlockInterval); ts < header.Timestamp(); ts = sched.Schedule(ts + thor.BlockInterval) {
+				missed++
+			}
+			if missed > 0 {
+				r, err := get(p.Address)
+				if err != nil {
+					return err
+				}
+				r.Missed += missed
+			}
+		}
+	}
+
+	for _, u := range updates {
+		r, err := get(u.Address)
+		if err != nil {
+			return err
+		}
+		if u.Active {
+			r.Activations++
+		} else {
+			r.Deactivations++
+		}
+		r.Active = u.Active
+	}
+	r, err := get(signer)
+	if err != nil {
+		return err
+	}
+	r.Produced++
+	r.LastSeen = header.Number()
+	r.LastSeenTime = header.Timestamp()
+	r.Active = true
+
+	bulk := t.data.Bulk()
+	for addr, r := range records {
+		data, err := rlp.EncodeToBytes(r)
+		if err != nil {
+			return err
+		}
+		if err := bulk.Put(recordKey(addr), data); err != nil {
+			return err
+		}
+	}
+	data, err := rlp.EncodeToBytes(undo)
+	if err != nil {
+		return err
+	}
+	if err := bulk.Put(undoKey(header.Number()), data); err != nil {
+		return err
+	}
+	if header.Number() > thor.MaxStateHistory {
+		if err := bulk.Delete(undoKey(header.Number() - thor.MaxStateHistory)); err != nil {
+			return err
+		}
+	}
+	if err := bulk.Put(cursorKey, header.ID().Bytes()); err != nil {
+		return err
+	}
+	if err := bulk.Write(); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		updateMetrics(r)
+	}
+	return nil
+}
+
+// undo rolls back records changed by the processed block, and returns the id of its parent.
+// Records never tracked before the block are removed.
+func (t *Tracker) undo(id thor.Bytes32) (thor.Bytes32, error) {
+	summary, err := t.repo.GetBlockSummary(id)
+	if err != nil {
+		return thor.Bytes32{}, err
+	}
+	num := summary.Header.Number()
+
+	// nothing to undo for the block where tracking began
+	var undo []Record
+	if data, err := t.data.Get(undoKey(num)); err != nil {
+		if !t.data.IsNotFound(err) {
+			return thor.Bytes32{}, err
+		}
+	} else if err := rlp.DecodeBytes(data, &undo); err != nil {
+		return thor.Bytes32{}, errors.Wrap(err, "decode undo")
+	}
+
+	bulk := t.data.Bulk()
+	for _, r := range undo {
+		if r == (Record{Address: r.Address}) {
+			if err := bulk.Delete(recordKey(r.Address)); err != nil {
+				return thor.Bytes32{}, err
+			}
+			continue
+		}
+		data, err := rlp.EncodeToBytes(&r)
+		if err != nil {
+			return thor.Bytes32{}, err
+		}
+		if err := bulk.Put(recordKey(r.Address), data); err != nil {
+			return thor.Bytes32{}, err
+		}
+	}
+	if err := bulk.Delete(undoKey(num)); err != nil {
+		return thor.Bytes32{}, err
+	}
+	parentID := summary.Header.ParentID()
+	if err := bulk.Put(cursorKey, parentID.Bytes()); err != nil {
+		return thor.Bytes32{}, err
+	}
+	if err := bulk.Write(); err != nil {
+		return thor.Bytes32{}, err
+	}
+
+	for _, r := range undo {
+		updateMetrics(&r)
+	}
+	return parentID, nil
+}
+
+// Record returns the liveness record of the proposer, or nil if never tracked.
+func (t *Tracker) Record(addr thor.Address) (*Record, error) {
+	data, err := t.data.Get(recordKey(addr))
+	if err != nil {
+		if t.data.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var r Record
+	if err := rlp.DecodeBytes(data, &r); err != nil {
+		return nil, errors.Wrap(err, "decode record")
+	}
+	return &r, nil
+}
+
+// Records returns liveness records of all tracked proposers, ordered by address.
+func (t *Tracker) Records() ([]*Record, error) {
+	var records []*Record
+	iter := t.data.Iterate(kv.Range(*util.BytesPrefix(recordPrefix)))
+	defer iter.Release()
+	for iter.Next() {
+		var r Record
+		if err := rlp.DecodeBytes(iter.Value(), &r); err != nil {
+			return nil, errors.Wrap(err, "decode record")
+		}
+		records = append(records, &r)
+	}
+	return records, iter.Error()
+}
+
+// getRecord returns the liveness record of the proposer, or an empty one if never tracked.
+func (t *Tracker) getRecord(addr thor.Address) (*Record, error) {
+	r, err := t.Record(addr)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		r = &Record{Address: addr}
+	}
+	return r, nil
+}
+
+func recordKey(addr thor.Address) []byte {
+	return append(append([]byte(nil), recordPrefix...), addr.Bytes()...)
+}
+
+func undoKey(num uint32) []byte {
+	var key [5]byte
+	copy(key[:], undoPrefix)
+	binary.BigEndian.PutUint32(key[1:], num)
+	return key[:]
+}
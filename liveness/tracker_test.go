@@ -0,0 +1,266 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package liveness
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/genesis"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+type testChain struct {
+	t      *testing.T
+	db     *muxdb.MuxDB
+	repo   *chain.Repository
+	stater *state.Stater
+	keys   []*ecdsa.PrivateKey
+}
+
+func newTestChain(t *testing.T) *testChain {
+	balance := (*math.HexOrDecimal256)(new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e9)))
+	gen := &genesis.CustomGenesis{
+		LaunchTime: 1526400000,
+		GasLimit:   thor.InitialGasLimit,
+		ForkConfig: &thor.ForkConfig{},
+	}
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		addr := thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+		gen.Authority = append(gen.Authority, genesis.Authority{MasterAddress: addr, EndorsorAddress: addr})
+		gen.Accounts = append(gen.Accounts, genesis.Account{Address: addr, Balance: balance, Energy: balance})
+	}
+	g, err := genesis.NewCustomNet(gen)
+	assert.Nil(t, err)
+
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+	b0, _, _, err := g.Build(stater)
+	assert.Nil(t, err)
+	repo, err := chain.NewRepository(db, b0)
+	assert.Nil(t, err)
+	return &testChain{t, db, repo, stater, keys}
+}
+
+func addressOf(key *ecdsa.PrivateKey) thor.Address {
+	return thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+}
+
+// pack packs a block signed by the key at its earliest scheduled time upon the best block,
+// with proposer statuses updated in the state. It returns the updates.
+func (c *testChain) pack(key *ecdsa.PrivateKey) []poa.Proposer {
+	return c.packAfter(key, c.repo.BestBlockSummary().Header.Timestamp()+thor.BlockInterval)
+}
+
+// packAfter is like pack, but the block is packed at the scheduled time no earlier than nowTime.
+func (c *testChain) packAfter(key *ecdsa.PrivateKey, nowTime uint64) []poa.Proposer {
+	parent := c.repo.BestBlockSummary()
+	num := parent.Header.Number() + 1
+	st := c.stater.NewState(parent.Header.StateRoot(), parent.Header.Number(), 0, 0)
+
+	authority := builtin.Authority.Native(st)
+	list, err := authority.AllCandidates()
+	assert.Nil(c.t, err)
+	proposers, _, err := poa.NewCandidates(list).Pick(st)
+	assert.Nil(c.t, err)
+	sched, err := poa.NewSchedulerV2(addressOf(key), proposers, parent.Header.Number(), parent.Header.Timestamp(), nil)
+	assert.Nil(c.t, err)
+
+	ts := sched.Schedule(nowTime)
+	updates, score := sched.Updates(ts)
+	for _, u := range updates {
+		_, err := authority.Update(u.Address, u.Active)
+		assert.Nil(c.t, err)
+	}
+	stage, err := st.Stage(num, 0)
+	assert.Nil(c.t, err)
+	root, err := stage.Commit()
+	assert.Nil(c.t, err)
+
+	blk := new(block.Builder).
+		ParentID(parent.Header.ID()).
+		Timestamp(ts).
+		TotalScore(parent.Header.TotalScore() + score).
+		GasLimit(parent.Header.GasLimit()).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	sig, err := crypto.Sign(blk.Header().SigningHash().Bytes(), key)
+	assert.Nil(c.t, err)
+	blk = blk.WithSignature(sig)
+
+	assert.Nil(c.t, c.repo.AddBlock(blk, nil, 0))
+	assert.Nil(c.t, c.repo.SetBestBlockID(blk.Header().ID()))
+	return updates
+}
+
+// first returns the key scheduled at the first slot after the best block.
+func (c *testChain) first() *ecdsa.PrivateKey {
+	parent := c.repo.BestBlockSummary().Header
+	for _, key := range c.keys {
+		st := c.stater.NewState(parent.StateRoot(), parent.Number(), 0, 0)
+		list, _ := builtin.Authority.Native(st).AllCandidates()
+		proposers, _, _ := poa.NewCandidates(list).Pick(st)
+		sched, err := poa.NewSchedulerV2(addressOf(key), proposers, parent.Number(), parent.Timestamp(), nil)
+		assert.Nil(c.t, err)
+		if sched.IsTheTime(parent.Timestamp() + thor.BlockInterval) {
+			return key
+		}
+	}
+	c.t.Fatal("no proposer scheduled")
+	return nil
+}
+
+func TestTracker(t *testing.T) {
+	c := newTestChain(t)
+	tracker, err := New(c.repo, c.stater, poa.NewSeeder(c.repo, c.db), c.db, thor.ForkConfig{})
+	assert.Nil(t, err)
+
+	// all slots taken
+	k1 := c.first()
+	assert.Empty(t, c.pack(k1))
+
+	// the first proposer misses its slot
+	missing := c.first()
+	var signer *ecdsa.PrivateKey
+	for _, k := range c.keys {
+		if k != missing {
+			signer = k
+		}
+	}
+	updates := c.pack(signer)
+	assert.Contains(t, updates, poa.Proposer{Address: addressOf(missing), Active: false})
+
+	// the inactive proposer misses its own slot again
+	parent := c.repo.BestBlockSummary().Header
+	st := c.stater.NewState(parent.StateRoot(), parent.Number(), 0, 0)
+	list, err := builtin.Authority.Native(st).AllCandidates()
+	assert.Nil(t, err)
+	proposers, _, err := poa.NewCandidates(list).Pick(st)
+	assert.Nil(t, err)
+	sched, err := poa.NewSchedulerV2(addressOf(missing), proposers, parent.Number(), parent.Timestamp(), nil)
+	assert.Nil(t, err)
+	c.packAfter(signer, sched.Schedule(parent.Timestamp()+thor.BlockInterval)+thor.BlockInterval)
+	assert.Nil(t, tracker.Sync())
+
+	r, err := tracker.Record(addressOf(missing))
+	assert.Nil(t, err)
+	assert.NotNil(t, r)
+	assert.False(t, r.Active)
+	assert.Equal(t, uint64(2), r.Missed)
+	assert.Equal(t, uint64(1), r.Deactivations)
+
+	// the inactive proposer comes back
+	updates = c.pack(missing)
+	assert.Contains(t, updates, poa.Proposer{Address: addressOf(missing), Active: true})
+	assert.Nil(t, tracker.Sync())
+
+	r, err = tracker.Record(addressOf(missing))
+	assert.Nil(t, err)
+	assert.True(t, r.Active)
+	assert.Equal(t, uint64(2), r.Missed)
+	assert.Equal(t, uint64(1), r.Deactivations)
+	assert.Equal(t, uint64(1), r.Activations)
+	assert.Equal(t, uint32(4), r.LastSeen)
+	assert.Equal(t, c.repo.BestBlockSummary().Header.Timestamp(), r.LastSeenTime)
+
+	r, err = tracker.Record(thor.Address{})
+	assert.Nil(t, err)
+	assert.Nil(t, r)
+
+	produced := func(tracker *Tracker) (n uint64) {
+		records, err := tracker.Records()
+		assert.Nil(t, err)
+		for _, r := range records {
+			n += r.Produced
+		}
+		return
+	}
+	assert.Equal(t, uint64(4), produced(tracker))
+
+	// blocks are processed once
+	assert.Nil(t, tracker.Sync())
+	assert.Equal(t, uint64(4), produced(tracker))
+
+	// restart
+	c.pack(c.first())
+	tracker, err = New(c.repo, c.stater, poa.NewSeeder(c.repo, c.db), c.db, thor.ForkConfig{})
+	assert.Nil(t, err)
+	assert.Nil(t, tracker.Sync())
+	assert.Equal(t, uint64(5), produced(tracker))
+}
+
+func TestTrackerStartsAfterBest(t *testing.T) {
+	c := newTestChain(t)
+	c.pack(c.first())
+
+	tracker, err := New(c.repo, c.stater, poa.NewSeeder(c.repo, c.db), c.db, thor.ForkConfig{})
+	assert.Nil(t, err)
+	assert.Nil(t, tracker.Sync())
+	records, err := tracker.Records()
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+
+	key := c.first()
+	c.pack(key)
+	assert.Nil(t, tracker.Sync())
+	r, err := tracker.Record(addressOf(key))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), r.Produced)
+	assert.Equal(t, uint32(2), r.LastSeen)
+}
+
+func TestTrackerReorg(t *testing.T) {
+	c := newTestChain(t)
+	tracker, err := New(c.repo, c.stater, poa.NewSeeder(c.repo, c.db), c.db, thor.ForkConfig{})
+	assert.Nil(t, err)
+	// syncs only once the reorg is done
+	fresh, err := New(c.repo, c.stater, poa.NewSeeder(c.repo, c.db), muxdb.NewMem(), thor.ForkConfig{})
+	assert.Nil(t, err)
+
+	c.pack(c.first())
+	forkPoint := c.repo.BestBlockSummary().Header
+	k2 := c.first()
+	c.pack(k2)
+	c.pack(c.first())
+	assert.Nil(t, tracker.Sync())
+
+	// the other branch upon block 1, where the first proposer misses its slot
+	assert.Nil(t, c.repo.SetBestBlockID(forkPoint.ID()))
+	for _, k := range c.keys {
+		if k != k2 {
+			c.pack(k)
+			break
+		}
+	}
+	assert.Nil(t, tracker.Sync())
+	assert.Nil(t, fresh.Sync())
+
+	records, err := tracker.Records()
+	assert.Nil(t, err)
+	expected, err := fresh.Records()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, expected)
+	assert.Equal(t, expected, records)
+
+	// undo records of the replaced blocks are removed
+	_, err = tracker.data.Get(undoKey(3))
+	assert.True(t, tracker.data.IsNotFound(err))
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2021 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package muxdb
+
+import (
+	"context"
+
+	"github.com/ashkanabbasii/thor/kv"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// bulk will auto flush when the batch size exceeds this value.
+	bulkAutoFlushSize = 1024 * 1024
+)
+
+var (
+	writeOpt = opt.WriteOptions{}
+	scanOpt  = opt.ReadOptions{DontFillCache: true}
+)
+
+// levelEngine implements kv.Store on top of leveldb.
+type levelEngine struct {
+	db *leveldb.DB
+}
+
+func newLevelEngine(db *leveldb.DB) kv.Store {
+	return &levelEngine{db}
+}
+
+func (ldb *levelEngine) IsNotFound(err error) bool {
+	return err == leveldb.ErrNotFound
+}
+
+func (ldb *levelEngine) Get(key []byte) ([]byte, error) {
+	return ldb.db.Get(key, nil)
+}
+
+func (ldb *levelEngine) Has(key []byte) (bool, error) {
+	return ldb.db.Has(key, nil)
+}
+
+func (ldb *levelEngine) Put(key, val []byte) error {
+	return ldb.db.Put(key, val, &writeOpt)
+}
+
+func (ldb *levelEngine) Delete(key []byte) error {
+	return ldb.db.Delete(key, &writeOpt)
+}
+
+func (ldb *levelEngine) Snapshot() kv.Snapshot {
+	s, err := ldb.db.GetSnapshot()
+	return &struct {
+		kv.GetFunc
+		kv.HasFunc
+		kv.IsNotFoundFunc
+		kv.ReleaseFunc
+	}{
+		func(key []byte) ([]byte, error) {
+			if err != nil {
+				return nil, err
+			}
+			return s.Get(key, nil)
+		},
+		func(key []byte) (bool, error) {
+			if err != nil {
+				return false, err
+			}
+			return s.Has(key, nil)
+		},
+		ldb.IsNotFound,
+		func() {
+			if s != nil {
+				s.Release()
+			}
+		},
+	}
+}
+
+func (ldb *levelEngine) Bulk() kv.Bulk {
+	var (
+		batch     = &leveldb.Batch{}
+		autoFlush bool
+	)
+
+	flush := func(minSize int) error {
+		if batch.Len() > 0 && len(batch.Dump()) >= minSize {
+			if err := ldb.db.Write(batch, &writeOpt); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	}
+
+	return &struct {
+		kv.PutFunc
+		kv.DeleteFunc
+		kv.EnableAutoFlushFunc
+		kv.WriteFunc
+	}{
+		func(key, val []byte) error {
+			batch.Put(key, val)
+			if autoFlush {
+				return flush(bulkAutoFlushSize)
+			}
+			return nil
+		},
+		func(key []byte) error {
+			batch.Delete(key)
+			if autoFlush {
+				return flush(bulkAutoFlushSize)
+			}
+			return nil
+		},
+		func() { autoFlush = true },
+		func() error { return flush(0) },
+	}
+}
+
+func (ldb *levelEngine) Iterate(r kv.Range) kv.Iterator {
+	return ldb.db.NewIterator(&util.Range{Start: r.Start, Limit: r.Limit}, &scanOpt)
+}
+
+func (ldb *levelEngine) DeleteRange(ctx context.Context, r kv.Range) error {
+	iter := ldb.db.NewIterator(&util.Range{Start: r.Start, Limit: r.Limit}, &scanOpt)
+	defer iter.Release()
+
+	bulk := ldb.Bulk()
+	bulk.EnableAutoFlush()
+	for iter.Next() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if err := bulk.Delete(iter.Key()); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return bulk.Write()
+}
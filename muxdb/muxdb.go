@@ -0,0 +1,111 @@
+// Copyright (c) 2021 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+// Package muxdb implements the storage layer for block-chain.
+// It manages instance of merkle-patricia-trie, and general purpose named kv-store.
+package muxdb
+
+import (
+	"github.com/ashkanabbasii/thor/kv"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+const (
+	// the key space for trie nodes. named stores never start with it.
+	trieSpacePrefix = "\x00"
+)
+
+// Options optional parameters for MuxDB.
+type Options struct {
+	// ReadCacheMB the size of the read cache of the underlying engine, in MB.
+	ReadCacheMB int
+	// OpenFilesCacheCapacity the maximum number of files can be opened at the same time.
+	OpenFilesCacheCapacity int
+	// WriteBufferMB the size of the write buffer of the underlying engine, in MB.
+	WriteBufferMB int
+}
+
+// MuxDB is the database to efficiently store state trie and block-chain data.
+type MuxDB struct {
+	engine  kv.Store
+	closeFn func() error
+}
+
+// Open opens or creates DB at the given path.
+func Open(path string, options *Options) (*MuxDB, error) {
+	var opts opt.Options
+	if options != nil {
+		opts.BlockCacheCapacity = options.ReadCacheMB * opt.MiB
+		opts.OpenFilesCacheCapacity = options.OpenFilesCacheCapacity
+		opts.WriteBuffer = options.WriteBufferMB * opt.MiB
+	}
+
+	ldb, err := leveldb.OpenFile(path, &opts)
+	if errors.IsCorrupted(err) {
+		ldb, err = leveldb.RecoverFile(path, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &MuxDB{
+		engine:  newLevelEngine(ldb),
+		closeFn: ldb.Close,
+	}, nil
+}
+
+// NewMem creates a memory-backed DB.
+func NewMem() *MuxDB {
+	storage := storage.NewMemStorage()
+	ldb, _ := leveldb.Open(storage, nil)
+
+	return &MuxDB{
+		engine:  newLevelEngine(ldb),
+		closeFn: ldb.Close,
+	}
+}
+
+// Close closes the DB.
+func (db *MuxDB) Close() error {
+	return db.closeFn()
+}
+
+// NewStore creates named kv-store.
+func (db *MuxDB) NewStore(name string) kv.Store {
+	return kv.Bucket(name).NewStore(db.engine)
+}
+
+// IsNotFound returns if the given error means not found.
+func (db *MuxDB) IsNotFound(err error) bool {
+	return db.engine.IsNotFound(err)
+}
+
+// NewTrie creates trie with existing root node.
+//
+// If root is zero or blake2b hash of an empty string, the trie is
+// initialized as an empty trie.
+func (db *MuxDB) NewTrie(name string, root thor.Bytes32, commitNum, distinctNum uint32) *Trie {
+	return newTrie(db.newTrieBackend(name, false), root, commitNum, distinctNum)
+}
+
+// NewNonCryptoTrie creates non-crypto trie with existing root node.
+//
+// If root is zero or blake2b hash of an empty string, the trie is
+// initialized as an empty trie.
+func (db *MuxDB) NewNonCryptoTrie(name string, root thor.Bytes32, commitNum, distinctNum uint32) *Trie {
+	return newTrie(db.newTrieBackend(name, true), root, commitNum, distinctNum)
+}
+
+func (db *MuxDB) newTrieBackend(name string, nonCrypto bool) *trieBackend {
+	return &trieBackend{
+		name:      name,
+		store:     kv.Bucket(trieSpacePrefix + name).NewStore(db.engine),
+		nonCrypto: nonCrypto,
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package muxdb
+
+import (
+	"testing"
+
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/trie"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	db := NewMem()
+	defer db.Close()
+
+	s1 := db.NewStore("s1")
+	s2 := db.NewStore("s2")
+
+	assert.Nil(t, s1.Put([]byte("k"), []byte("v1")))
+	assert.Nil(t, s2.Put([]byte("k"), []byte("v2")))
+
+	v, err := s1.Get([]byte("k"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v1"), v)
+
+	v, err = s2.Get([]byte("k"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v2"), v)
+
+	_, err = s1.Get([]byte("x"))
+	assert.True(t, db.IsNotFound(err))
+}
+
+func TestTrie(t *testing.T) {
+	db := NewMem()
+	defer db.Close()
+
+	tr := db.NewTrie("t", thor.Bytes32{}, 0, 0)
+	assert.Nil(t, tr.Update([]byte("k1"), []byte("v1"), []byte("m1")))
+	assert.Nil(t, tr.Update([]byte("k2"), []byte("v2"), nil))
+
+	root, commit := tr.Stage(1, 0)
+	assert.Equal(t, tr.Hash(), root)
+	assert.Nil(t, commit())
+
+	tr = db.NewTrie("t", root, 1, 0)
+	val, meta, err := tr.Get([]byte("k1"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v1"), val)
+	assert.Equal(t, []byte("m1"), meta)
+
+	// plain trie can read nodes stored by extended trie
+	plain, err := trie.New(root, db.newTrieBackend("t", false))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v2"), plain.Get([]byte("k2")))
+}
+
+func TestNonCryptoTrie(t *testing.T) {
+	db := NewMem()
+	defer db.Close()
+
+	tr := db.NewNonCryptoTrie("n", thor.Bytes32{}, 0, 0)
+	assert.Nil(t, tr.Update([]byte("k"), []byte("v0"), nil))
+	root, err := tr.Commit(0, 0)
+	assert.Nil(t, err)
+
+	// two branches with distinct numbers
+	for i := uint32(1); i <= 2; i++ {
+		tr := db.NewNonCryptoTrie("n", root, 0, 0)
+		assert.Nil(t, tr.Update([]byte("k"), []byte{byte(i)}, nil))
+		_, err := tr.Commit(1, i)
+		assert.Nil(t, err)
+	}
+
+	for i := uint32(1); i <= 2; i++ {
+		val, _, err := db.NewNonCryptoTrie("n", trie.NonCryptoNodeHash, 1, i).Get([]byte("k"))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{byte(i)}, val)
+	}
+	val, _, err := db.NewNonCryptoTrie("n", trie.NonCryptoNodeHash, 0, 0).Get([]byte("k"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v0"), val)
+}
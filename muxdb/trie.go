@@ -0,0 +1,141 @@
+// Copyright (c) 2021 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package muxdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ashkanabbasii/thor/kv"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/trie"
+)
+
+// trieBackend is the node storage of a named trie.
+//
+// Nodes of crypto tries are keyed by hash, so identical nodes are shared. Since all
+// nodes of a non-crypto trie have the same hash, its nodes are keyed by path and
+// sequence number.
+type trieBackend struct {
+	name      string
+	store     kv.Store
+	nonCrypto bool
+}
+
+// Get implements trie.DatabaseReader.
+func (b *trieBackend) Get(key []byte) ([]byte, error) {
+	return b.store.Get(key)
+}
+
+// Put implements trie.DatabaseWriter.
+func (b *trieBackend) Put(key, val []byte) error {
+	return b.store.Put(key, val)
+}
+
+// Encode implements trie.DatabaseKeyEncoder.
+func (b *trieBackend) Encode(hash []byte, seq uint64, path []byte) []byte {
+	if !b.nonCrypto {
+		return hash
+	}
+	key := make([]byte, len(path)+8)
+	copy(key, path)
+	binary.BigEndian.PutUint64(key[len(path):], seq)
+	return key
+}
+
+// stagedWriter collects nodes into a bulk, with the backend's key encoding.
+type stagedWriter struct {
+	kv.Bulk
+	*trieBackend
+}
+
+// Put implements trie.DatabaseWriter.
+func (w *stagedWriter) Put(key, val []byte) error {
+	return w.Bulk.Put(key, val)
+}
+
+// Trie is the managed trie.
+type Trie struct {
+	back *trieBackend
+	ext  *trie.ExtendedTrie
+}
+
+func newTrie(back *trieBackend, root thor.Bytes32, commitNum, distinctNum uint32) *Trie {
+	return &Trie{
+		back,
+		trie.NewExtended(root, makeSeq(commitNum, distinctNum), back, back.nonCrypto),
+	}
+}
+
+// makeSeq packs commit number and distinct number into the node sequence number.
+func makeSeq(commitNum, distinctNum uint32) uint64 {
+	return uint64(commitNum)<<32 | uint64(distinctNum)
+}
+
+// Name returns the trie name.
+func (t *Trie) Name() string {
+	return t.back.name
+}
+
+// Get returns the value and metadata for key stored in the trie.
+func (t *Trie) Get(key []byte) ([]byte, []byte, error) {
+	return t.ext.Get(key)
+}
+
+// Update associates key with value and metadata in the trie. If value has
+// length zero, any existing value is deleted from the trie.
+func (t *Trie) Update(key, val, meta []byte) error {
+	return t.ext.Update(key, val, meta)
+}
+
+// Prove constructs a merkle proof for key, and writes the proof nodes into proofDb.
+func (t *Trie) Prove(key []byte, proofDb trie.DatabaseWriter) error {
+	return t.ext.Prove(key, proofDb)
+}
+
+// Hash returns the root hash of the trie.
+func (t *Trie) Hash() thor.Bytes32 {
+	return t.ext.Hash()
+}
+
+// Stage processes trie updates and calculates the new root hash.
+// Nodes are not written until commit is called.
+func (t *Trie) Stage(newCommitNum, newDistinctNum uint32) (root thor.Bytes32, commit func() error) {
+	var (
+		bulk = t.back.store.Bulk()
+		w    = &stagedWriter{bulk, t.back}
+		err  error
+	)
+	bulk.EnableAutoFlush()
+
+	root, err = t.ext.CommitTo(w, makeSeq(newCommitNum, newDistinctNum))
+	if err != nil {
+		return thor.Bytes32{}, func() error { return err }
+	}
+	return root, bulk.Write
+}
+
+// Commit writes all updates into the database and returns the new root hash.
+func (t *Trie) Commit(newCommitNum, newDistinctNum uint32) (thor.Bytes32, error) {
+	root, commit := t.Stage(newCommitNum, newDistinctNum)
+	if err := commit(); err != nil {
+		return thor.Bytes32{}, err
+	}
+	return root, nil
+}
+
+// NodeIterator returns an iterator that returns nodes of the trie.
+// Iteration starts at the key after the given start key.
+func (t *Trie) NodeIterator(start []byte) trie.NodeIterator {
+	return t.ext.NodeIterator(start, func(uint64) bool { return true })
+}
+
+// Copy makes a copy of the trie, sharing the underlying nodes.
+func (t *Trie) Copy() *Trie {
+	return &Trie{
+		t.back,
+		trie.NewExtendedCached(t.ext.RootNode(), t.back, t.back.nonCrypto),
+	}
+}
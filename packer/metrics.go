@@ -0,0 +1,16 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package packer
+
+import (
+	"github.com/ashkanabbasii/thor/metrics"
+)
+
+var (
+	metricExecutionTime      = metrics.LazyLoadHistogram("packer_block_execution_ms", []int64{0, 50, 100, 200, 300, 400, 500, 750, 1000, 2000})
+	metricTargetGasLimit     = metrics.LazyLoadGauge("packer_target_gas_limit")
+	metricGasLimitAdjustment = metrics.LazyLoadCounterVec("packer_gas_limit_adjustment_count", []string{"direction"})
+)
@@ -6,62 +6,279 @@
 package packer
 
 import (
+	"sync/atomic"
+	"time"
+
 	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
 	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/log"
 	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/runtime"
+	"github.com/ashkanabbasii/thor/state"
 	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/xenv"
 )
 
+var logger = log.WithContext("pkg", "packer")
+
+// maxAdaptiveGasLimit caps the target gas limit tuned by packing time.
+const maxAdaptiveGasLimit = 4 * thor.InitialGasLimit
+
 // Packer to pack txs and build new blocks.
 type Packer struct {
 	repo           *chain.Repository
+	stater         *state.Stater
 	nodeMaster     thor.Address
 	beneficiary    *thor.Address
 	targetGasLimit uint64
 	forkConfig     thor.ForkConfig
 	seeder         *poa.Seeder
+
+	adaptiveGasLimit uint64       // target gas limit tuned by packing time, accessed atomically
+	lastPacked       atomic.Value // *packedBlock, the last block packed by a scheduled flow
+}
+
+// packedBlock is the packing stats of a block, identified by its signing hash.
+type packedBlock struct {
+	signingHash thor.Bytes32
+	gasLimit    uint64
+	gasUsed     uint64
+	elapsed     time.Duration
 }
 
 // New create a new Packer instance.
 // The beneficiary is optional, it defaults to endorsor if not set.
-//func New(
-//	repo *chain.Repository,
-//	nodeMaster thor.Address,
-//	beneficiary *thor.Address,
-//	forkConfig thor.ForkConfig,
-//) *Packer {
-//	return &Packer{
-//		repo,
-//		stater,
-//		nodeMaster,
-//		beneficiary,
-//		0,
-//		forkConfig,
-//		poa.NewSeeder(repo),
-//	}
-//}
+func New(
+	repo *chain.Repository,
+	stater *state.Stater,
+	seeder *poa.Seeder,
+	nodeMaster thor.Address,
+	beneficiary *thor.Address,
+	forkConfig thor.ForkConfig,
+) *Packer {
+	return &Packer{
+		repo,
+		stater,
+		nodeMaster,
+		beneficiary,
+		0,
+		forkConfig,
+		seeder,
+		0,
+		atomic.Value{},
+	}
+}
 
 // Schedule schedule a packing flow to pack new block upon given parent and clock time.
+// If the parent is the last block packed by the packer, which is committed now, its packing
+// stats are taken to tune the target gas limit.
 func (p *Packer) Schedule(parent *chain.BlockSummary, nowTimestamp uint64) (flow *Flow, err error) {
-	return nil, nil
+	if last, ok := p.lastPacked.Load().(*packedBlock); ok && last != nil && last.signingHash == parent.Header.SigningHash() {
+		p.lastPacked.Store((*packedBlock)(nil))
+		p.adaptGasLimit(last.gasLimit, last.gasUsed, last.elapsed)
+	}
+
+	state := p.stater.NewState(parent.Header.StateRoot(), parent.Header.Number(), parent.Conflicts, parent.SteadyNum)
+
+	features, err := p.prepare(parent.Header, state)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		authority   = builtin.Authority.Native(state)
+		beneficiary thor.Address
+	)
+	if p.beneficiary != nil {
+		beneficiary = *p.beneficiary
+	} else {
+		// no beneficiary set, defaults to the endorsor of the node master
+		listed, endorsor, _, _, err := authority.Get(p.nodeMaster)
+		if err != nil {
+			return nil, err
+		}
+		if listed {
+			beneficiary = endorsor
+		}
+	}
+
+	endorsement, err := builtin.Params.Native(state).Get(thor.KeyProposerEndorsement)
+	if err != nil {
+		return nil, err
+	}
+	mbp, err := builtin.Params.Native(state).Get(thor.KeyMaxBlockProposers)
+	if err != nil {
+		return nil, err
+	}
+	maxBlockProposers := mbp.Uint64()
+	if maxBlockProposers == 0 || maxBlockProposers > thor.InitialMaxBlockProposers {
+		maxBlockProposers = thor.InitialMaxBlockProposers
+	}
+
+	candidates, err := authority.Candidates(endorsement, maxBlockProposers)
+	if err != nil {
+		return nil, err
+	}
+	proposers := make([]poa.Proposer, 0, len(candidates))
+	for _, c := range candidates {
+		proposers = append(proposers, poa.Proposer{
+			Address: c.NodeMaster,
+			Active:  c.Active,
+		})
+	}
+
+	var sched poa.Scheduler
+	if parent.Header.Number()+1 >= p.forkConfig.VIP214 {
+		seed, err := p.seeder.Generate(parent.Header.ID())
+		if err != nil {
+			return nil, err
+		}
+		sched, err = poa.NewSchedulerV2(p.nodeMaster, proposers, parent.Header.Number(), parent.Header.Timestamp(), seed)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		sched, err = poa.NewSchedulerV1(p.nodeMaster, proposers, parent.Header.Number(), parent.Header.Timestamp())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	newBlockTime := sched.Schedule(nowTimestamp)
+	updates, score := sched.Updates(newBlockTime)
+
+	for _, u := range updates {
+		if _, err := authority.Update(u.Address, u.Active); err != nil {
+			return nil, err
+		}
+	}
+
+	rt := runtime.New(
+		p.repo.NewChain(parent.Header.ID()),
+		state,
+		&xenv.BlockContext{
+			Beneficiary: beneficiary,
+			Signer:      p.nodeMaster,
+			Number:      parent.Header.Number() + 1,
+			Time:        newBlockTime,
+			GasLimit:    p.gasLimit(parent.Header.GasLimit()),
+			TotalScore:  parent.Header.TotalScore() + score,
+		},
+		p.forkConfig)
+
+	flow = newFlow(p, parent.Header, rt, features)
+	flow.scheduled = true
+	return flow, nil
 }
 
 // Mock create a packing flow upon given parent, but with a designated timestamp.
 // It will skip the PoA verification and scheduling, and the block produced by
 // the returned flow is not in consensus.
 func (p *Packer) Mock(parent *chain.BlockSummary, targetTime uint64, gasLimit uint64) (*Flow, error) {
-	return nil, nil
+	state := p.stater.NewState(parent.Header.StateRoot(), parent.Header.Number(), parent.Conflicts, parent.SteadyNum)
+
+	features, err := p.prepare(parent.Header, state)
+	if err != nil {
+		return nil, err
+	}
+
+	gl := gasLimit
+	if gasLimit == 0 {
+		gl = p.gasLimit(parent.Header.GasLimit())
+	}
+
+	beneficiary := p.nodeMaster
+	if p.beneficiary != nil {
+		beneficiary = *p.beneficiary
+	}
+
+	rt := runtime.New(
+		p.repo.NewChain(parent.Header.ID()),
+		state,
+		&xenv.BlockContext{
+			Beneficiary: beneficiary,
+			Signer:      p.nodeMaster,
+			Number:      parent.Header.Number() + 1,
+			Time:        targetTime,
+			GasLimit:    gl,
+			TotalScore:  parent.Header.TotalScore() + 1,
+		},
+		p.forkConfig)
+
+	return newFlow(p, parent.Header, rt, features), nil
+}
+
+// prepare applies fork hooks on the parent state, and returns tx features supported by the new block.
+func (p *Packer) prepare(parent *block.Header, state *state.State) (tx.Features, error) {
+	// Before process hook of VIP-191, update builtin extension contract's code to V2
+	vip191 := p.forkConfig.VIP191
+	if vip191 == 0 {
+		vip191 = 1
+	}
+	if parent.Number()+1 == vip191 {
+		if err := state.SetCode(builtin.Extension.Address, builtin.Extension.V2.RuntimeBytecodes()); err != nil {
+			return 0, err
+		}
+	}
+
+	var features tx.Features
+	if parent.Number()+1 >= p.forkConfig.VIP191 {
+		features |= tx.DelegationFeature
+	}
+	return features, nil
 }
 
 func (p *Packer) gasLimit(parentGasLimit uint64) uint64 {
 	if p.targetGasLimit != 0 {
 		return block.GasLimit(p.targetGasLimit).Qualify(parentGasLimit)
 	}
+	if adaptive := atomic.LoadUint64(&p.adaptiveGasLimit); adaptive != 0 {
+		return block.GasLimit(adaptive).Qualify(parentGasLimit)
+	}
 	return parentGasLimit
 }
 
 // SetTargetGasLimit set target gas limit, the Packer will adjust block gas limit close to
-// it as it can.
+// it as it can. If it's zero, the target is tuned by the time spent on executing txs.
 func (p *Packer) SetTargetGasLimit(gl uint64) {
 	p.targetGasLimit = gl
 }
+
+// adaptGasLimit tunes the target gas limit according to the execution time of a committed block.
+// Only blocks packed by scheduled flows are sampled, when they become the parent of the next schedule.
+// The target steps up while a full block can be executed within thor.TolerableBlockPackingTime,
+// up to maxAdaptiveGasLimit, and steps down once the execution time exceeds it.
+func (p *Packer) adaptGasLimit(gasLimit, gasUsed uint64, elapsed time.Duration) {
+	metricExecutionTime().Observe(elapsed.Milliseconds())
+
+	if p.targetGasLimit != 0 {
+		// fixed target
+		return
+	}
+
+	var (
+		target    uint64
+		direction string
+	)
+	switch {
+	case elapsed > thor.TolerableBlockPackingTime:
+		target = block.GasLimit(gasLimit).Adjust(-int64(gasLimit))
+		direction = "down"
+	case gasUsed > gasLimit/3 && uint64(elapsed)*gasLimit/gasUsed < uint64(thor.TolerableBlockPackingTime):
+		// only blocks with enough txs are fair samples to estimate the time to execute a full block
+		target = block.GasLimit(gasLimit).Adjust(int64(gasLimit))
+		if target > maxAdaptiveGasLimit {
+			target = maxAdaptiveGasLimit
+		}
+		direction = "up"
+	default:
+		return
+	}
+
+	atomic.StoreUint64(&p.adaptiveGasLimit, target)
+	metricTargetGasLimit().Set(int64(target))
+	metricGasLimitAdjustment().AddWithLabel(1, map[string]string{"direction": direction})
+	logger.Debug("target gas limit adjusted", "value", target, "elapsed", elapsed)
+}
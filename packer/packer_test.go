@@ -0,0 +1,280 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package packer
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/vrf"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	masterKey, _ = crypto.GenerateKey()
+	senderKey, _ = crypto.GenerateKey()
+	endorsor     = thor.BytesToAddress([]byte("endorsor"))
+	recipient    = thor.BytesToAddress([]byte("recipient"))
+)
+
+func keyAddr(key *ecdsa.PrivateKey) thor.Address {
+	return thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+}
+
+func newTestChain(t *testing.T) (*chain.Repository, *state.Stater) {
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+
+	st := stater.NewState(thor.Bytes32{}, 0, 0, 0)
+	// the storage of an empty account is not committed
+	assert.Nil(t, st.SetCode(builtin.Authority.Address, builtin.Authority.RuntimeBytecodes()))
+	_, err := builtin.Authority.Native(st).Add(keyAddr(masterKey), endorsor, thor.Bytes32{})
+	assert.Nil(t, err)
+	assert.Nil(t, st.SetBalance(keyAddr(senderKey), big.NewInt(1e18)))
+	assert.Nil(t, st.SetEnergy(keyAddr(senderKey), new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6)), 0))
+	stage, err := st.Stage(0, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+	return repo, stater
+}
+
+func newTransferTx(chainTag byte, nonce uint64, blockRef uint32) *tx.Transaction {
+	return tx.MustSign(new(tx.Builder).
+		ChainTag(chainTag).
+		Clause(tx.NewClause(&recipient).WithValue(big.NewInt(100))).
+		Gas(21000).
+		BlockRef(tx.NewBlockRef(blockRef)).
+		Expiration(100).
+		Nonce(nonce).
+		Build(), senderKey)
+}
+
+func TestSchedule(t *testing.T) {
+	repo, stater := newTestChain(t)
+	genesis := repo.GenesisBlock().Header()
+
+	p := New(repo, stater, poa.NewSeeder(repo, nil), keyAddr(masterKey), nil, thor.NoFork)
+	flow, err := p.Schedule(repo.BestBlockSummary(), genesis.Timestamp())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), flow.Number())
+	assert.Equal(t, genesis.Timestamp()+thor.BlockInterval, flow.When())
+	assert.Equal(t, genesis.ID(), flow.ParentHeader().ID())
+	// beneficiary defaults to the endorsor
+	assert.Equal(t, endorsor, flow.runtime.Context().Beneficiary)
+
+	// not a listed proposer
+	other, _ := crypto.GenerateKey()
+	_, err = New(repo, stater, poa.NewSeeder(repo, nil), keyAddr(other), nil, thor.NoFork).Schedule(repo.BestBlockSummary(), genesis.Timestamp())
+	assert.NotNil(t, err)
+}
+
+func TestAdoptAndPack(t *testing.T) {
+	repo, stater := newTestChain(t)
+	genesis := repo.GenesisBlock().Header()
+
+	beneficiary := thor.BytesToAddress([]byte("beneficiary"))
+	p := New(repo, stater, poa.NewSeeder(repo, nil), keyAddr(masterKey), &beneficiary, thor.NoFork)
+	flow, err := p.Schedule(repo.BestBlockSummary(), genesis.Timestamp())
+	assert.Nil(t, err)
+
+	trx := newTransferTx(repo.ChainTag(), 1, 0)
+	assert.Nil(t, flow.Adopt(trx))
+	assert.True(t, IsKnownTx(flow.Adopt(trx)))
+	assert.True(t, IsBadTx(flow.Adopt(newTransferTx(repo.ChainTag()+1, 2, 0))))
+	assert.True(t, IsTxNotAdoptableNow(flow.Adopt(newTransferTx(repo.ChainTag(), 3, 10))))
+
+	// delegation is not supported
+	var features tx.Features
+	features.SetDelegated(true)
+	delegated := tx.MustSignDelegated(new(tx.Builder).
+		ChainTag(repo.ChainTag()).
+		Clause(tx.NewClause(&recipient)).
+		Gas(21000).
+		Expiration(100).
+		Features(features).
+		Build(), senderKey, masterKey)
+	assert.True(t, IsBadTx(flow.Adopt(delegated)))
+
+	_, _, _, err = flow.Pack(senderKey, 0, false)
+	assert.NotNil(t, err)
+
+	blk, stage, receipts, err := flow.Pack(masterKey, 0, true)
+	assert.Nil(t, err)
+	header := blk.Header()
+
+	signer, err := header.Signer()
+	assert.Nil(t, err)
+	assert.Equal(t, keyAddr(masterKey), signer)
+	assert.Equal(t, beneficiary, header.Beneficiary())
+	assert.Equal(t, uint64(21000), header.GasUsed())
+	assert.Equal(t, tx.Transactions{trx}, blk.Transactions())
+	assert.Equal(t, receipts.RootHash(), header.ReceiptsRoot())
+	assert.Equal(t, stage.Hash(), header.StateRoot())
+	// finality not activated
+	assert.False(t, header.COM())
+	assert.Empty(t, header.Alpha())
+
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+	st := stater.NewState(root, 1, 0, 0)
+	bal, err := st.GetBalance(recipient)
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(100), bal)
+}
+
+func TestPackWithVRF(t *testing.T) {
+	repo, stater := newTestChain(t)
+	genesis := repo.GenesisBlock().Header()
+
+	p := New(repo, stater, poa.NewSeeder(repo, nil), keyAddr(masterKey), nil, thor.ForkConfig{})
+	flow, err := p.Schedule(repo.BestBlockSummary(), genesis.Timestamp())
+	assert.Nil(t, err)
+	assert.Nil(t, flow.Adopt(newTransferTx(repo.ChainTag(), 1, 0)))
+
+	blk, _, _, err := flow.Pack(masterKey, 0, true)
+	assert.Nil(t, err)
+	header := blk.Header()
+
+	signer, err := header.Signer()
+	assert.Nil(t, err)
+	assert.Equal(t, keyAddr(masterKey), signer)
+	assert.True(t, header.COM())
+	assert.Equal(t, tx.DelegationFeature, header.TxsFeatures())
+
+	// alpha is initialized with parent state root
+	assert.Equal(t, genesis.StateRoot().Bytes(), header.Alpha())
+	beta, err := header.Beta()
+	assert.Nil(t, err)
+	expected, _, err := vrf.Prove(masterKey, header.Alpha())
+	assert.Nil(t, err)
+	assert.Equal(t, expected, beta)
+}
+
+func TestMock(t *testing.T) {
+	repo, stater := newTestChain(t)
+	genesis := repo.GenesisBlock().Header()
+
+	// mock does not require the node master to be a proposer
+	other, _ := crypto.GenerateKey()
+	p := New(repo, stater, poa.NewSeeder(repo, nil), keyAddr(other), nil, thor.NoFork)
+	flow, err := p.Mock(repo.BestBlockSummary(), genesis.Timestamp()+100, 100_000)
+	assert.Nil(t, err)
+	assert.Equal(t, genesis.Timestamp()+100, flow.When())
+	assert.Equal(t, genesis.TotalScore()+1, flow.TotalScore())
+
+	assert.Nil(t, flow.Adopt(newTransferTx(repo.ChainTag(), 1, 0)))
+	assert.Nil(t, flow.Adopt(newTransferTx(repo.ChainTag(), 2, 0)))
+	assert.Nil(t, flow.Adopt(newTransferTx(repo.ChainTag(), 3, 0)))
+	assert.Nil(t, flow.Adopt(newTransferTx(repo.ChainTag(), 4, 0)))
+	// gas limit reached
+	assert.True(t, IsGasLimitReached(flow.Adopt(newTransferTx(repo.ChainTag(), 5, 0))))
+
+	blk, _, _, err := flow.Pack(other, 0, false)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100_000), blk.Header().GasLimit())
+	assert.Equal(t, keyAddr(other), blk.Header().Beneficiary())
+}
+
+func TestAdaptGasLimit(t *testing.T) {
+	repo, stater := newTestChain(t)
+	p := New(repo, stater, poa.NewSeeder(repo, nil), keyAddr(masterKey), nil, thor.NoFork)
+
+	const gl = thor.InitialGasLimit
+	step := gl / thor.GasLimitBoundDivisor
+
+	// follows parent if not measured yet
+	assert.Equal(t, gl, p.gasLimit(gl))
+
+	// too few txs to measure
+	p.adaptGasLimit(gl, gl/10, time.Millisecond)
+	assert.Equal(t, gl, p.gasLimit(gl))
+
+	// fast enough to execute a full block
+	p.adaptGasLimit(gl, gl/2, time.Millisecond)
+	assert.Equal(t, gl+step, p.gasLimit(gl))
+
+	// neither fast nor slow, keeps the target
+	p.adaptGasLimit(gl+step, gl/2, thor.TolerableBlockPackingTime/2)
+	assert.Equal(t, gl+step, p.gasLimit(gl))
+
+	// too slow
+	p.adaptGasLimit(gl, gl/10, thor.TolerableBlockPackingTime+time.Millisecond)
+	assert.Equal(t, gl-step, p.gasLimit(gl))
+	// the gas limit bound is respected
+	assert.Equal(t, 2*gl-2*gl/thor.GasLimitBoundDivisor, p.gasLimit(2*gl))
+
+	// capped by the upper bound
+	p.adaptGasLimit(maxAdaptiveGasLimit, maxAdaptiveGasLimit/2, time.Millisecond)
+	assert.Equal(t, maxAdaptiveGasLimit, p.gasLimit(maxAdaptiveGasLimit))
+
+	// fixed target is not affected
+	p.SetTargetGasLimit(gl)
+	p.adaptGasLimit(gl, gl/2, time.Millisecond)
+	assert.Equal(t, gl, p.gasLimit(gl))
+}
+
+func TestScheduleAdaptsGasLimit(t *testing.T) {
+	repo, stater := newTestChain(t)
+	genesis := repo.GenesisBlock().Header()
+
+	const gl = thor.InitialGasLimit
+	p := New(repo, stater, poa.NewSeeder(repo, nil), keyAddr(masterKey), nil, thor.NoFork)
+
+	// mocked blocks are not sampled
+	flow, err := p.Mock(repo.BestBlockSummary(), genesis.Timestamp()+thor.BlockInterval, 50_000)
+	assert.Nil(t, err)
+	assert.Nil(t, flow.Adopt(newTransferTx(repo.ChainTag(), 1, 0)))
+	_, _, _, err = flow.Pack(masterKey, 0, false)
+	assert.Nil(t, err)
+	assert.Nil(t, p.lastPacked.Load())
+
+	flow, err = p.Schedule(repo.BestBlockSummary(), genesis.Timestamp())
+	assert.Nil(t, err)
+	assert.Nil(t, flow.Adopt(newTransferTx(repo.ChainTag(), 1, 0)))
+	assert.NotZero(t, flow.execElapsed)
+	// pretend a half full block to make it a fair sample
+	flow.gasUsed = gl / 2
+
+	blk, stage, receipts, err := flow.Pack(masterKey, 0, false)
+	assert.Nil(t, err)
+
+	// the packed block is not committed
+	_, err = p.Schedule(repo.BestBlockSummary(), genesis.Timestamp())
+	assert.Nil(t, err)
+	assert.Equal(t, gl, p.gasLimit(gl))
+
+	_, err = stage.Commit()
+	assert.Nil(t, err)
+	assert.Nil(t, repo.AddBlock(blk, receipts, 0))
+	assert.Nil(t, repo.SetBestBlockID(blk.Header().ID()))
+
+	// sampled once it's the parent
+	flow, err = p.Schedule(repo.BestBlockSummary(), blk.Header().Timestamp())
+	assert.Nil(t, err)
+	assert.Equal(t, gl+gl/thor.GasLimitBoundDivisor, p.gasLimit(gl))
+	assert.Equal(t, gl+gl/thor.GasLimitBoundDivisor, flow.runtime.Context().GasLimit)
+}
@@ -6,17 +6,46 @@
 package poa
 
 import (
+	"github.com/ashkanabbasii/thor/builtin"
 	"github.com/ashkanabbasii/thor/builtin/authority"
-	//"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/state"
 	"github.com/ashkanabbasii/thor/thor"
 )
 
+// ExclusionReason is the reason why a candidate is excluded from proposers.
+type ExclusionReason uint8
+
+const (
+	// Underfunded means the balance of the endorsor is below the proposer endorsement.
+	Underfunded ExclusionReason = iota + 1
+	// MaxProposersExceeded means the count of satisfied candidates before it reaches the max block proposers.
+	MaxProposersExceeded
+)
+
+func (r ExclusionReason) String() string {
+	switch r {
+	case Underfunded:
+		return "underfunded endorsor"
+	case MaxProposersExceeded:
+		return "max proposers exceeded"
+	default:
+		return "unknown"
+	}
+}
+
+// Exclusion is a candidate excluded from proposers, with the reason.
+type Exclusion struct {
+	NodeMaster thor.Address
+	Reason     ExclusionReason
+}
+
 // Candidates holds candidates list in memory, and tends to be reused in PoA stage without querying from contract.
 type Candidates struct {
 	list       []*authority.Candidate
 	masters    map[thor.Address]int  // map master address to list index
 	endorsors  map[thor.Address]bool // endorsor bitset
 	satisfied  []int
+	excluded   []Exclusion
 	referenced bool
 }
 
@@ -36,6 +65,7 @@ func NewCandidates(list []*authority.Candidate) *Candidates {
 		masters,
 		endorsors,
 		nil,
+		nil,
 		false,
 	}
 }
@@ -47,47 +77,55 @@ func (c *Candidates) Copy() *Candidates {
 	return &cpy
 }
 
-// Pick picks a list of proposers, which satisfy preset conditions.
-//func (c *Candidates) Pick(state *state.State) ([]Proposer, error) {
-//	//satisfied := c.satisfied
-//	//if len(satisfied) == 0 {
-//	//	// re-pick
-//	//	endorsement, err := builtin.Params.Native(state).Get(thor.KeyProposerEndorsement)
-//	//	if err != nil {
-//	//		return nil, err
-//	//	}
-//	//
-//	//	mbp, err := builtin.Params.Native(state).Get(thor.KeyMaxBlockProposers)
-//	//	if err != nil {
-//	//		return nil, err
-//	//	}
-//	//	maxBlockProposers := mbp.Uint64()
-//	//	if maxBlockProposers == 0 || maxBlockProposers > thor.InitialMaxBlockProposers {
-//	//		maxBlockProposers = thor.InitialMaxBlockProposers
-//	//	}
-//	//
-//	//	satisfied = make([]int, 0, len(c.list))
-//	//	for i := 0; i < len(c.list) && uint64(len(satisfied)) < maxBlockProposers; i++ {
-//	//		bal, err := state.GetBalance(c.list[i].Endorsor)
-//	//		if err != nil {
-//	//			return nil, err
-//	//		}
-//	//		if bal.Cmp(endorsement) >= 0 {
-//	//			satisfied = append(satisfied, i)
-//	//		}
-//	//	}
-//	//	c.satisfied = satisfied
-//	//}
-//	//
-//	//proposers := make([]Proposer, 0, len(satisfied))
-//	//for _, i := range satisfied {
-//	//	proposers = append(proposers, Proposer{
-//	//		Address: c.list[i].NodeMaster,
-//	//		Active:  c.list[i].Active,
-//	//	})
-//	//}
-//	//return proposers, nil
-//}
+// Pick picks a list of proposers, which satisfy preset conditions. Candidates excluded are returned
+// along with reasons, in the order of the list.
+func (c *Candidates) Pick(state *state.State) ([]Proposer, []Exclusion, error) {
+	satisfied := c.satisfied
+	if len(satisfied) == 0 {
+		// re-pick
+		endorsement, err := builtin.Params.Native(state).Get(thor.KeyProposerEndorsement)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		mbp, err := builtin.Params.Native(state).Get(thor.KeyMaxBlockProposers)
+		if err != nil {
+			return nil, nil, err
+		}
+		maxBlockProposers := mbp.Uint64()
+		if maxBlockProposers == 0 || maxBlockProposers > thor.InitialMaxBlockProposers {
+			maxBlockProposers = thor.InitialMaxBlockProposers
+		}
+
+		var excluded []Exclusion
+		satisfied = make([]int, 0, len(c.list))
+		for i := 0; i < len(c.list); i++ {
+			bal, err := state.GetBalance(c.list[i].Endorsor)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch {
+			case bal.Cmp(endorsement) < 0:
+				excluded = append(excluded, Exclusion{c.list[i].NodeMaster, Underfunded})
+			case uint64(len(satisfied)) >= maxBlockProposers:
+				excluded = append(excluded, Exclusion{c.list[i].NodeMaster, MaxProposersExceeded})
+			default:
+				satisfied = append(satisfied, i)
+			}
+		}
+		c.satisfied = satisfied
+		c.excluded = excluded
+	}
+
+	proposers := make([]Proposer, 0, len(satisfied))
+	for _, i := range satisfied {
+		proposers = append(proposers, Proposer{
+			Address: c.list[i].NodeMaster,
+			Active:  c.list[i].Active,
+		})
+	}
+	return proposers, append([]Exclusion(nil), c.excluded...), nil
+}
 
 // Update update candidate activity status, by its master address.
 // It returns false if the given address is not a master.
@@ -115,4 +153,5 @@ func (c *Candidates) IsEndorsor(addr thor.Address) bool {
 // InvalidateCache invalidate the result cache of Pick method.
 func (c *Candidates) InvalidateCache() {
 	c.satisfied = nil
+	c.excluded = nil
 }
@@ -0,0 +1,66 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package poa
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCandidatesPick(t *testing.T) {
+	st := state.NewStater(muxdb.NewMem()).NewState(thor.Bytes32{}, 0, 0, 0)
+	endorsement := big.NewInt(100)
+	assert.Nil(t, builtin.Params.Native(st).Set(thor.KeyProposerEndorsement, endorsement))
+	assert.Nil(t, builtin.Params.Native(st).Set(thor.KeyMaxBlockProposers, big.NewInt(2)))
+
+	// p1, p3, p4 funded, p2 underfunded, p4 beyond the cap
+	authority := builtin.Authority.Native(st)
+	for i, p := range []thor.Address{p1, p2, p3, p4} {
+		endorsor := thor.BytesToAddress([]byte{byte(i + 1)})
+		ok, err := authority.Add(p, endorsor, thor.Bytes32{})
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		if p != p2 {
+			assert.Nil(t, st.SetBalance(endorsor, endorsement))
+		} else {
+			assert.Nil(t, st.SetBalance(endorsor, big.NewInt(99)))
+		}
+	}
+	list, err := authority.AllCandidates()
+	assert.Nil(t, err)
+
+	candidates := NewCandidates(list)
+	proposers, excluded, err := candidates.Pick(st)
+	assert.Nil(t, err)
+	assert.Equal(t, []Proposer{{p1, true}, {p3, true}}, proposers)
+	assert.Equal(t, []Exclusion{{p2, Underfunded}, {p4, MaxProposersExceeded}}, excluded)
+	assert.Equal(t, "underfunded endorsor", excluded[0].Reason.String())
+
+	// results cached
+	assert.True(t, candidates.Update(p1, false))
+	proposers, excluded, err = candidates.Pick(st)
+	assert.Nil(t, err)
+	assert.Equal(t, []Proposer{{p1, false}, {p3, true}}, proposers)
+	assert.Len(t, excluded, 2)
+
+	// cap lifted, 0 means the initial max block proposers
+	assert.Nil(t, builtin.Params.Native(st).Set(thor.KeyMaxBlockProposers, big.NewInt(0)))
+	proposers, _, err = candidates.Pick(st)
+	assert.Nil(t, err)
+	assert.Len(t, proposers, 2)
+
+	candidates.InvalidateCache()
+	proposers, excluded, err = candidates.Pick(st)
+	assert.Nil(t, err)
+	assert.Equal(t, []Proposer{{p1, false}, {p3, true}, {p4, true}}, proposers)
+	assert.Equal(t, []Exclusion{{p2, Underfunded}}, excluded)
+}
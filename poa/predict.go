@@ -0,0 +1,61 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package poa
+
+import (
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/thor"
+)
+
+// Slot is a time slot for producing a block, with the proposer expected to produce it.
+type Slot struct {
+	Time     uint64
+	Proposer thor.Address
+}
+
+// Predict returns the expected proposers of up to `slots` slots after the parent block, following
+// the shuffling of SchedulerV2. Each slot is assumed to be filled by its expected proposer, so the
+// proposer of a slot is the first one shuffled upon the block of the previous slot, and the status
+// of proposers stays unchanged. Inactive proposers are assumed to stay offline.
+// The prediction stops at the epoch whose seed block is beyond the parent block, as the seed is unknown.
+func Predict(seeder *Seeder, parent *block.Header, proposers []Proposer, slots int) ([]Slot, error) {
+	var active []Proposer
+	for _, p := range proposers {
+		if p.Active {
+			active = append(active, p)
+		}
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+
+	var (
+		chain     = seeder.repo.NewChain(parent.ID())
+		predicted = make([]Slot, 0, slots)
+		epoch     uint32
+		seed      []byte
+	)
+	for i := 0; i < slots; i++ {
+		parentNum := parent.Number() + uint32(i)
+		// refresh the seed at epoch boundaries
+		if e := (parentNum + 1) / epochInterval; i == 0 || e != epoch {
+			if e > 1 && (e-1)*epochInterval > parent.Number() {
+				break
+			}
+			var err error
+			if _, seed, err = seeder.EpochSeed(chain, e); err != nil {
+				return nil, err
+			}
+			epoch = e
+		}
+
+		predicted = append(predicted, Slot{
+			Time:     parent.Timestamp() + thor.BlockInterval*uint64(i+1),
+			Proposer: shuffle(active, parentNum, seed)[0],
+		})
+	}
+	return predicted, nil
+}
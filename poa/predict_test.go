@@ -0,0 +1,98 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package poa
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/vrf"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredict(t *testing.T) {
+	mockEpochInterval(3)
+	defer mockEpochInterval(thor.SeederInterval)
+
+	keys := make(map[thor.Address]*ecdsa.PrivateKey)
+	var proposers []Proposer
+	for i := 0; i < 5; i++ {
+		key, _ := crypto.GenerateKey()
+		addr := thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+		keys[addr] = key
+		proposers = append(proposers, Proposer{addr, true})
+	}
+	// offline, never expected
+	proposers = append(proposers, Proposer{thor.BytesToAddress([]byte("inactive")), false})
+
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(muxdb.NewMem(), genesis)
+	assert.Nil(t, err)
+	seeder := NewSeeder(repo, nil)
+
+	// builds the chain by the predicted proposers, each of them must be scheduled upon the actual parent
+	follow := func(parent *block.Header, predicted []Slot) *block.Header {
+		for _, slot := range predicted {
+			seed, err := seeder.Generate(parent.ID())
+			assert.Nil(t, err)
+			sched, err := NewSchedulerV2(slot.Proposer, proposers, parent.Number(), parent.Timestamp(), seed)
+			assert.Nil(t, err)
+			assert.True(t, sched.IsTheTime(slot.Time), "block %v", parent.Number()+1)
+
+			alpha, err := parent.Beta()
+			assert.Nil(t, err)
+			if len(alpha) == 0 {
+				alpha = parent.StateRoot().Bytes()
+			}
+			blk := new(block.Builder).
+				ParentID(parent.ID()).
+				Timestamp(slot.Time).
+				TotalScore(parent.TotalScore() + 5).
+				GasLimit(parent.GasLimit()).
+				ReceiptsRoot(tx.Receipts(nil).RootHash()).
+				Alpha(alpha).
+				Build()
+			key := keys[slot.Proposer]
+			ec, err := crypto.Sign(blk.Header().SigningHash().Bytes(), key)
+			assert.Nil(t, err)
+			_, proof, err := vrf.Prove(key, alpha)
+			assert.Nil(t, err)
+			sig, err := block.NewComplexSignature(ec, proof)
+			assert.Nil(t, err)
+			blk = blk.WithSignature(sig)
+
+			assert.Nil(t, repo.AddBlock(blk, nil, 0))
+			parent = blk.Header()
+		}
+		return parent
+	}
+
+	// the seed of epoch 2 is provided by block 3, which is beyond the genesis
+	predicted, err := Predict(seeder, genesis.Header(), proposers, 10)
+	assert.Nil(t, err)
+	assert.Len(t, predicted, 5)
+	head := follow(genesis.Header(), predicted[:3])
+
+	// crosses the boundary of epoch 1 and 2, until block 6 providing the seed of epoch 3
+	predicted, err = Predict(seeder, head, proposers, 10)
+	assert.Nil(t, err)
+	assert.Len(t, predicted, 5)
+	follow(head, predicted)
+
+	predicted, err = Predict(seeder, head, []Proposer{{thor.BytesToAddress([]byte("inactive")), false}}, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, predicted)
+}
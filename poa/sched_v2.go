@@ -36,13 +36,8 @@ func NewSchedulerV2(
 	var (
 		listed   = false
 		proposer Proposer
-		list     []struct {
-			addr thor.Address
-			hash thor.Bytes32
-		}
+		list     []Proposer
 	)
-	var num [4]byte
-	binary.BigEndian.PutUint32(num[:], parentBlockNumber)
 
 	for _, p := range proposers {
 		if p.Address == addr {
@@ -50,13 +45,7 @@ func NewSchedulerV2(
 			listed = true
 		}
 		if p.Active || p.Address == addr {
-			list = append(list, struct {
-				addr thor.Address
-				hash thor.Bytes32
-			}{
-				p.Address,
-				thor.Blake2b(seed, num[:], p.Address.Bytes()),
-			})
+			list = append(list, p)
 		}
 	}
 
@@ -64,14 +53,7 @@ func NewSchedulerV2(
 		return nil, errors.New("unauthorized block proposer")
 	}
 
-	sort.Slice(list, func(i, j int) bool {
-		return bytes.Compare(list[i].hash.Bytes(), list[j].hash.Bytes()) < 0
-	})
-
-	shuffled := make([]thor.Address, 0, len(list))
-	for _, t := range list {
-		shuffled = append(shuffled, t.addr)
-	}
+	shuffled := shuffle(list, parentBlockNumber, seed)
 
 	return &SchedulerV2{
 		proposer,
@@ -147,3 +129,33 @@ func (s *SchedulerV2) Updates(newBlockTime uint64) (updates []Proposer, score ui
 	}
 	return
 }
+
+// shuffle sorts proposers by the hash of seed, parent block number and address.
+func shuffle(proposers []Proposer, parentBlockNumber uint32, seed []byte) []thor.Address {
+	var num [4]byte
+	binary.BigEndian.PutUint32(num[:], parentBlockNumber)
+
+	list := make([]struct {
+		addr thor.Address
+		hash thor.Bytes32
+	}, 0, len(proposers))
+	for _, p := range proposers {
+		list = append(list, struct {
+			addr thor.Address
+			hash thor.Bytes32
+		}{
+			p.Address,
+			thor.Blake2b(seed, num[:], p.Address.Bytes()),
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].hash.Bytes(), list[j].hash.Bytes()) < 0
+	})
+
+	shuffled := make([]thor.Address, 0, len(list))
+	for _, t := range list {
+		shuffled = append(shuffled, t.addr)
+	}
+	return shuffled
+}
@@ -8,62 +8,103 @@ package poa
 import (
 	"github.com/ashkanabbasii/thor/block"
 	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/kv"
+	"github.com/ashkanabbasii/thor/muxdb"
 	"github.com/ashkanabbasii/thor/thor"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	seedStoreName = "poa.seed"
+	seedCacheSize = 32
 )
 
 var epochInterval uint32 = thor.SeederInterval
 
 // mockEpochInterval mocks the epoch interval。
 // TEST ONLY
-//
-//nolint:unused
 func mockEpochInterval(interval uint32) {
 	epochInterval = interval
 }
 
-// Seeder generates seed for poa scheduler.
+// Seeder generates seed for poa scheduler. Seeds are persisted by the id of the block providing
+// them, with a bounded cache in front. It's safe for concurrent use.
 type Seeder struct {
 	repo  *chain.Repository
-	cache map[thor.Bytes32][]byte
+	store kv.Store
+	cache *lru.Cache
 }
 
-// NewSeeder creates a seeder
-func NewSeeder(repo *chain.Repository) *Seeder {
-	return &Seeder{
-		repo,
-		make(map[thor.Bytes32][]byte),
+// NewSeeder creates a seeder. The db is optional, seeds are only cached in memory if it's nil.
+func NewSeeder(repo *chain.Repository, mainDB *muxdb.MuxDB) *Seeder {
+	seeder := &Seeder{repo: repo}
+	if mainDB != nil {
+		seeder.store = mainDB.NewStore(seedStoreName)
 	}
+	seeder.cache, _ = lru.New(seedCacheSize)
+	return seeder
 }
 
 // Generate creates a seed for the given parent block's header.
 func (seeder *Seeder) Generate(parentID thor.Bytes32) (seed []byte, err error) {
 	blockNum := block.Number(parentID) + 1
 
-	epoch := blockNum / epochInterval
+	_, seed, err = seeder.EpochSeed(seeder.repo.NewChain(parentID), blockNum/epochInterval)
+	return
+}
+
+// EpochSeed returns the seed of the epoch on the given chain, along with the id of the block providing it,
+// which is the first block of the previous epoch. Epochs before 2 have no seed.
+func (seeder *Seeder) EpochSeed(chain *chain.Chain, epoch uint32) (seedID thor.Bytes32, seed []byte, err error) {
 	if epoch <= 1 {
 		return
 	}
 	seedNum := (epoch - 1) * epochInterval
 
-	seedID, err := seeder.repo.NewChain(parentID).GetBlockID(seedNum)
+	seedID, err = chain.GetBlockID(seedNum)
 	if err != nil {
 		return
 	}
+	seed, err = seeder.get(seedID)
+	return
+}
 
-	if v, ok := seeder.cache[seedID]; ok {
-		return v, nil
+// get returns the seed provided by the block.
+func (seeder *Seeder) get(seedID thor.Bytes32) (seed []byte, err error) {
+	if v, ok := seeder.cache.Get(seedID); ok {
+		return v.([]byte), nil
 	}
-
 	defer func() {
 		if err == nil {
-			seeder.cache[seedID] = seed
+			seeder.cache.Add(seedID, seed)
 		}
 	}()
 
+	if seeder.store != nil {
+		data, err := seeder.store.Get(seedID[:])
+		if err == nil {
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data, nil
+		}
+		if !seeder.store.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
 	summary, err := seeder.repo.GetBlockSummary(seedID)
 	if err != nil {
 		return
 	}
+	if seed, err = summary.Header.Beta(); err != nil {
+		return
+	}
 
-	return summary.Header.Beta()
+	if seeder.store != nil {
+		if err = seeder.store.Put(seedID[:], seed); err != nil {
+			return
+		}
+	}
+	return
 }
@@ -0,0 +1,102 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package poa
+
+import (
+	"testing"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/vrf"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRepo(t *testing.T, db *muxdb.MuxDB, n int) *chain.Repository {
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		GasLimit(thor.InitialGasLimit).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+
+	key, _ := crypto.GenerateKey()
+	parent := genesis.Header()
+	for i := 0; i < n; i++ {
+		alpha, err := parent.Beta()
+		assert.Nil(t, err)
+		if len(alpha) == 0 {
+			alpha = parent.StateRoot().Bytes()
+		}
+		blk := new(block.Builder).
+			ParentID(parent.ID()).
+			Timestamp(parent.Timestamp() + thor.BlockInterval).
+			TotalScore(parent.TotalScore() + 1).
+			GasLimit(parent.GasLimit()).
+			ReceiptsRoot(tx.Receipts(nil).RootHash()).
+			Alpha(alpha).
+			Build()
+		ec, err := crypto.Sign(blk.Header().SigningHash().Bytes(), key)
+		assert.Nil(t, err)
+		_, proof, err := vrf.Prove(key, alpha)
+		assert.Nil(t, err)
+		sig, err := block.NewComplexSignature(ec, proof)
+		assert.Nil(t, err)
+		blk = blk.WithSignature(sig)
+
+		assert.Nil(t, repo.AddBlock(blk, nil, 0))
+		assert.Nil(t, repo.SetBestBlockID(blk.Header().ID()))
+		parent = blk.Header()
+	}
+	return repo
+}
+
+func TestSeeder(t *testing.T) {
+	mockEpochInterval(3)
+	defer mockEpochInterval(thor.SeederInterval)
+
+	db := muxdb.NewMem()
+	repo := newTestRepo(t, db, 9)
+	best := repo.NewBestChain()
+	seeder := NewSeeder(repo, db)
+
+	for _, epoch := range []uint32{0, 1} {
+		seedID, seed, err := seeder.EpochSeed(best, epoch)
+		assert.Nil(t, err)
+		assert.True(t, seedID.IsZero())
+		assert.Nil(t, seed)
+	}
+
+	seedID, seed, err := seeder.EpochSeed(best, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(6), block.Number(seedID))
+	header, err := best.GetBlockHeader(6)
+	assert.Nil(t, err)
+	beta, err := header.Beta()
+	assert.Nil(t, err)
+	assert.Equal(t, beta, seed)
+
+	// blocks 9, 10 and 11 belong to epoch 3
+	generated, err := seeder.Generate(best.HeadID())
+	assert.Nil(t, err)
+	assert.Equal(t, seed, generated)
+
+	// persisted
+	stored, err := db.NewStore(seedStoreName).Get(seedID[:])
+	assert.Nil(t, err)
+	assert.Equal(t, seed, stored)
+	seed, err = NewSeeder(repo, db).get(seedID)
+	assert.Nil(t, err)
+	assert.Equal(t, beta, seed)
+
+	// seed block not reached
+	_, _, err = seeder.EpochSeed(best, 5)
+	assert.True(t, repo.IsNotFound(err))
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package runtime_test
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/runtime"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAccount struct {
+	key  *ecdsa.PrivateKey
+	addr thor.Address
+}
+
+func newAccount() testAccount {
+	key, _ := crypto.GenerateKey()
+	return testAccount{key, thor.Address(crypto.PubkeyToAddress(key.PublicKey))}
+}
+
+func newTestState() *state.State {
+	st := state.New(muxdb.NewMem(), thor.Bytes32{}, 0, 0, 0)
+	builtin.Params.Native(st).Set(thor.KeyBaseGasPrice, thor.InitialBaseGasPrice)
+	return st
+}
+
+func newTx(to thor.Address, gas uint64, delegated bool) *tx.Transaction {
+	var feat tx.Features
+	feat.SetDelegated(delegated)
+	return new(tx.Builder).
+		ChainTag(1).
+		Clause(tx.NewClause(&to)).
+		Gas(gas).
+		Expiration(32).
+		Features(feat).
+		Build()
+}
+
+func TestResolveTransaction(t *testing.T) {
+	origin := newAccount()
+	to := thor.BytesToAddress([]byte("to"))
+
+	resolved, err := runtime.ResolveTransaction(tx.MustSign(newTx(to, 21000, false), origin.key))
+	assert.Nil(t, err)
+	assert.Equal(t, origin.addr, resolved.Origin)
+	assert.Nil(t, resolved.Delegator)
+	assert.Equal(t, &to, resolved.CommonTo())
+
+	_, err = runtime.ResolveTransaction(tx.MustSign(newTx(to, 1000, false), origin.key))
+	assert.NotNil(t, err, "intrinsic gas exceeds provided gas")
+}
+
+func TestBuyGas(t *testing.T) {
+	var (
+		origin    = newAccount()
+		delegator = newAccount()
+		sponsor   = newAccount()
+		to        = thor.BytesToAddress([]byte("to"))
+		gas       = uint64(21000)
+		blockTime = uint64(1000)
+		prepaid   = new(big.Int).Mul(new(big.Int).SetUint64(gas), thor.InitialBaseGasPrice)
+	)
+
+	buyGas := func(st *state.State, trx *tx.Transaction) (thor.Address, func(uint64) error, error) {
+		resolved, err := runtime.ResolveTransaction(trx)
+		if err != nil {
+			return thor.Address{}, nil, err
+		}
+		_, _, payer, returnGas, err := resolved.BuyGas(st, blockTime)
+		return payer, returnGas, err
+	}
+	energyOf := func(st *state.State, addr thor.Address) *big.Int {
+		e, _ := st.GetEnergy(addr, blockTime)
+		return e
+	}
+
+	// insufficient energy
+	st := newTestState()
+	_, _, err := buyGas(st, tx.MustSign(newTx(to, gas, false), origin.key))
+	assert.NotNil(t, err)
+
+	// origin pays
+	st.SetEnergy(origin.addr, prepaid, blockTime)
+	payer, returnGas, err := buyGas(st, tx.MustSign(newTx(to, gas, false), origin.key))
+	assert.Nil(t, err)
+	assert.Equal(t, origin.addr, payer)
+	assert.Equal(t, 0, energyOf(st, origin.addr).Sign())
+	assert.Nil(t, returnGas(gas))
+	assert.Equal(t, prepaid, energyOf(st, origin.addr))
+
+	// delegator pays, and never falls back to origin
+	st = newTestState()
+	st.SetEnergy(origin.addr, prepaid, blockTime)
+	delegated := tx.MustSignDelegated(newTx(to, gas, true), origin.key, delegator.key)
+	_, _, err = buyGas(st, delegated)
+	assert.NotNil(t, err)
+
+	st.SetEnergy(delegator.addr, prepaid, blockTime)
+	payer, _, err = buyGas(st, delegated)
+	assert.Nil(t, err)
+	assert.Equal(t, delegator.addr, payer)
+	assert.Equal(t, prepaid, energyOf(st, origin.addr))
+
+	// contract pays for its user
+	st = newTestState()
+	binding := builtin.Prototype.Native(st).Bind(to)
+	binding.SetCreditPlan(new(big.Int).Mul(prepaid, big.NewInt(2)), big.NewInt(0))
+	binding.AddUser(origin.addr, blockTime)
+	st.SetEnergy(to, prepaid, blockTime)
+
+	payer, returnGas, err = buyGas(st, tx.MustSign(newTx(to, gas, false), origin.key))
+	assert.Nil(t, err)
+	assert.Equal(t, to, payer)
+	assert.Nil(t, returnGas(0))
+	credit, _ := binding.UserCredit(origin.addr, blockTime)
+	assert.Equal(t, prepaid, credit)
+
+	// current sponsor pays in preference to the contract
+	binding.Sponsor(sponsor.addr, true)
+	binding.SelectSponsor(sponsor.addr)
+	st.SetEnergy(sponsor.addr, prepaid, blockTime)
+
+	payer, returnGas, err = buyGas(st, tx.MustSign(newTx(to, gas, false), origin.key))
+	assert.Nil(t, err)
+	assert.Equal(t, sponsor.addr, payer)
+	assert.Nil(t, returnGas(0))
+	credit, _ = binding.UserCredit(origin.addr, blockTime)
+	assert.Equal(t, 0, credit.Sign())
+
+	// credit exhausted, sponsor is not charged and the broke origin fails
+	st.SetEnergy(sponsor.addr, prepaid, blockTime)
+	_, _, err = buyGas(st, tx.MustSign(newTx(to, gas, false), origin.key))
+	assert.NotNil(t, err)
+	assert.Equal(t, prepaid, energyOf(st, sponsor.addr))
+
+	// credit exhausted, origin pays for itself
+	st.SetEnergy(origin.addr, prepaid, blockTime)
+	payer, _, err = buyGas(st, tx.MustSign(newTx(to, gas, false), origin.key))
+	assert.Nil(t, err)
+	assert.Equal(t, origin.addr, payer)
+	assert.Equal(t, prepaid, energyOf(st, sponsor.addr))
+}
+
+func TestBuyGasWithZeroBaseGasPrice(t *testing.T) {
+	origin := newAccount()
+	to := thor.BytesToAddress([]byte("to"))
+
+	// a governance-set zero is honoured
+	st := newTestState()
+	builtin.Params.Native(st).Set(thor.KeyBaseGasPrice, big.NewInt(0))
+
+	resolved, err := runtime.ResolveTransaction(tx.MustSign(newTx(to, 21000, false), origin.key))
+	assert.Nil(t, err)
+	// nothing to prepay, even without energy
+	baseGasPrice, gasPrice, _, _, err := resolved.BuyGas(st, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, baseGasPrice.Sign())
+	assert.Equal(t, 0, gasPrice.Sign())
+}
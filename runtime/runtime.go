@@ -0,0 +1,374 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package runtime
+
+import (
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/runtime/statedb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	Tx "github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/xenv"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/pkg/errors"
+)
+
+var bigE18 = big.NewInt(1e18)
+
+// Output output of clause execution.
+type Output struct {
+	Data            []byte
+	Events          Tx.Events
+	Transfers       Tx.Transfers
+	LeftOverGas     uint64
+	RefundGas       uint64
+	VMErr           error         // VMErr identify the execution result of the contract function, not evm function's err.
+	ContractAddress *thor.Address // if create a new contract, or is nil.
+}
+
+// TransactionExecutor to execute txs.
+type TransactionExecutor struct {
+	HasNextClause func() bool
+	PrepareNext   func() (exec func() (gasUsed uint64, output *Output, err error), interrupt func(), err error)
+	Finalize      func() (*Tx.Receipt, error)
+}
+
+// Runtime bases on EVM providing smart contract execution environment.
+type Runtime struct {
+	vmConfig    vm.Config
+	chain       *chain.Chain
+	state       *state.State
+	ctx         *xenv.BlockContext
+	chainConfig *params.ChainConfig
+	forkConfig  thor.ForkConfig
+}
+
+// New create a Runtime object.
+func New(
+	chain *chain.Chain,
+	state *state.State,
+	ctx *xenv.BlockContext,
+	forkConfig thor.ForkConfig,
+) *Runtime {
+	bigNum := func(n uint32) *big.Int { return new(big.Int).SetUint64(uint64(n)) }
+	chainConfig := &params.ChainConfig{
+		ChainID:             new(big.Int),
+		HomesteadBlock:      big.NewInt(0),
+		EIP150Block:         big.NewInt(0),
+		EIP155Block:         big.NewInt(0),
+		EIP158Block:         big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: bigNum(forkConfig.ETH_CONST),
+		PetersburgBlock:     bigNum(forkConfig.ETH_CONST),
+		IstanbulBlock:       bigNum(forkConfig.ETH_IST),
+	}
+	// chain is absent when building genesis
+	if chain != nil {
+		chainConfig.ChainID.SetBytes(chain.GenesisID().Bytes())
+	}
+
+	return &Runtime{
+		chain:       chain,
+		state:       state,
+		ctx:         ctx,
+		chainConfig: chainConfig,
+		forkConfig:  forkConfig,
+	}
+}
+
+// Chain returns the chain the runtime based on.
+func (rt *Runtime) Chain() *chain.Chain { return rt.chain }
+
+// State returns state.
+func (rt *Runtime) State() *state.State { return rt.state }
+
+// Context returns block context.
+func (rt *Runtime) Context() *xenv.BlockContext { return rt.ctx }
+
+// SetVMConfig config VM.
+// Returns this runtime.
+func (rt *Runtime) SetVMConfig(config vm.Config) *Runtime {
+	rt.vmConfig = config
+	return rt
+}
+
+func (rt *Runtime) newEVM(stateDB *statedb.StateDB, txCtx *xenv.TransactionContext) (*vm.EVM, func() error) {
+	var lastErr error
+	evm := vm.NewEVM(vm.BlockContext{
+		CanTransfer: func(db vm.StateDB, addr common.Address, amount *uint256.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db vm.StateDB, sender, recipient common.Address, amount *uint256.Int) {
+			if amount.IsZero() {
+				return
+			}
+			// touch energy balance when token balance changed
+			// SHOULD be performed before transfer
+			senderEnergy, err := rt.state.GetEnergy(thor.Address(sender), rt.ctx.Time)
+			if err != nil {
+				lastErr = err
+				return
+			}
+			recipientEnergy, err := rt.state.GetEnergy(thor.Address(recipient), rt.ctx.Time)
+			if err != nil {
+				lastErr = err
+				return
+			}
+			if err := rt.state.SetEnergy(thor.Address(sender), senderEnergy, rt.ctx.Time); err != nil {
+				lastErr = err
+				return
+			}
+			if err := rt.state.SetEnergy(thor.Address(recipient), recipientEnergy, rt.ctx.Time); err != nil {
+				lastErr = err
+				return
+			}
+
+			db.SubBalance(sender, amount, 0)
+			db.AddBalance(recipient, amount, 0)
+
+			stateDB.AddTransfer(sender, recipient, amount.ToBig())
+		},
+		GetHash: func(num uint64) common.Hash {
+			id, err := rt.chain.GetBlockID(uint32(num))
+			if err != nil {
+				lastErr = err
+				return common.Hash{}
+			}
+			return common.Hash(id)
+		},
+		Coinbase:    common.Address(rt.ctx.Beneficiary),
+		GasLimit:    rt.ctx.GasLimit,
+		BlockNumber: new(big.Int).SetUint64(uint64(rt.ctx.Number)),
+		Time:        rt.ctx.Time,
+		Difficulty:  new(big.Int).SetUint64(rt.ctx.TotalScore),
+	}, vm.TxContext{
+		Origin:   common.Address(txCtx.Origin),
+		GasPrice: txCtx.GasPrice,
+	}, stateDB, rt.chainConfig, rt.vmConfig)
+
+	return evm, func() error {
+		if lastErr != nil {
+			return lastErr
+		}
+		return stateDB.Err()
+	}
+}
+
+// PrepareClause prepare to execute clause.
+// It allows to interrupt execution.
+func (rt *Runtime) PrepareClause(
+	clause *Tx.Clause,
+	clauseIndex uint32,
+	gas uint64,
+	txCtx *xenv.TransactionContext,
+) (exec func() (output *Output, interrupted bool, err error), interrupt func()) {
+	var (
+		stateDB        = statedb.New(rt.state, txCtx.ID, clauseIndex)
+		evm, checkErr  = rt.newEVM(stateDB, txCtx)
+		interruptFlag  uint32
+		value, _       = uint256.FromBig(clause.Value())
+		caller         = vm.AccountRef(common.Address(txCtx.Origin))
+		data           []byte
+		leftOverGas    uint64
+		vmErr          error
+		contractAddr   *thor.Address
+		checkpointBase = rt.state.NewCheckpoint()
+	)
+
+	if rt.vmConfig.Tracer != nil {
+		stateDB.SetLogHook(rt.vmConfig.Tracer.OnLog)
+	}
+
+	exec = func() (*Output, bool, error) {
+		if clause.To() == nil {
+			var caddr common.Address
+			stateDB.InitNonce(common.Address(txCtx.Origin))
+			data, caddr, leftOverGas, vmErr = evm.Create(caller, clause.Data(), gas, value)
+			contractAddr = (*thor.Address)(&caddr)
+		} else {
+			data, leftOverGas, vmErr = evm.Call(caller, common.Address(*clause.To()), clause.Data(), gas, value)
+		}
+
+		interrupted := atomic.LoadUint32(&interruptFlag) != 0
+		if err := checkErr(); err != nil {
+			rt.state.RevertTo(checkpointBase)
+			return nil, interrupted, err
+		}
+
+		output := &Output{
+			Data:            data,
+			LeftOverGas:     leftOverGas,
+			RefundGas:       stateDB.GetRefund(),
+			VMErr:           vmErr,
+			ContractAddress: contractAddr,
+		}
+		if vmErr == nil {
+			// self-destructed accounts are removed after the clause
+			stateDB.ForEachSelfDestructed(func(addr thor.Address) bool {
+				rt.state.Delete(addr)
+				return true
+			})
+			output.Events, output.Transfers = stateDB.GetLogs()
+		}
+		return output, interrupted, nil
+	}
+
+	interrupt = func() {
+		atomic.StoreUint32(&interruptFlag, 1)
+		evm.Cancel()
+	}
+	return
+}
+
+// ExecuteTransaction executes a transaction.
+// If some clause failed, receipt.Outputs will be nil and vmOutputs may shorter than clause count.
+func (rt *Runtime) ExecuteTransaction(tx *Tx.Transaction) (receipt *Tx.Receipt, err error) {
+	executor, err := rt.PrepareTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	for executor.HasNextClause() {
+		exec, _, err := executor.PrepareNext()
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := exec(); err != nil {
+			return nil, err
+		}
+	}
+	return executor.Finalize()
+}
+
+// PrepareTransaction prepare to execute tx.
+func (rt *Runtime) PrepareTransaction(tx *Tx.Transaction) (*TransactionExecutor, error) {
+	if err := tx.TestFeatures(rt.supportedFeatures()); err != nil {
+		return nil, err
+	}
+
+	resolvedTx, err := ResolveTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	baseGasPrice, gasPrice, payer, returnGas, err := resolvedTx.BuyGas(rt.state, rt.ctx.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	txCtx, err := resolvedTx.ToContext(gasPrice, payer, rt.ctx.Number, rt.chain.GetBlockID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ResolveTransaction has checked that tx.Gas() >= IntrinsicGas
+	leftOverGas := tx.Gas() - resolvedTx.IntrinsicGas
+	// checkpoint to be reverted when clause failure.
+	checkpoint := rt.state.NewCheckpoint()
+
+	txOutputs := make([]*Tx.Output, 0, len(resolvedTx.Clauses))
+	reverted := false
+	finalized := false
+
+	hasNext := func() bool {
+		return !reverted && len(txOutputs) < len(resolvedTx.Clauses)
+	}
+
+	return &TransactionExecutor{
+		HasNextClause: hasNext,
+		PrepareNext: func() (exec func() (uint64, *Output, error), interrupt func(), err error) {
+			if !hasNext() {
+				return nil, nil, errors.New("no more clause")
+			}
+			nextClauseIndex := uint32(len(txOutputs))
+			execFunc, interrupt := rt.PrepareClause(resolvedTx.Clauses[nextClauseIndex], nextClauseIndex, leftOverGas, txCtx)
+
+			exec = func() (gasUsed uint64, output *Output, err error) {
+				output, _, err = execFunc()
+				if err != nil {
+					return 0, nil, err
+				}
+				gasUsed = leftOverGas - output.LeftOverGas
+				leftOverGas = output.LeftOverGas
+
+				// Apply refund counter, capped to half of the used gas.
+				refund := gasUsed / 2
+				if refund > output.RefundGas {
+					refund = output.RefundGas
+				}
+
+				// won't overflow
+				leftOverGas += refund
+
+				if output.VMErr != nil {
+					// vm exception here
+					// revert all executed clauses
+					rt.state.RevertTo(checkpoint)
+					reverted = true
+					txOutputs = nil
+					return
+				}
+				txOutputs = append(txOutputs, &Tx.Output{Events: output.Events, Transfers: output.Transfers})
+				return
+			}
+
+			return exec, interrupt, nil
+		},
+		Finalize: func() (*Tx.Receipt, error) {
+			if hasNext() {
+				return nil, errors.New("not all clauses processed")
+			}
+			if finalized {
+				return nil, errors.New("already finalized")
+			}
+			finalized = true
+
+			receipt := &Tx.Receipt{
+				Reverted: reverted,
+				Outputs:  txOutputs,
+				GasUsed:  tx.Gas() - leftOverGas,
+				GasPayer: payer,
+			}
+			receipt.Paid = new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), gasPrice)
+
+			if err := returnGas(leftOverGas); err != nil {
+				return nil, err
+			}
+
+			// reward
+			rewardRatio, err := builtin.Params.Native(rt.state).Get(thor.KeyRewardRatio)
+			if err != nil {
+				return nil, err
+			}
+			overallGasPrice := tx.OverallGasPrice(baseGasPrice, txCtx.ProvedWork)
+
+			reward := new(big.Int).SetUint64(receipt.GasUsed)
+			reward.Mul(reward, overallGasPrice)
+			reward.Mul(reward, rewardRatio)
+			reward.Div(reward, bigE18)
+			if err := builtin.Energy.Native(rt.state, rt.ctx.Time).Add(rt.ctx.Beneficiary, reward); err != nil {
+				return nil, err
+			}
+
+			receipt.Reward = reward
+			return receipt, nil
+		},
+	}, nil
+}
+
+// supportedFeatures returns tx features supported at the block.
+func (rt *Runtime) supportedFeatures() (features Tx.Features) {
+	if rt.ctx.Number >= rt.forkConfig.VIP191 {
+		features.SetDelegated(true)
+	}
+	return
+}
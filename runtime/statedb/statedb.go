@@ -0,0 +1,447 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package statedb
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ashkanabbasii/thor/stackedmap"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/holiman/uint256"
+)
+
+var codeHashOfEmpty = common.Hash(thor.Keccak256(nil))
+
+// StateDB implements evm.StateDB, only adapt to evm.
+//
+// It is created per clause. Accounts in thor have no nonce, so the nonce of
+// contract accounts starts from a value derived from the tx id and the clause
+// index, which keeps addresses of created contracts unique.
+type StateDB struct {
+	state     *state.State
+	repo      *stackedmap.StackedMap
+	nonceBase uint64
+	onLog     tracing.LogHook
+	err       error
+}
+
+type (
+	suicideFlagKey common.Address
+	createdFlagKey common.Address
+	nonceKey       common.Address
+	refundKey      struct{}
+	eventKey       struct{}
+	transferKey    struct{}
+	stateRevKey    struct{}
+	originalKey    struct {
+		addr common.Address
+		key  common.Hash
+	}
+	transientKey struct {
+		addr common.Address
+		key  common.Hash
+	}
+	accessAddrKey common.Address
+	accessSlotKey struct {
+		addr common.Address
+		slot common.Hash
+	}
+)
+
+// New create a statedb object.
+func New(state *state.State, txID thor.Bytes32, clauseIndex uint32) *StateDB {
+	getter := func(k interface{}) (interface{}, bool, error) {
+		switch k.(type) {
+		case suicideFlagKey, createdFlagKey:
+			return false, true, nil
+		case refundKey:
+			return uint64(0), true, nil
+		case transientKey:
+			return common.Hash{}, true, nil
+		case accessAddrKey, accessSlotKey:
+			return false, true, nil
+		}
+		return nil, false, nil
+	}
+
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], clauseIndex)
+	h := thor.Blake2b(txID[:], b[:])
+
+	return &StateDB{
+		state:     state,
+		repo:      stackedmap.New(getter),
+		nonceBase: binary.BigEndian.Uint64(h[:]) >> 1,
+	}
+}
+
+// Err returns the first error occurred when accessing the underlying state.
+func (s *StateDB) Err() error {
+	return s.err
+}
+
+func (s *StateDB) setError(err error) {
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+}
+
+// GetRefund returns total refund during VM life-cycle.
+func (s *StateDB) GetRefund() uint64 {
+	v, _, _ := s.repo.Get(refundKey{})
+	return v.(uint64)
+}
+
+// GetLogs returns collected event and transfer logs.
+func (s *StateDB) GetLogs() (events tx.Events, transfers tx.Transfers) {
+	s.repo.Journal(func(k, v interface{}) bool {
+		switch k.(type) {
+		case eventKey:
+			events = append(events, v.(*tx.Event))
+		case transferKey:
+			transfers = append(transfers, v.(*tx.Transfer))
+		}
+		return true
+	})
+	return
+}
+
+// ForEachSelfDestructed iterates over self-destructed accounts.
+func (s *StateDB) ForEachSelfDestructed(cb func(thor.Address) bool) {
+	// the journal may contain duplicated flags
+	seen := make(map[common.Address]bool)
+	s.repo.Journal(func(k, v interface{}) bool {
+		if key, ok := k.(suicideFlagKey); ok {
+			addr := common.Address(key)
+			if !seen[addr] && s.HasSelfDestructed(addr) {
+				seen[addr] = true
+				return cb(thor.Address(addr))
+			}
+		}
+		return true
+	})
+}
+
+// AddTransfer records a token transfer.
+func (s *StateDB) AddTransfer(sender, recipient common.Address, amount *big.Int) {
+	s.repo.Put(transferKey{}, &tx.Transfer{
+		Sender:    thor.Address(sender),
+		Recipient: thor.Address(recipient),
+		Amount:    amount,
+	})
+}
+
+// CreateAccount stub.
+func (s *StateDB) CreateAccount(addr common.Address) {}
+
+// CreateContract marks the address as a contract created in this clause.
+func (s *StateDB) CreateContract(addr common.Address) {
+	s.repo.Put(createdFlagKey(addr), true)
+}
+
+// GetBalance returns balance for the given address.
+func (s *StateDB) GetBalance(addr common.Address) *uint256.Int {
+	bal, err := s.state.GetBalance(thor.Address(addr))
+	if err != nil {
+		s.setError(err)
+		return new(uint256.Int)
+	}
+	v, _ := uint256.FromBig(bal)
+	return v
+}
+
+// SubBalance subtracts amount from the account balance.
+func (s *StateDB) SubBalance(addr common.Address, amount *uint256.Int, _ tracing.BalanceChangeReason) uint256.Int {
+	prev := s.GetBalance(addr)
+	if amount.IsZero() {
+		return *prev
+	}
+	s.setError(s.state.SetBalance(thor.Address(addr), new(uint256.Int).Sub(prev, amount).ToBig()))
+	return *prev
+}
+
+// AddBalance adds amount to the account balance.
+func (s *StateDB) AddBalance(addr common.Address, amount *uint256.Int, _ tracing.BalanceChangeReason) uint256.Int {
+	prev := s.GetBalance(addr)
+	if amount.IsZero() {
+		return *prev
+	}
+	s.setError(s.state.SetBalance(thor.Address(addr), new(uint256.Int).Add(prev, amount).ToBig()))
+	return *prev
+}
+
+// GetNonce returns the nonce of the account.
+// Only contract accounts and the address given nonce explicitly have non-zero nonce.
+func (s *StateDB) GetNonce(addr common.Address) uint64 {
+	if v, _, _ := s.repo.Get(nonceKey(addr)); v != nil {
+		return v.(uint64)
+	}
+	if s.GetCodeSize(addr) > 0 {
+		return s.nonceBase
+	}
+	return 0
+}
+
+// SetNonce sets the nonce of the account.
+func (s *StateDB) SetNonce(addr common.Address, nonce uint64) {
+	s.repo.Put(nonceKey(addr), nonce)
+}
+
+// InitNonce gives the account the initial nonce of this clause.
+func (s *StateDB) InitNonce(addr common.Address) {
+	s.SetNonce(addr, s.nonceBase)
+}
+
+// GetCodeHash returns code hash of the account.
+func (s *StateDB) GetCodeHash(addr common.Address) common.Hash {
+	hash, err := s.state.GetCodeHash(thor.Address(addr))
+	if err != nil {
+		s.setError(err)
+		return common.Hash{}
+	}
+	if hash.IsZero() {
+		if s.Exist(addr) {
+			return codeHashOfEmpty
+		}
+		return common.Hash{}
+	}
+	return common.Hash(hash)
+}
+
+// GetCode returns code of the account.
+func (s *StateDB) GetCode(addr common.Address) []byte {
+	code, err := s.state.GetCode(thor.Address(addr))
+	if err != nil {
+		s.setError(err)
+		return nil
+	}
+	return code
+}
+
+// GetCodeSize returns code size of the account.
+func (s *StateDB) GetCodeSize(addr common.Address) int {
+	return len(s.GetCode(addr))
+}
+
+// SetCode sets code of the account.
+func (s *StateDB) SetCode(addr common.Address, code []byte) {
+	s.setError(s.state.SetCode(thor.Address(addr), code))
+}
+
+// AddRefund adds gas to the refund counter.
+func (s *StateDB) AddRefund(gas uint64) {
+	s.repo.Put(refundKey{}, s.GetRefund()+gas)
+}
+
+// SubRefund removes gas from the refund counter.
+func (s *StateDB) SubRefund(gas uint64) {
+	refund := s.GetRefund()
+	if gas > refund {
+		panic("refund counter below zero")
+	}
+	s.repo.Put(refundKey{}, refund-gas)
+}
+
+// GetCommittedState returns the value before the first modification in this clause.
+func (s *StateDB) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	if v, _, _ := s.repo.Get(originalKey{addr, key}); v != nil {
+		return v.(common.Hash)
+	}
+	return s.GetState(addr, key)
+}
+
+// GetState returns storage value.
+func (s *StateDB) GetState(addr common.Address, key common.Hash) common.Hash {
+	v, err := s.state.GetStorage(thor.Address(addr), thor.Bytes32(key))
+	if err != nil {
+		s.setError(err)
+		return common.Hash{}
+	}
+	return common.Hash(v)
+}
+
+// SetState sets storage value.
+func (s *StateDB) SetState(addr common.Address, key common.Hash, value common.Hash) common.Hash {
+	prev := s.GetState(addr, key)
+	if v, _, _ := s.repo.Get(originalKey{addr, key}); v == nil {
+		s.repo.Put(originalKey{addr, key}, prev)
+	}
+	s.state.SetStorage(thor.Address(addr), thor.Bytes32(key), thor.Bytes32(value))
+	return prev
+}
+
+// GetStorageRoot returns the storage root of the account.
+func (s *StateDB) GetStorageRoot(addr common.Address) common.Hash {
+	root, err := s.state.GetStorageRoot(thor.Address(addr))
+	if err != nil {
+		s.setError(err)
+		return common.Hash{}
+	}
+	return common.Hash(root)
+}
+
+// GetTransientState returns transient storage value.
+func (s *StateDB) GetTransientState(addr common.Address, key common.Hash) common.Hash {
+	v, _, _ := s.repo.Get(transientKey{addr, key})
+	return v.(common.Hash)
+}
+
+// SetTransientState sets transient storage value.
+func (s *StateDB) SetTransientState(addr common.Address, key, value common.Hash) {
+	s.repo.Put(transientKey{addr, key}, value)
+}
+
+// SelfDestruct marks the account as self-destructed and clears its balance.
+func (s *StateDB) SelfDestruct(addr common.Address) uint256.Int {
+	prev := s.GetBalance(addr)
+	s.repo.Put(suicideFlagKey(addr), true)
+	if !prev.IsZero() {
+		s.setError(s.state.SetBalance(thor.Address(addr), &big.Int{}))
+	}
+	return *prev
+}
+
+// HasSelfDestructed returns whether the account is self-destructed.
+func (s *StateDB) HasSelfDestructed(addr common.Address) bool {
+	v, _, _ := s.repo.Get(suicideFlagKey(addr))
+	return v.(bool)
+}
+
+// SelfDestruct6780 self-destructs the account only if it's created in this clause.
+func (s *StateDB) SelfDestruct6780(addr common.Address) (uint256.Int, bool) {
+	if v, _, _ := s.repo.Get(createdFlagKey(addr)); v.(bool) {
+		return s.SelfDestruct(addr), true
+	}
+	return *s.GetBalance(addr), false
+}
+
+// Exist returns whether the account exists.
+func (s *StateDB) Exist(addr common.Address) bool {
+	if s.HasSelfDestructed(addr) {
+		return true
+	}
+	b, err := s.state.Exists(thor.Address(addr))
+	if err != nil {
+		s.setError(err)
+		return false
+	}
+	return b
+}
+
+// Empty returns whether the account is empty.
+func (s *StateDB) Empty(addr common.Address) bool {
+	return !s.Exist(addr)
+}
+
+// AddressInAccessList returns whether the address is in the access list.
+func (s *StateDB) AddressInAccessList(addr common.Address) bool {
+	v, _, _ := s.repo.Get(accessAddrKey(addr))
+	return v.(bool)
+}
+
+// SlotInAccessList returns whether the address and the slot are in the access list.
+func (s *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addressOk bool, slotOk bool) {
+	v, _, _ := s.repo.Get(accessSlotKey{addr, slot})
+	return s.AddressInAccessList(addr), v.(bool)
+}
+
+// AddAddressToAccessList adds the address to the access list.
+func (s *StateDB) AddAddressToAccessList(addr common.Address) {
+	s.repo.Put(accessAddrKey(addr), true)
+}
+
+// AddSlotToAccessList adds the address and the slot to the access list.
+func (s *StateDB) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	s.AddAddressToAccessList(addr)
+	s.repo.Put(accessSlotKey{addr, slot}, true)
+}
+
+// PointCache stub.
+func (s *StateDB) PointCache() *utils.PointCache { return nil }
+
+// Prepare prepares the access list.
+func (s *StateDB) Prepare(rules params.Rules, sender, coinbase common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList) {
+	if !rules.IsBerlin {
+		return
+	}
+	s.AddAddressToAccessList(sender)
+	if dest != nil {
+		s.AddAddressToAccessList(*dest)
+	}
+	for _, addr := range precompiles {
+		s.AddAddressToAccessList(addr)
+	}
+	for _, el := range txAccesses {
+		s.AddAddressToAccessList(el.Address)
+		for _, key := range el.StorageKeys {
+			s.AddSlotToAccessList(el.Address, key)
+		}
+	}
+	if rules.IsShanghai {
+		s.AddAddressToAccessList(coinbase)
+	}
+}
+
+// Snapshot makes a snapshot.
+func (s *StateDB) Snapshot() int {
+	rev := s.repo.Push()
+	s.repo.Put(stateRevKey{}, s.state.NewCheckpoint())
+	return rev
+}
+
+// RevertToSnapshot reverts to a snapshot.
+func (s *StateDB) RevertToSnapshot(rev int) {
+	if rev < 0 || rev > s.repo.Depth() {
+		panic("invalid snapshot revision")
+	}
+	revertToState := func() {
+		if stateRev, ok, _ := s.repo.Get(stateRevKey{}); ok {
+			s.state.RevertTo(stateRev.(int))
+		}
+	}
+	s.repo.PopTo(rev + 1)
+	revertToState()
+	s.repo.PopTo(rev)
+}
+
+// SetLogHook sets the hook to be called when a log is added.
+func (s *StateDB) SetLogHook(hook tracing.LogHook) {
+	s.onLog = hook
+}
+
+// AddLog adds a log.
+func (s *StateDB) AddLog(vmlog *types.Log) {
+	if s.onLog != nil {
+		s.onLog(vmlog)
+	}
+	var topics []thor.Bytes32
+	for _, t := range vmlog.Topics {
+		topics = append(topics, thor.Bytes32(t))
+	}
+	s.repo.Put(eventKey{}, &tx.Event{
+		Address: thor.Address(vmlog.Address),
+		Topics:  topics,
+		Data:    vmlog.Data,
+	})
+}
+
+// AddPreimage stub.
+func (s *StateDB) AddPreimage(common.Hash, []byte) {}
+
+// Witness stub.
+func (s *StateDB) Witness() *stateless.Witness { return nil }
+
+// Finalise stub.
+func (s *StateDB) Finalise(bool) {}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package solo
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ashkanabbasii/thor/api/accounts"
+	"github.com/ashkanabbasii/thor/api/blocks"
+	"github.com/ashkanabbasii/thor/api/debug"
+	"github.com/ashkanabbasii/thor/api/node"
+	"github.com/ashkanabbasii/thor/api/proposers"
+	"github.com/ashkanabbasii/thor/api/transactions"
+	"github.com/ashkanabbasii/thor/bft"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/co"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const defaultCallGasLimit = 50 * 1000 * 1000
+
+// bftEngine is a fake bft engine for solo, the only proposer makes the best block final.
+type bftEngine struct {
+	repo *chain.Repository
+}
+
+// NewBFTEngine creates a fake bft committer for solo.
+func NewBFTEngine(repo *chain.Repository) bft.Committer {
+	return &bftEngine{repo}
+}
+
+func (b *bftEngine) Finalized() thor.Bytes32 {
+	return b.repo.BestBlockSummary().Header.ID()
+}
+
+func (b *bftEngine) Justified() (thor.Bytes32, error) {
+	return b.repo.BestBlockSummary().Header.ID(), nil
+}
+
+// HTTPHandler returns the handler serving the REST API of the solo node.
+func (s *Solo) HTTPHandler() http.Handler {
+	callGasLimit := s.options.CallGasLimit
+	if callGasLimit == 0 {
+		callGasLimit = defaultCallGasLimit
+	}
+	committer := NewBFTEngine(s.repo)
+
+	router := mux.NewRouter()
+	accounts.New(s.repo, s.stater, callGasLimit, s.forkConfig, committer).
+		Mount(router, "/accounts")
+	blocks.New(s.repo, committer).
+		Mount(router, "/blocks")
+	transactions.New(s.repo, s.txPool).
+		Mount(router, "/transactions")
+	debug.New(s.repo, s.stater, s.forkConfig, callGasLimit, committer).
+		Mount(router, "/debug")
+	node.New(s.repo, s.forkConfig, nil).
+		Mount(router, "/node")
+	proposers.New(s.repo, s.stater, s.seeder, s.forkConfig, committer, nil).
+		Mount(router, "/proposers")
+
+	return handlers.CompressHandler(router)
+}
+
+// StartAPIServer serves the REST API on the given address.
+// It returns the URL of the server and a func to close it.
+func (s *Solo) StartAPIServer(addr string) (string, func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "listen API addr [%v]", addr)
+	}
+
+	srv := &http.Server{Handler: s.HTTPHandler(), ReadHeaderTimeout: time.Second, ReadTimeout: 5 * time.Second}
+	var goes co.Goes
+	goes.Go(func() {
+		srv.Serve(listener)
+	})
+	return "http://" + listener.Addr().String(), func() {
+		srv.Close()
+		goes.Wait()
+	}, nil
+}
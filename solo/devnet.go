@@ -0,0 +1,60 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package solo
+
+import (
+	"time"
+
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/genesis"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/txpool"
+	"github.com/pkg/errors"
+)
+
+// Devnet is an in-process solo node upon a fresh in-memory devnet,
+// the dev accounts are pre-funded.
+type Devnet struct {
+	*Solo
+	Repo   *chain.Repository
+	Stater *state.Stater
+	TxPool *txpool.TxPool
+}
+
+// NewDevnet wires genesis, repository, tx pool and packer of a solo node upon an in-memory db.
+func NewDevnet(options Options) (*Devnet, error) {
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+
+	gene := genesis.NewDevnet()
+	b0, _, _, err := gene.Build(stater)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build genesis")
+	}
+	repo, err := chain.NewRepository(db, b0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "initialize repository")
+	}
+
+	pool := txpool.New(repo, stater, txpool.Options{
+		Limit:           10000,
+		LimitPerAccount: 16,
+		MaxLifetime:     20 * time.Minute,
+	})
+
+	return &Devnet{
+		Solo:   New(repo, stater, pool, gene.ForkConfig(), options),
+		Repo:   repo,
+		Stater: stater,
+		TxPool: pool,
+	}, nil
+}
+
+// Close releases resources of the devnet.
+func (d *Devnet) Close() {
+	d.TxPool.Close()
+}
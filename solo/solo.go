@@ -0,0 +1,213 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+// Package solo runs a single-node chain for development, packing blocks with the built-in dev authority.
+package solo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/genesis"
+	"github.com/ashkanabbasii/thor/log"
+	"github.com/ashkanabbasii/thor/packer"
+	"github.com/ashkanabbasii/thor/poa"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/txpool"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/pkg/errors"
+)
+
+var logger = log.WithContext("pkg", "solo")
+
+// Options options for solo.
+type Options struct {
+	GasLimit     uint64 // fixed block gas limit, follows the parent block if 0
+	OnDemand     bool   // pack blocks only when executable txs arrive, instead of every block interval
+	CallGasLimit uint64 // gas limit of calls through the API
+}
+
+// Solo mode is the standalone client without p2p server.
+type Solo struct {
+	repo       *chain.Repository
+	stater     *state.Stater
+	txPool     *txpool.TxPool
+	seeder     *poa.Seeder
+	packer     *packer.Packer
+	forkConfig thor.ForkConfig
+	options    Options
+}
+
+// New returns Solo instance.
+func New(
+	repo *chain.Repository,
+	stater *state.Stater,
+	txPool *txpool.TxPool,
+	forkConfig thor.ForkConfig,
+	options Options,
+) *Solo {
+	// seeds are never generated by mock packing
+	seeder := poa.NewSeeder(repo, nil)
+	return &Solo{
+		repo:   repo,
+		stater: stater,
+		txPool: txPool,
+		seeder: seeder,
+		packer: packer.New(
+			repo,
+			stater,
+			seeder,
+			genesis.DevAccounts()[0].Address,
+			&genesis.DevAccounts()[0].Address,
+			forkConfig),
+		forkConfig: forkConfig,
+		options:    options,
+	}
+}
+
+// Run runs the packer for solo until the context is done.
+func (s *Solo) Run(ctx context.Context) {
+	logger.Info("prepared to pack block", "onDemand", s.options.OnDemand)
+	defer logger.Info("stopping packing service")
+
+	var scope event.SubscriptionScope
+	defer scope.Close()
+
+	txEvCh := make(chan *txpool.TxEvent, 10)
+	scope.Track(s.txPool.SubscribeTxEvent(txEvCh))
+
+	// the tx pool accepts txs only when the chain is synced, so the
+	// chain is brought up to date first
+	if best := s.repo.BestBlockSummary(); best.Header.Timestamp()+thor.BlockInterval < uint64(time.Now().Unix()) {
+		if _, err := s.packing(nil, false); err != nil {
+			logger.Error("failed to pack block", "err", err)
+		}
+	}
+	s.loop(ctx, txEvCh)
+}
+
+// loop packs blocks on tx arrivals or block intervals, according to the options.
+func (s *Solo) loop(ctx context.Context, txEvCh <-chan *txpool.TxEvent) {
+	ticker := time.NewTicker(time.Duration(thor.BlockInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case txEv := <-txEvCh:
+			if !s.options.OnDemand {
+				continue
+			}
+			// the pool leaves new txs unevaluated once the head is stale, which is usual for
+			// an idle on-demand chain, so the tx is packed along with executables regardless
+			pendingTxs := s.txPool.Executables()
+			if !containsTx(pendingTxs, txEv.Tx) {
+				pendingTxs = append(pendingTxs[:len(pendingTxs):len(pendingTxs)], txEv.Tx)
+			}
+			if _, err := s.packing(pendingTxs, true); err != nil {
+				logger.Error("failed to pack block", "err", err)
+			}
+		case <-ticker.C:
+			if s.options.OnDemand {
+				continue
+			}
+			if _, err := s.packing(s.txPool.Executables(), false); err != nil {
+				logger.Error("failed to pack block", "err", err)
+			}
+		}
+	}
+}
+
+// packing packs the pending txs into a new block upon the best block, and makes it the new best.
+// In on-demand mode, no block is packed if none of the txs adopted.
+func (s *Solo) packing(pendingTxs tx.Transactions, onDemand bool) (bool, error) {
+	best := s.repo.BestBlockSummary()
+
+	now := uint64(time.Now().Unix())
+	// blocks may be packed faster than one per second on demand
+	if now <= best.Header.Timestamp() {
+		now = best.Header.Timestamp() + 1
+	}
+
+	flow, err := s.packer.Mock(best, now, s.options.GasLimit)
+	if err != nil {
+		return false, errors.WithMessage(err, "mock packer")
+	}
+
+	var dropped, adopted tx.Transactions
+	defer func() {
+		for _, trx := range dropped {
+			s.txPool.Remove(trx.Hash(), trx.ID())
+		}
+	}()
+
+	startTime := mclock.Now()
+	for _, trx := range pendingTxs {
+		if err := flow.Adopt(trx); err != nil {
+			if packer.IsGasLimitReached(err) {
+				break
+			}
+			if packer.IsTxNotAdoptableNow(err) {
+				continue
+			}
+			logger.Debug("drop tx", "id", trx.ID(), "err", err)
+			dropped = append(dropped, trx)
+			continue
+		}
+		adopted = append(adopted, trx)
+	}
+
+	if onDemand && len(adopted) == 0 {
+		return false, nil
+	}
+
+	b, stage, receipts, err := flow.Pack(genesis.DevAccounts()[0].PrivateKey, 0, false)
+	if err != nil {
+		return false, errors.WithMessage(err, "pack")
+	}
+	execElapsed := mclock.Now() - startTime
+
+	if _, err := stage.Commit(); err != nil {
+		return false, errors.WithMessage(err, "commit state")
+	}
+	// ignore fork when solo
+	if err := s.repo.AddBlock(b, receipts, 0); err != nil {
+		return false, errors.WithMessage(err, "commit block")
+	}
+	if err := s.repo.SetBestBlockID(b.Header().ID()); err != nil {
+		return false, errors.WithMessage(err, "set best block")
+	}
+	commitElapsed := mclock.Now() - startTime - execElapsed
+
+	// packed txs are removed immediately, rather than waiting for the pool to wash them out
+	for _, trx := range adopted {
+		s.txPool.Remove(trx.Hash(), trx.ID())
+	}
+
+	logger.Info("📦 new block packed",
+		"txs", len(receipts),
+		"mgas", float64(b.Header().GasUsed())/1000/1000,
+		"et", fmt.Sprintf("%v|%v", common.PrettyDuration(execElapsed), common.PrettyDuration(commitElapsed)),
+		"id", fmt.Sprintf("[#%v…%x]", b.Header().Number(), b.Header().ID().Bytes()[28:]),
+	)
+	logger.Debug(b.String())
+	return true, nil
+}
+
+func containsTx(txs tx.Transactions, trx *tx.Transaction) bool {
+	for _, t := range txs {
+		if t.ID() == trx.ID() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package solo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashkanabbasii/thor/api/accounts"
+	"github.com/ashkanabbasii/thor/api/transactions"
+	"github.com/ashkanabbasii/thor/genesis"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ashkanabbasii/thor/txpool"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+)
+
+var recipient = thor.BytesToAddress([]byte("recipient"))
+
+func newTransferTx(d *Devnet, nonce uint64) *tx.Transaction {
+	best := d.Repo.BestBlockSummary().Header
+	return tx.MustSign(new(tx.Builder).
+		ChainTag(d.Repo.ChainTag()).
+		Clause(tx.NewClause(&recipient).WithValue(big.NewInt(100))).
+		Gas(21000).
+		BlockRef(tx.NewBlockRef(best.Number())).
+		Expiration(100).
+		Nonce(nonce).
+		Build(), genesis.DevAccounts()[1].PrivateKey)
+}
+
+func TestPacking(t *testing.T) {
+	d, err := NewDevnet(Options{})
+	assert.Nil(t, err)
+	defer d.Close()
+
+	// empty block is packed in interval mode
+	packed, err := d.packing(nil, false)
+	assert.Nil(t, err)
+	assert.True(t, packed)
+	best := d.Repo.BestBlockSummary().Header
+	assert.Equal(t, uint32(1), best.Number())
+	assert.Equal(t, genesis.DevAccounts()[0].Address, best.Beneficiary())
+
+	// but not in on-demand mode
+	packed, err = d.packing(nil, true)
+	assert.Nil(t, err)
+	assert.False(t, packed)
+
+	trx := newTransferTx(d, 1)
+	assert.Nil(t, d.TxPool.AddLocal(trx))
+	// dropped, mismatched chain tag
+	bad := tx.MustSign(new(tx.Builder).ChainTag(d.Repo.ChainTag()+1).Gas(21000).Build(), genesis.DevAccounts()[1].PrivateKey)
+
+	packed, err = d.packing(tx.Transactions{bad, trx}, true)
+	assert.Nil(t, err)
+	assert.True(t, packed)
+	blk, err := d.Repo.GetBlock(d.Repo.BestBlockSummary().Header.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), blk.Header().Number())
+	assert.True(t, blk.Header().Timestamp() > best.Timestamp())
+	assert.Equal(t, tx.Transactions{trx}, blk.Transactions())
+	// packed tx is removed from the pool
+	assert.Nil(t, d.TxPool.Get(trx.ID()))
+}
+
+func TestRunOnDemand(t *testing.T) {
+	d, err := NewDevnet(Options{OnDemand: true})
+	assert.Nil(t, err)
+	defer d.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// brought up to date on start
+	assert.Eventually(t, func() bool {
+		return d.Repo.BestBlockSummary().Header.Number() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	handler := d.HTTPHandler()
+
+	// send tx through the API
+	trx := newTransferTx(d, 1)
+	raw, err := rlp.EncodeToBytes(trx)
+	assert.Nil(t, err)
+	body, err := json.Marshal(&transactions.RawTx{Raw: hexutil.Encode(raw)})
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// packed immediately
+	assert.Eventually(t, func() bool {
+		return d.Repo.BestBlockSummary().Header.Number() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/transactions/"+trx.ID().String()+"/receipt", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var receipt transactions.Receipt
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &receipt))
+	assert.Equal(t, uint32(2), receipt.Meta.BlockNumber)
+	assert.False(t, receipt.Reverted)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/accounts/"+recipient.String(), nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var acc accounts.Account
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &acc))
+	assert.Equal(t, big.NewInt(100), (*big.Int)(&acc.Balance))
+}
+
+func TestRunOnDemandStaleHead(t *testing.T) {
+	d, err := NewDevnet(Options{OnDemand: true})
+	assert.Nil(t, err)
+	defer d.Close()
+
+	// the head is the genesis, launched long before, and is not brought up to date
+	assert.True(t, d.Repo.BestBlockSummary().Header.Timestamp()+thor.BlockInterval*6 < uint64(time.Now().Unix()))
+
+	txEvCh := make(chan *txpool.TxEvent, 10)
+	sub := d.TxPool.SubscribeTxEvent(txEvCh)
+	defer sub.Unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.loop(ctx, txEvCh)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// not evaluated by the pool upon the stale head, but still packed
+	trx := newTransferTx(d, 1)
+	assert.Nil(t, d.TxPool.AddLocal(trx))
+	assert.Empty(t, d.TxPool.Executables())
+
+	assert.Eventually(t, func() bool {
+		return d.Repo.BestBlockSummary().Header.Number() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	blk, err := d.Repo.GetBlock(d.Repo.BestBlockSummary().Header.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, tx.Transactions{trx}, blk.Transactions())
+	assert.Nil(t, d.TxPool.Get(trx.ID()))
+}
+
+func TestStartAPIServer(t *testing.T) {
+	d, err := NewDevnet(Options{})
+	assert.Nil(t, err)
+	defer d.Close()
+
+	url, closeFunc, err := d.StartAPIServer("localhost:0")
+	assert.Nil(t, err)
+	defer closeFunc()
+
+	res, err := http.Get(url + "/node/forks")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Account is the Thor consensus representation of an account.
+// RLP encoded objects are stored in main account trie.
+type Account struct {
+	Balance     *big.Int
+	Energy      *big.Int
+	BlockTime   uint64
+	Master      []byte // master address
+	CodeHash    []byte // hash of code
+	StorageRoot []byte // merkle root of the storage trie
+}
+
+// IsEmpty returns if an account is empty.
+// An empty account has zero balance and zero length code hash.
+// Energy and master are also counted.
+func (a *Account) IsEmpty() bool {
+	return a.Balance.Sign() == 0 &&
+		a.Energy.Sign() == 0 &&
+		len(a.Master) == 0 &&
+		len(a.CodeHash) == 0
+}
+
+var bigE18 = big.NewInt(1e18)
+
+// CalcEnergy calculates energy based on current block time.
+func (a *Account) CalcEnergy(blockTime uint64) *big.Int {
+	if a.BlockTime == 0 {
+		return a.Energy
+	}
+
+	if a.Balance.Sign() == 0 {
+		return a.Energy
+	}
+
+	if blockTime <= a.BlockTime {
+		return a.Energy
+	}
+
+	x := new(big.Int).SetUint64(blockTime - a.BlockTime)
+	x.Mul(x, a.Balance)
+	x.Mul(x, thor.EnergyGrowthRate)
+	x.Div(x, bigE18)
+	return new(big.Int).Add(a.Energy, x)
+}
+
+func emptyAccount() *Account {
+	a := Account{Balance: &big.Int{}, Energy: &big.Int{}}
+	return &a
+}
+
+// loadAccount load an account object by address in trie.
+// It returns empty account is no account found at the address.
+func loadAccount(trie *muxdb.Trie, addr thor.Address) (*Account, error) {
+	data, _, err := trie.Get(thor.Blake2b(addr[:]).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return emptyAccount(), nil
+	}
+	var a Account
+	if err := rlp.DecodeBytes(data, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// saveAccount save account into trie at given address.
+// If the given account is empty, the value for given address is deleted.
+func saveAccount(trie *muxdb.Trie, addr thor.Address, a *Account) error {
+	key := thor.Blake2b(addr[:])
+	if a.IsEmpty() {
+		// delete if account is empty
+		return trie.Update(key[:], nil, nil)
+	}
+
+	data, err := rlp.EncodeToBytes(a)
+	if err != nil {
+		return err
+	}
+	return trie.Update(key[:], data, nil)
+}
+
+// loadStorage load storage data for given key.
+func loadStorage(trie *muxdb.Trie, key thor.Bytes32) (rlp.RawValue, error) {
+	v, _, err := trie.Get(thor.Blake2b(key[:]).Bytes())
+	return v, err
+}
+
+// saveStorage save value for given key.
+// If the data is zero, the given key will be deleted.
+// The raw key is kept as the metadata of the leaf, as the preimage of the hashed key.
+func saveStorage(trie *muxdb.Trie, key thor.Bytes32, data rlp.RawValue) error {
+	hkey := thor.Blake2b(key[:])
+	if len(data) == 0 {
+		return trie.Update(hkey[:], nil, nil)
+	}
+	return trie.Update(hkey[:], data, key[:])
+}
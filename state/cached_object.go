@@ -0,0 +1,84 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package state
+
+import (
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// cachedObject to cache code and storage of an account.
+type cachedObject struct {
+	db   *muxdb.MuxDB
+	data Account
+
+	cache struct {
+		code        []byte
+		storageTrie *muxdb.Trie
+		storage     map[thor.Bytes32]rlp.RawValue
+	}
+}
+
+func newCachedObject(db *muxdb.MuxDB, data *Account) *cachedObject {
+	return &cachedObject{db: db, data: *data}
+}
+
+func (co *cachedObject) getOrCreateStorageTrie() *muxdb.Trie {
+	if co.cache.storageTrie != nil {
+		return co.cache.storageTrie
+	}
+
+	trie := co.db.NewTrie(StorageTrieName, thor.BytesToBytes32(co.data.StorageRoot), 0, 0)
+
+	co.cache.storageTrie = trie
+	return trie
+}
+
+// GetStorage returns storage value for given key.
+func (co *cachedObject) GetStorage(key thor.Bytes32) (rlp.RawValue, error) {
+	cache := &co.cache
+	// retrieve from storage cache
+	if cache.storage != nil {
+		if v, ok := cache.storage[key]; ok {
+			return v, nil
+		}
+	} else {
+		cache.storage = make(map[thor.Bytes32]rlp.RawValue)
+	}
+	// not found in cache
+
+	trie := co.getOrCreateStorageTrie()
+
+	// load from trie
+	v, err := loadStorage(trie, key)
+	if err != nil {
+		return nil, err
+	}
+	// put into cache
+	cache.storage[key] = v
+	return v, nil
+}
+
+// GetCode returns the code of the account.
+func (co *cachedObject) GetCode() ([]byte, error) {
+	cache := &co.cache
+
+	if len(cache.code) > 0 {
+		return cache.code, nil
+	}
+
+	if len(co.data.CodeHash) > 0 {
+		// do have code
+		code, err := co.db.NewStore(codeStoreName).Get(co.data.CodeHash)
+		if err != nil {
+			return nil, err
+		}
+		cache.code = code
+		return code, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package state
+
+import (
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+)
+
+// Stage abstracts changes on the main accounts trie.
+type Stage struct {
+	db       *muxdb.MuxDB
+	tries    []*muxdb.Trie // storage tries followed by the accounts trie
+	codes    map[thor.Bytes32][]byte
+	blockNum uint32
+	conflict uint32
+}
+
+// Hash computes hash of the main accounts trie.
+func (s *Stage) Hash() thor.Bytes32 {
+	return s.tries[len(s.tries)-1].Hash()
+}
+
+// Commit commits all changes into main accounts trie and storage tries.
+func (s *Stage) Commit() (root thor.Bytes32, err error) {
+	// write codes
+	codeStore := s.db.NewStore(codeStoreName)
+	bulk := codeStore.Bulk()
+	for hash, code := range s.codes {
+		if err := bulk.Put(hash[:], code); err != nil {
+			return thor.Bytes32{}, &Error{err}
+		}
+	}
+	if err := bulk.Write(); err != nil {
+		return thor.Bytes32{}, &Error{err}
+	}
+
+	// commit storage tries before the accounts trie
+	for _, t := range s.tries {
+		if root, err = t.Commit(s.blockNum, s.conflict); err != nil {
+			return thor.Bytes32{}, &Error{err}
+		}
+	}
+	return root, nil
+}
@@ -0,0 +1,547 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/stackedmap"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// AccountTrieName is the name of account trie.
+	AccountTrieName = "a"
+	// StorageTrieName is the name of storage tries.
+	StorageTrieName = "s"
+
+	codeStoreName = "state.code"
+)
+
+// Error is the error caused by state access failure.
+type Error struct {
+	cause error
+}
+
+func (e *Error) Error() string {
+	return "state: " + e.cause.Error()
+}
+
+// Cause returns the underlying error.
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// State manages the world state.
+type State struct {
+	db    *muxdb.MuxDB
+	trie  *muxdb.Trie                    // the accounts trie reader
+	cache map[thor.Address]*cachedObject // cache of accounts trie
+	sm    *stackedmap.StackedMap         // keeps revisions of accounts state
+
+	steadyBlockNum uint32
+}
+
+// New create state object.
+// The steadyBlockNum is the number of the head block of the steady chain,
+// states of blocks below it are never reverted.
+func New(db *muxdb.MuxDB, root thor.Bytes32, blockNum, blockConflicts, steadyBlockNum uint32) *State {
+	state := State{
+		db:             db,
+		trie:           db.NewTrie(AccountTrieName, root, blockNum, blockConflicts),
+		cache:          make(map[thor.Address]*cachedObject),
+		steadyBlockNum: steadyBlockNum,
+	}
+
+	state.sm = stackedmap.New(func(key interface{}) (interface{}, bool, error) {
+		return state.cacheGetter(key)
+	})
+	return &state
+}
+
+// Checkout checkouts to another state.
+func (s *State) Checkout(root thor.Bytes32, blockNum, blockConflicts, steadyBlockNum uint32) *State {
+	return New(s.db, root, blockNum, blockConflicts, steadyBlockNum)
+}
+
+// cacheGetter implements stackedmap.MapGetter.
+func (s *State) cacheGetter(key interface{}) (value interface{}, exist bool, err error) {
+	switch k := key.(type) {
+	case thor.Address: // get account
+		obj, err := s.getCachedObject(k)
+		if err != nil {
+			return nil, false, err
+		}
+		return &obj.data, true, nil
+	case codeKey: // get code
+		obj, err := s.getCachedObject(thor.Address(k))
+		if err != nil {
+			return nil, false, err
+		}
+		code, err := obj.GetCode()
+		if err != nil {
+			return nil, false, err
+		}
+		return code, true, nil
+	case storageKey: // get storage
+		// the address was ever deleted in the life-cycle of this state instance.
+		// treat its storage as an empty set.
+		if k.barrier != 0 {
+			return rlp.RawValue(nil), true, nil
+		}
+
+		obj, err := s.getCachedObject(k.addr)
+		if err != nil {
+			return nil, false, err
+		}
+		v, err := obj.GetStorage(k.key)
+		if err != nil {
+			return nil, false, err
+		}
+		return v, true, nil
+	case storageBarrierKey: // the barrier was not set, return false
+		return nil, false, nil
+	}
+	panic("unexpected key type")
+}
+
+func (s *State) getCachedObject(addr thor.Address) (*cachedObject, error) {
+	if co, ok := s.cache[addr]; ok {
+		return co, nil
+	}
+	a, err := loadAccount(s.trie, addr)
+	if err != nil {
+		return nil, err
+	}
+	co := newCachedObject(s.db, a)
+	s.cache[addr] = co
+	return co, nil
+}
+
+// getAccount gets account by address. the returned account should not be modified.
+func (s *State) getAccount(addr thor.Address) (*Account, error) {
+	v, _, err := s.sm.Get(addr)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Account), nil
+}
+
+// getAccountCopy get a copy of account by address.
+func (s *State) getAccountCopy(addr thor.Address) (Account, error) {
+	acc, err := s.getAccount(addr)
+	if err != nil {
+		return Account{}, err
+	}
+	return *acc, nil
+}
+
+func (s *State) updateAccount(addr thor.Address, acc *Account) {
+	s.sm.Put(addr, acc)
+}
+
+// GetBalance returns balance for the given address.
+func (s *State) GetBalance(addr thor.Address) (*big.Int, error) {
+	acc, err := s.getAccount(addr)
+	if err != nil {
+		return nil, &Error{err}
+	}
+	return acc.Balance, nil
+}
+
+// SetBalance set balance for the given address.
+func (s *State) SetBalance(addr thor.Address, balance *big.Int) error {
+	cpy, err := s.getAccountCopy(addr)
+	if err != nil {
+		return &Error{err}
+	}
+	cpy.Balance = balance
+	s.updateAccount(addr, &cpy)
+	return nil
+}
+
+// GetEnergy get energy for the given address at block number specified.
+func (s *State) GetEnergy(addr thor.Address, blockTime uint64) (*big.Int, error) {
+	acc, err := s.getAccount(addr)
+	if err != nil {
+		return nil, &Error{err}
+	}
+	return acc.CalcEnergy(blockTime), nil
+}
+
+// SetEnergy set energy at block number for the given address.
+func (s *State) SetEnergy(addr thor.Address, energy *big.Int, blockTime uint64) error {
+	cpy, err := s.getAccountCopy(addr)
+	if err != nil {
+		return &Error{err}
+	}
+	cpy.Energy, cpy.BlockTime = energy, blockTime
+	s.updateAccount(addr, &cpy)
+	return nil
+}
+
+// GetMaster get master for the given address.
+// Master can move energy, manage users...
+func (s *State) GetMaster(addr thor.Address) (thor.Address, error) {
+	acc, err := s.getAccount(addr)
+	if err != nil {
+		return thor.Address{}, &Error{err}
+	}
+	return thor.BytesToAddress(acc.Master), nil
+}
+
+// SetMaster set master for the given address.
+func (s *State) SetMaster(addr thor.Address, master thor.Address) error {
+	cpy, err := s.getAccountCopy(addr)
+	if err != nil {
+		return &Error{err}
+	}
+	if master.IsZero() {
+		cpy.Master = nil
+	} else {
+		cpy.Master = master[:]
+	}
+	s.updateAccount(addr, &cpy)
+	return nil
+}
+
+// GetStorage returns storage value for the given address and key.
+func (s *State) GetStorage(addr thor.Address, key thor.Bytes32) (thor.Bytes32, error) {
+	raw, err := s.GetRawStorage(addr, key)
+	if err != nil {
+		return thor.Bytes32{}, &Error{err}
+	}
+	if len(raw) == 0 {
+		return thor.Bytes32{}, nil
+	}
+	kind, content, _, err := rlp.Split(raw)
+	if err != nil {
+		return thor.Bytes32{}, &Error{err}
+	}
+	if kind == rlp.List {
+		// special case for rlp list, it should be customized storage value
+		// return hash of raw data
+		return thor.Blake2b(raw), nil
+	}
+	return thor.BytesToBytes32(content), nil
+}
+
+// SetStorage set storage value for the given address and key.
+func (s *State) SetStorage(addr thor.Address, key, value thor.Bytes32) {
+	if value.IsZero() {
+		s.SetRawStorage(addr, key, nil)
+		return
+	}
+	v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
+	s.SetRawStorage(addr, key, v)
+}
+
+// GetRawStorage returns storage value in rlp raw for given address and key.
+func (s *State) GetRawStorage(addr thor.Address, key thor.Bytes32) (rlp.RawValue, error) {
+	data, _, err := s.sm.Get(storageKey{addr, s.getStorageBarrier(addr), key})
+	if err != nil {
+		return nil, &Error{err}
+	}
+	return data.(rlp.RawValue), nil
+}
+
+// SetRawStorage set storage value in rlp raw.
+func (s *State) SetRawStorage(addr thor.Address, key thor.Bytes32, raw rlp.RawValue) {
+	s.sm.Put(storageKey{addr, s.getStorageBarrier(addr), key}, raw)
+}
+
+// getStorageBarrier returns how many times the account was deleted.
+func (s *State) getStorageBarrier(addr thor.Address) int {
+	barrier, _, _ := s.sm.Get(storageBarrierKey(addr))
+	bv, _ := barrier.(int)
+	return bv
+}
+
+// EncodeStorage set storage value encoded by given enc method.
+// Error returned by end will be absorbed by State instance.
+func (s *State) EncodeStorage(addr thor.Address, key thor.Bytes32, enc func() ([]byte, error)) error {
+	raw, err := enc()
+	if err != nil {
+		return &Error{err}
+	}
+	s.SetRawStorage(addr, key, raw)
+	return nil
+}
+
+// DecodeStorage get and decode storage value.
+// Error returned by dec will be absorbed by State instance.
+func (s *State) DecodeStorage(addr thor.Address, key thor.Bytes32, dec func([]byte) error) error {
+	raw, err := s.GetRawStorage(addr, key)
+	if err != nil {
+		return &Error{err}
+	}
+	if err := dec(raw); err != nil {
+		return &Error{err}
+	}
+	return nil
+}
+
+// GetCode returns code for the given address.
+func (s *State) GetCode(addr thor.Address) ([]byte, error) {
+	v, _, err := s.sm.Get(codeKey(addr))
+	if err != nil {
+		return nil, &Error{err}
+	}
+	return v.([]byte), nil
+}
+
+// GetCodeHash returns code hash for the given address.
+func (s *State) GetCodeHash(addr thor.Address) (thor.Bytes32, error) {
+	acc, err := s.getAccount(addr)
+	if err != nil {
+		return thor.Bytes32{}, &Error{err}
+	}
+	return thor.BytesToBytes32(acc.CodeHash), nil
+}
+
+// SetCode set code for the given address.
+func (s *State) SetCode(addr thor.Address, code []byte) error {
+	var codeHash []byte
+	if len(code) > 0 {
+		s.sm.Put(codeKey(addr), code)
+		codeHash = thor.Keccak256(code).Bytes()
+	} else {
+		s.sm.Put(codeKey(addr), []byte(nil))
+	}
+	cpy, err := s.getAccountCopy(addr)
+	if err != nil {
+		return &Error{err}
+	}
+	cpy.CodeHash = codeHash
+	s.updateAccount(addr, &cpy)
+	return nil
+}
+
+// GetStorageRoot returns the storage root of the account at the committed state.
+// Pending storage changes are not reflected.
+func (s *State) GetStorageRoot(addr thor.Address) (thor.Bytes32, error) {
+	acc, err := s.getAccount(addr)
+	if err != nil {
+		return thor.Bytes32{}, &Error{err}
+	}
+	return thor.BytesToBytes32(acc.StorageRoot), nil
+}
+
+// BuildStorageTrie builds up the storage trie for the given address with cumulative changes.
+func (s *State) BuildStorageTrie(addr thor.Address) (*muxdb.Trie, error) {
+	acc, err := s.getAccount(addr)
+	if err != nil {
+		return nil, &Error{err}
+	}
+
+	trie := s.db.NewTrie(StorageTrieName, thor.BytesToBytes32(acc.StorageRoot), 0, 0)
+
+	// only changes made after the latest deletion are effective
+	barrier := s.getStorageBarrier(addr)
+	var jerr error
+	s.sm.Journal(func(k, v interface{}) bool {
+		if key, ok := k.(storageKey); ok && key.addr == addr && key.barrier == barrier {
+			if err := saveStorage(trie, key.key, v.(rlp.RawValue)); err != nil {
+				jerr = &Error{err}
+				return false
+			}
+		}
+		return true
+	})
+	if jerr != nil {
+		return nil, jerr
+	}
+	return trie, nil
+}
+
+// proofList collects encoded trie nodes of a merkle proof.
+type proofList [][]byte
+
+// Put implements trie.DatabaseWriter.
+func (l *proofList) Put(_, value []byte) error {
+	*l = append(*l, append([]byte(nil), value...))
+	return nil
+}
+
+// ProveAccount returns the merkle proof of the account against the committed state root.
+// Pending changes are not reflected.
+func (s *State) ProveAccount(addr thor.Address) ([][]byte, error) {
+	var proof proofList
+	if err := s.trie.Prove(thor.Blake2b(addr[:]).Bytes(), &proof); err != nil {
+		return nil, &Error{err}
+	}
+	return proof, nil
+}
+
+// ProveStorage returns the merkle proof of the storage value against the committed storage root
+// of the account. Pending changes are not reflected.
+func (s *State) ProveStorage(addr thor.Address, key thor.Bytes32) ([][]byte, error) {
+	acc, err := loadAccount(s.trie, addr)
+	if err != nil {
+		return nil, &Error{err}
+	}
+	var proof proofList
+	trie := s.db.NewTrie(StorageTrieName, thor.BytesToBytes32(acc.StorageRoot), 0, 0)
+	if err := trie.Prove(thor.Blake2b(key[:]).Bytes(), &proof); err != nil {
+		return nil, &Error{err}
+	}
+	return proof, nil
+}
+
+// Exists returns whether an account exists at the given address.
+// See Account.IsEmpty()
+func (s *State) Exists(addr thor.Address) (bool, error) {
+	acc, err := s.getAccount(addr)
+	if err != nil {
+		return false, &Error{err}
+	}
+	return !acc.IsEmpty(), nil
+}
+
+// Delete delete an account at the given address.
+// That's set balance, energy and code to zero value.
+func (s *State) Delete(addr thor.Address) {
+	s.sm.Put(codeKey(addr), []byte(nil))
+	s.updateAccount(addr, emptyAccount())
+	// increase the barrier value
+	s.sm.Put(storageBarrierKey(addr), s.getStorageBarrier(addr)+1)
+}
+
+// NewCheckpoint makes a checkpoint of current state.
+// It returns revision of the checkpoint.
+func (s *State) NewCheckpoint() int {
+	return s.sm.Push()
+}
+
+// RevertTo revert to checkpoint specified by revision.
+func (s *State) RevertTo(revision int) {
+	s.sm.PopTo(revision)
+}
+
+// Stage makes a stage object to compute hash of trie or commit all changes.
+func (s *State) Stage(newBlockNum, newBlockConflicts uint32) (*Stage, error) {
+	type changed struct {
+		data            Account
+		storage         map[thor.Bytes32]rlp.RawValue
+		baseStorageTrie *muxdb.Trie
+	}
+
+	var (
+		changes = make(map[thor.Address]*changed)
+		codes   = make(map[thor.Bytes32][]byte)
+	)
+
+	// get or create changed account
+	getChanged := func(addr thor.Address) (*changed, error) {
+		if obj, ok := changes[addr]; ok {
+			return obj, nil
+		}
+		co, err := s.getCachedObject(addr)
+		if err != nil {
+			return nil, &Error{err}
+		}
+
+		c := &changed{data: co.data, baseStorageTrie: co.cache.storageTrie}
+		changes[addr] = c
+		return c, nil
+	}
+
+	var jerr error
+	// traverse journal to filter out changes
+	s.sm.Journal(func(k, v interface{}) bool {
+		switch key := k.(type) {
+		case thor.Address:
+			c, err := getChanged(key)
+			if err != nil {
+				jerr = err
+				return false
+			}
+			c.data = *(v.(*Account))
+		case codeKey:
+			code := v.([]byte)
+			if len(code) > 0 {
+				codes[thor.Keccak256(code)] = code
+			}
+		case storageKey:
+			c, err := getChanged(key.addr)
+			if err != nil {
+				jerr = err
+				return false
+			}
+			if c.storage == nil {
+				c.storage = make(map[thor.Bytes32]rlp.RawValue)
+			}
+			c.storage[key.key] = v.(rlp.RawValue)
+		case storageBarrierKey:
+			c, err := getChanged(thor.Address(key))
+			if err != nil {
+				jerr = err
+				return false
+			}
+			// discard all storage updates and base storage trie when meet the barrier
+			c.storage = nil
+			c.baseStorageTrie = s.db.NewTrie(StorageTrieName, thor.Bytes32{}, 0, 0)
+			c.data.StorageRoot = nil
+		}
+		return true
+	})
+	if jerr != nil {
+		return nil, jerr
+	}
+
+	trie := s.trie.Copy()
+	tries := make([]*muxdb.Trie, 0, len(changes)+1)
+
+	for addr, c := range changes {
+		// skip storage changes if account is empty
+		if !c.data.IsEmpty() {
+			if len(c.storage) > 0 {
+				var sTrie *muxdb.Trie
+				if c.baseStorageTrie != nil {
+					sTrie = c.baseStorageTrie.Copy()
+				} else {
+					sTrie = s.db.NewTrie(StorageTrieName, thor.BytesToBytes32(c.data.StorageRoot), 0, 0)
+				}
+				for k, v := range c.storage {
+					if err := saveStorage(sTrie, k, v); err != nil {
+						return nil, &Error{err}
+					}
+				}
+				sRoot := sTrie.Hash()
+				c.data.StorageRoot = sRoot[:]
+				tries = append(tries, sTrie)
+			}
+		} else {
+			// account is empty, so its storage root is meaningless
+			c.data.StorageRoot = nil
+		}
+		if err := saveAccount(trie, addr, &c.data); err != nil {
+			return nil, &Error{err}
+		}
+	}
+	tries = append(tries, trie)
+
+	return &Stage{
+		db:       s.db,
+		tries:    tries,
+		codes:    codes,
+		blockNum: newBlockNum,
+		conflict: newBlockConflicts,
+	}, nil
+}
+
+type (
+	storageKey struct {
+		addr    thor.Address
+		barrier int
+		key     thor.Bytes32
+	}
+	codeKey           thor.Address
+	storageBarrierKey thor.Address
+)
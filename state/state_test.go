@@ -0,0 +1,204 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/trie"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateReadWrite(t *testing.T) {
+	db := muxdb.NewMem()
+	state := New(db, thor.Bytes32{}, 0, 0, 0)
+
+	addr := thor.BytesToAddress([]byte("account1"))
+	storageKey := thor.BytesToBytes32([]byte("storageKey"))
+
+	assert.Equal(t, M(false, nil), M(state.Exists(addr)))
+	assert.Equal(t, M(&big.Int{}, nil), M(state.GetBalance(addr)))
+	assert.Equal(t, M([]byte(nil), nil), M(state.GetCode(addr)))
+	assert.Equal(t, M(thor.Bytes32{}, nil), M(state.GetCodeHash(addr)))
+	assert.Equal(t, M(thor.Bytes32{}, nil), M(state.GetStorage(addr, storageKey)))
+
+	// make account not empty
+	state.SetBalance(addr, big.NewInt(1))
+	assert.Equal(t, M(big.NewInt(1), nil), M(state.GetBalance(addr)))
+
+	state.SetMaster(addr, thor.BytesToAddress([]byte("master")))
+	assert.Equal(t, M(thor.BytesToAddress([]byte("master")), nil), M(state.GetMaster(addr)))
+
+	state.SetCode(addr, []byte("code"))
+	assert.Equal(t, M([]byte("code"), nil), M(state.GetCode(addr)))
+	assert.Equal(t, M(thor.Keccak256([]byte("code")), nil), M(state.GetCodeHash(addr)))
+
+	state.SetStorage(addr, storageKey, thor.BytesToBytes32([]byte("storageValue")))
+	assert.Equal(t, M(thor.BytesToBytes32([]byte("storageValue")), nil), M(state.GetStorage(addr, storageKey)))
+
+	assert.Equal(t, M(true, nil), M(state.Exists(addr)))
+
+	// delete account
+	state.Delete(addr)
+	assert.Equal(t, M(false, nil), M(state.Exists(addr)))
+	assert.Equal(t, M(&big.Int{}, nil), M(state.GetBalance(addr)))
+	assert.Equal(t, M(thor.Address{}, nil), M(state.GetMaster(addr)))
+	assert.Equal(t, M([]byte(nil), nil), M(state.GetCode(addr)))
+	assert.Equal(t, M(thor.Bytes32{}, nil), M(state.GetCodeHash(addr)))
+	assert.Equal(t, M(thor.Bytes32{}, nil), M(state.GetStorage(addr, storageKey)), "should be empty")
+}
+
+func TestStateRevert(t *testing.T) {
+	db := muxdb.NewMem()
+	state := New(db, thor.Bytes32{}, 0, 0, 0)
+
+	addr := thor.BytesToAddress([]byte("account1"))
+	storageKey := thor.BytesToBytes32([]byte("storageKey"))
+
+	values := []struct {
+		balance *big.Int
+		code    []byte
+		storage thor.Bytes32
+	}{
+		{big.NewInt(1), []byte("code1"), thor.BytesToBytes32([]byte("v1"))},
+		{big.NewInt(2), []byte("code2"), thor.BytesToBytes32([]byte("v2"))},
+		{big.NewInt(3), []byte("code3"), thor.BytesToBytes32([]byte("v3"))},
+	}
+
+	revs := make([]int, len(values))
+	for i, v := range values {
+		revs[i] = state.NewCheckpoint()
+		state.SetBalance(addr, v.balance)
+		state.SetCode(addr, v.code)
+		state.SetStorage(addr, storageKey, v.storage)
+	}
+
+	for i := len(values) - 1; i >= 0; i-- {
+		v := values[i]
+		assert.Equal(t, M(v.balance, nil), M(state.GetBalance(addr)))
+		assert.Equal(t, M(v.code, nil), M(state.GetCode(addr)))
+		assert.Equal(t, M(thor.Keccak256(v.code), nil), M(state.GetCodeHash(addr)))
+		assert.Equal(t, M(v.storage, nil), M(state.GetStorage(addr, storageKey)))
+		state.RevertTo(revs[i])
+	}
+	assert.Equal(t, M(false, nil), M(state.Exists(addr)))
+
+	state = New(db, thor.Bytes32{}, 0, 0, 0)
+	rev := state.NewCheckpoint()
+	state.RevertTo(rev)
+	assert.Equal(t, rev, state.NewCheckpoint())
+}
+
+func TestStateCommit(t *testing.T) {
+	db := muxdb.NewMem()
+	st := New(db, thor.Bytes32{}, 0, 0, 0)
+
+	addr := thor.BytesToAddress([]byte("account1"))
+	key := thor.BytesToBytes32([]byte("key"))
+
+	st.SetBalance(addr, big.NewInt(100))
+	st.SetEnergy(addr, big.NewInt(10), 1)
+	st.SetCode(addr, []byte("code"))
+	st.SetStorage(addr, key, thor.BytesToBytes32([]byte("value")))
+
+	stage, err := st.Stage(1, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, stage.Hash(), root)
+
+	st = New(db, root, 1, 0, 0)
+	assert.Equal(t, M(big.NewInt(100), nil), M(st.GetBalance(addr)))
+	assert.Equal(t, M([]byte("code"), nil), M(st.GetCode(addr)))
+	assert.Equal(t, M(thor.BytesToBytes32([]byte("value")), nil), M(st.GetStorage(addr, key)))
+
+	// energy grows with balance and time
+	energy, err := st.GetEnergy(addr, 11)
+	assert.Nil(t, err)
+	expected := new(big.Int).Mul(big.NewInt(100*10), thor.EnergyGrowthRate)
+	expected.Div(expected, big.NewInt(1e18))
+	expected.Add(expected, big.NewInt(10))
+	assert.Equal(t, expected, energy)
+
+	// deleted account clears storage after commit
+	st.Delete(addr)
+	st.SetBalance(addr, big.NewInt(1))
+	stage, err = st.Stage(2, 0)
+	assert.Nil(t, err)
+	root, err = stage.Commit()
+	assert.Nil(t, err)
+
+	st = New(db, root, 2, 0, 0)
+	assert.Equal(t, M(thor.Bytes32{}, nil), M(st.GetStorage(addr, key)))
+	assert.Equal(t, M(thor.Bytes32{}, nil), M(st.GetStorageRoot(addr)))
+}
+
+// proofDB serves proof nodes by their hash.
+type proofDB map[thor.Bytes32][]byte
+
+func (db proofDB) Get(key []byte) ([]byte, error) {
+	return db[thor.BytesToBytes32(key)], nil
+}
+
+func newProofDB(proof [][]byte) proofDB {
+	db := make(proofDB)
+	for _, node := range proof {
+		db[thor.Blake2b(node)] = node
+	}
+	return db
+}
+
+func TestStateProve(t *testing.T) {
+	db := muxdb.NewMem()
+	st := New(db, thor.Bytes32{}, 0, 0, 0)
+
+	addr := thor.BytesToAddress([]byte("account1"))
+	key := thor.BytesToBytes32([]byte("key"))
+	for i := 0; i < 10; i++ {
+		st.SetBalance(thor.BytesToAddress([]byte{byte(i)}), big.NewInt(1))
+		st.SetStorage(addr, thor.BytesToBytes32([]byte{byte(i)}), thor.BytesToBytes32([]byte{1}))
+	}
+	st.SetBalance(addr, big.NewInt(100))
+	st.SetStorage(addr, key, thor.BytesToBytes32([]byte("value")))
+
+	stage, err := st.Stage(1, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	st = New(db, root, 1, 0, 0)
+	proof, err := st.ProveAccount(addr)
+	assert.Nil(t, err)
+	enc, err, _ := trie.VerifyProof(root, thor.Blake2b(addr[:]).Bytes(), newProofDB(proof))
+	assert.Nil(t, err)
+	var acc Account
+	assert.Nil(t, rlp.DecodeBytes(enc, &acc))
+	assert.Equal(t, big.NewInt(100), acc.Balance)
+
+	proof, err = st.ProveStorage(addr, key)
+	assert.Nil(t, err)
+	enc, err, _ = trie.VerifyProof(thor.BytesToBytes32(acc.StorageRoot), thor.Blake2b(key[:]).Bytes(), newProofDB(proof))
+	assert.Nil(t, err)
+	_, content, _, err := rlp.Split(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, thor.BytesToBytes32([]byte("value")), thor.BytesToBytes32(content))
+
+	// proof of absence
+	absent := thor.BytesToAddress([]byte("absent"))
+	proof, err = st.ProveAccount(absent)
+	assert.Nil(t, err)
+	enc, err, _ = trie.VerifyProof(root, thor.Blake2b(absent[:]).Bytes(), newProofDB(proof))
+	assert.Nil(t, err)
+	assert.Nil(t, enc)
+}
+
+func M(a ...interface{}) []interface{} {
+	return a
+}
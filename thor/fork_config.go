@@ -9,17 +9,18 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"sync"
 )
 
 // nolint: revive
 // ForkConfig config for a fork.
 type ForkConfig struct {
-	VIP191    uint32
-	ETH_CONST uint32
-	BLOCKLIST uint32
-	ETH_IST   uint32
-	VIP214    uint32
-	FINALITY  uint32
+	VIP191    uint32 `json:"VIP191" yaml:"VIP191"`
+	ETH_CONST uint32 `json:"ETH_CONST" yaml:"ETH_CONST"`
+	BLOCKLIST uint32 `json:"BLOCKLIST" yaml:"BLOCKLIST"`
+	ETH_IST   uint32 `json:"ETH_IST" yaml:"ETH_IST"`
+	VIP214    uint32 `json:"VIP214" yaml:"VIP214"`
+	FINALITY  uint32 `json:"FINALITY" yaml:"FINALITY"`
 }
 
 func (fc ForkConfig) String() string {
@@ -72,7 +73,36 @@ var forkConfigs = map[Bytes32]ForkConfig{
 	},
 }
 
+// well-known networks, which can't be overridden
+var builtinForkConfigs = func() map[Bytes32]bool {
+	m := make(map[Bytes32]bool, len(forkConfigs))
+	for id := range forkConfigs {
+		m[id] = true
+	}
+	return m
+}()
+
+var forkConfigsLock sync.RWMutex
+
 // GetForkConfig get fork config for given genesis ID.
 func GetForkConfig(genesisID Bytes32) ForkConfig {
+	forkConfigsLock.RLock()
+	defer forkConfigsLock.RUnlock()
 	return forkConfigs[genesisID]
 }
+
+// RegisterForkConfig registers fork config for a custom network with the given genesis ID.
+// Configs of well-known networks can't be overridden.
+func RegisterForkConfig(genesisID Bytes32, fc ForkConfig) error {
+	if builtinForkConfigs[genesisID] {
+		return fmt.Errorf("fork config of genesis %v is built in", genesisID)
+	}
+	if err := fc.Validate(); err != nil {
+		return err
+	}
+
+	forkConfigsLock.Lock()
+	defer forkConfigsLock.Unlock()
+	forkConfigs[genesisID] = fc
+	return nil
+}
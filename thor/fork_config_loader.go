@@ -0,0 +1,78 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package thor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validate checks that forks depending on each other are activated in order.
+func (fc ForkConfig) Validate() error {
+	ordered := []struct {
+		name    string
+		num     uint32
+		depName string
+		depNum  uint32
+	}{
+		// istanbul rules are built upon constantinople ones
+		{"ETH_IST", fc.ETH_IST, "ETH_CONST", fc.ETH_CONST},
+		// finality votes are carried by VRF signed blocks
+		{"FINALITY", fc.FINALITY, "VIP214", fc.VIP214},
+	}
+	for _, o := range ordered {
+		if o.num < o.depNum {
+			return fmt.Errorf("fork %v (#%v) activated before %v (#%v)", o.name, o.num, o.depName, o.depNum)
+		}
+	}
+	return nil
+}
+
+// ParseForkConfig parses fork config in JSON or YAML format.
+// Forks absent from the data are disabled, and unknown keys or empty data are rejected.
+func ParseForkConfig(data []byte, isYAML bool) (ForkConfig, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ForkConfig{}, errors.New("empty fork config")
+	}
+
+	fc := NoFork
+	if isYAML {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&fc); err != nil {
+			return ForkConfig{}, fmt.Errorf("decode fork config: %w", err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&fc); err != nil {
+			return ForkConfig{}, fmt.Errorf("decode fork config: %w", err)
+		}
+	}
+
+	if err := fc.Validate(); err != nil {
+		return ForkConfig{}, err
+	}
+	return fc, nil
+}
+
+// LoadForkConfig loads fork config from file, in YAML format if the file is
+// suffixed with .yaml or .yml, otherwise in JSON format.
+func LoadForkConfig(path string) (ForkConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ForkConfig{}, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	return ParseForkConfig(data, ext == ".yaml" || ext == ".yml")
+}
@@ -7,7 +7,11 @@ package thor
 
 import (
 	"math"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // TestForkConfigString verifies that the String method returns expected values.
@@ -57,3 +61,78 @@ func TestGetForkConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestParseForkConfig(t *testing.T) {
+	expected := NoFork
+	expected.VIP191 = 1
+	expected.ETH_CONST = 2
+	expected.ETH_IST = 3
+
+	fc, err := ParseForkConfig([]byte(`{"VIP191": 1, "ETH_CONST": 2, "ETH_IST": 3}`), false)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, fc)
+
+	fc, err = ParseForkConfig([]byte("VIP191: 1\nETH_CONST: 2\nETH_IST: 3\n"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, fc)
+
+	// empty config
+	for _, data := range []string{"", " \n", "# no forks\n"} {
+		_, err = ParseForkConfig([]byte(data), true)
+		assert.NotNil(t, err, "%q", data)
+	}
+	for _, data := range []string{"", " \n"} {
+		_, err = ParseForkConfig([]byte(data), false)
+		assert.NotNil(t, err, "%q", data)
+	}
+
+	// unknown keys
+	_, err = ParseForkConfig([]byte(`{"VIP191": 1, "VIP999": 2}`), false)
+	assert.NotNil(t, err)
+	_, err = ParseForkConfig([]byte("VIP191: 1\nVIP999: 2\n"), true)
+	assert.NotNil(t, err)
+
+	// out of order
+	_, err = ParseForkConfig([]byte(`{"ETH_CONST": 3, "ETH_IST": 2}`), false)
+	assert.NotNil(t, err)
+	_, err = ParseForkConfig([]byte("FINALITY: 0\n"), true)
+	assert.NotNil(t, err)
+}
+
+func TestLoadForkConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "forks.yml")
+	assert.Nil(t, os.WriteFile(path, []byte("VIP214: 10\nFINALITY: 20\n"), 0644))
+	fc, err := LoadForkConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(10), fc.VIP214)
+	assert.Equal(t, uint32(20), fc.FINALITY)
+
+	// parsed as JSON
+	path = filepath.Join(dir, "forks.json")
+	assert.Nil(t, os.WriteFile(path, []byte("VIP214: 10\n"), 0644))
+	_, err = LoadForkConfig(path)
+	assert.NotNil(t, err)
+
+	_, err = LoadForkConfig(filepath.Join(dir, "missing.json"))
+	assert.NotNil(t, err)
+}
+
+func TestRegisterForkConfig(t *testing.T) {
+	customID := MustParseBytes32("0x00000000c05a20fbca2bf6ae3affba6af4a74b800b585bf7a4988aba7aea69f6")
+	fc := NoFork
+	fc.VIP191 = 10
+
+	assert.Nil(t, RegisterForkConfig(customID, fc))
+	assert.Equal(t, fc, GetForkConfig(customID))
+
+	// well-known networks can't be overridden
+	mainnetID := MustParseBytes32("0x00000000851caf3cfdb6e899cf5958bfb1ac3413d346d43539627e6be7ec1b4a")
+	assert.NotNil(t, RegisterForkConfig(mainnetID, fc))
+	assert.NotEqual(t, fc, GetForkConfig(mainnetID))
+
+	// invalid
+	fc.FINALITY = 0
+	assert.NotNil(t, RegisterForkConfig(customID, fc))
+}
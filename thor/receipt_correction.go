@@ -5,10 +5,11 @@
 
 package thor
 
-// LoadCorrectReceiptsRoots load a map from block ID to correct receipts root.
-func LoadCorrectReceiptsRoots() map[string]string {
-	return map[string]string{
-		// mainnet
+// correctReceiptsRoots maps genesis ID to a map from block ID to correct receipts root.
+// Receipts roots of these blocks were miscalculated, and got packed.
+var correctReceiptsRoots = map[Bytes32]map[string]string{
+	// mainnet
+	MustParseBytes32("0x00000000851caf3cfdb6e899cf5958bfb1ac3413d346d43539627e6be7ec1b4a"): {
 		"0x000c2c63d845188f8390de84d1364b59cd2890f276452762f9ab0761ecc93069": "0xe467857991d0e04da9b2e0365110b49f198febf9d3fa8413c536527f857bd246",
 		"0x000c2e6f3bdf5dca3a9ef3956b0b27dc1f91be34a4a13f9440d01245ec966bde": "0x6be181b115b01c68b4aa60a005d7c5d1053fd49e488d9d140dde44ea11ba7110",
 		"0x000c2f8e068e9aff4287cfb55b7ff815577810cb4a6646b34727d2766b337b0f": "0x1fb406967052dbb03131838809995919bec842baf179855ee0c74367cf3e12fa",
@@ -748,7 +749,9 @@ func LoadCorrectReceiptsRoots() map[string]string {
 		"0x00104d3f85414fc23be207b64a8479a9d41f3cc31ff3cbad71ec394078391c0b": "0x8e50da3072a2166c74eabca60d75d38639ca62281b4cf6f2f3872111cc120b68",
 		"0x001050763a843b8a726c4719fcad3c14b5031fafc6514e5b590c00947bf65e2e": "0x64061654eec34dfe635eccd5017700992cdda5111eee8d43282d183d0c2cee77",
 		"0x001059e923ab6fbd14a47fc46f46d81435715479b0e973761faa2faef27b0c89": "0x1074996004e0f443f8756f9dee327f641198c3f9df0b895b6b48baee5fd4356a",
-		// testnet
+	},
+	// testnet
+	MustParseBytes32("0x000000000b2bce3c70bc649a02749e8687721b09ed2e15997f466536b20bb127"): {
 		"0x0003f14f1f7a293dae5e8828e9ddab7bd5b6d874cc5cb247e7e231d6eeeb6132": "0x767f2a98bf6e765d44d2d974c4e419b1723d2962614eb4aa07dd4557a0d76204",
 		"0x00082231521a0016dd3238518cc479e8181aaa862d8829d163b017b03fa4b204": "0xc2a66ddfb2c3ca060af12fbb529835df14c0c257fe1eb2dce8606d44160f46cb",
 		"0x000882e1ea1699dc6e1a7ba932db4849e918dbc9b3618718e08099a0032d8246": "0x1319daa38794d834ee7b66b8d0de5377437bfcaac516c121d4e0455d45c1bbc9",
@@ -876,5 +879,21 @@ func LoadCorrectReceiptsRoots() map[string]string {
 		"0x001006ddb1181bcfab89f49c95c29f594cae7c34b22883830e8512e40845d0a3": "0xf5be0fd3fbb09e1189b8b84b9eea5a70b72a6f8eb7be0eda1decc6c7e2433332",
 		"0x001007063816823fd76dd6c9568a4187e409d412f7e7cdca0507c0801b3f744e": "0x36eabfe86cb616e0870e678376a066ad2be585d7a984ee6af1d1f3f7d2eb30a4",
 		"0x00100a8d97d35fa76691feecd20cd2fce5da312b264887e766956b8deec7d3dc": "0x11f4a3de3d03d106f4319c81874acb2f867f5ac472d72c4351fb476e62768e53",
+	},
+}
+
+// LoadCorrectReceiptsRoots load a map from block ID to correct receipts root.
+func LoadCorrectReceiptsRoots() map[string]string {
+	roots := make(map[string]string)
+	for _, m := range correctReceiptsRoots {
+		for id, root := range m {
+			roots[id] = root
+		}
 	}
+	return roots
+}
+
+// GetCorrectReceiptsRoots get the map from block ID to correct receipts root for given genesis ID.
+func GetCorrectReceiptsRoots(genesisID Bytes32) map[string]string {
+	return correctReceiptsRoots[genesisID]
 }
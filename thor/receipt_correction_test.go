@@ -29,3 +29,22 @@ func TestLoadCorrectReceiptsRootsForKeyValue(t *testing.T) {
 		t.Errorf("For key %s, expected value %s, got %s", expectedKey, expectedValue, actualValue)
 	}
 }
+
+func TestGetCorrectReceiptsRoots(t *testing.T) {
+	mainnet := MustParseBytes32("0x00000000851caf3cfdb6e899cf5958bfb1ac3413d346d43539627e6be7ec1b4a")
+	testnet := MustParseBytes32("0x000000000b2bce3c70bc649a02749e8687721b09ed2e15997f466536b20bb127")
+	id := "0x000c2c63d845188f8390de84d1364b59cd2890f276452762f9ab0761ecc93069"
+
+	if _, ok := GetCorrectReceiptsRoots(mainnet)[id]; !ok {
+		t.Errorf("Expected key %s found in mainnet corrections", id)
+	}
+	if _, ok := GetCorrectReceiptsRoots(testnet)[id]; ok {
+		t.Errorf("Expected key %s not found in testnet corrections", id)
+	}
+	if roots := GetCorrectReceiptsRoots(Bytes32{}); len(roots) != 0 {
+		t.Errorf("Expected no corrections for unknown network, got %d", len(roots))
+	}
+	if len(LoadCorrectReceiptsRoots()) != len(GetCorrectReceiptsRoots(mainnet))+len(GetCorrectReceiptsRoots(testnet)) {
+		t.Errorf("Expected all corrections loaded")
+	}
+}
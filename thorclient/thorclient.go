@@ -115,6 +115,11 @@ func (c *Client) Block(revision string) (blocks *blocks.JSONCollapsedBlock, err
 	return c.httpConn.GetBlock(revision)
 }
 
+// RawBlock retrieves the rlp encoded block header by its revision.
+func (c *Client) RawBlock(revision string) (*blocks.JSONRawBlockSummary, error) {
+	return c.httpConn.GetRawBlock(revision)
+}
+
 // ExpandedBlock retrieves an expanded block by its revision.
 func (c *Client) ExpandedBlock(revision string) (blocks *blocks.JSONExpandedBlock, err error) {
 	return c.httpConn.GetExpandedBlock(revision)
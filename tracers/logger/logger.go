@@ -0,0 +1,231 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+// Package logger implements the opcode level struct logger.
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tracers"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register(tracers.DefaultName, func(ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
+		var config Config
+		if len(cfg) > 0 {
+			if err := json.Unmarshal(cfg, &config); err != nil {
+				return nil, err
+			}
+		}
+		l := NewStructLogger(&config, ctx.State)
+		return &tracers.Tracer{
+			Hooks:     l.Hooks(),
+			GetResult: l.GetResult,
+			Stop:      l.Stop,
+		}, nil
+	})
+}
+
+// Config are the configuration options for the struct logger.
+type Config struct {
+	EnableMemory     bool `json:"enableMemory"`     // enable memory capture
+	DisableStack     bool `json:"disableStack"`     // disable stack capture
+	DisableStorage   bool `json:"disableStorage"`   // disable storage capture
+	EnableReturnData bool `json:"enableReturnData"` // enable return data capture
+	Limit            int  `json:"limit"`            // maximum length of output, but zero means unlimited
+}
+
+// StructLog is emitted to the EVM each cycle and lists information about the
+// current internal state prior to the execution of the statement.
+type StructLog struct {
+	Pc         uint64                      `json:"pc"`
+	Op         string                      `json:"op"`
+	Gas        uint64                      `json:"gas"`
+	GasCost    uint64                      `json:"gasCost"`
+	Depth      int                         `json:"depth"`
+	Error      string                      `json:"error,omitempty"`
+	Stack      []hexutil.U256              `json:"stack,omitempty"`
+	Memory     []string                    `json:"memory,omitempty"`
+	Storage    map[common.Hash]common.Hash `json:"storage,omitempty"`
+	ReturnData hexutil.Bytes               `json:"returnData,omitempty"`
+}
+
+// ExecutionResult groups all structured logs emitted by the EVM
+// while replaying a clause in debug mode.
+type ExecutionResult struct {
+	Gas         uint64      `json:"gas"`
+	Failed      bool        `json:"failed"`
+	ReturnValue string      `json:"returnValue"`
+	StructLogs  []StructLog `json:"structLogs"`
+}
+
+// StructLogger is an EVM state logger and implements the tracing hooks.
+//
+// StructLogger can capture state based on the given Log configuration and also keeps
+// a track record of modified storage which is used in reporting snapshots of the
+// contract their storage.
+type StructLogger struct {
+	cfg   Config
+	state *state.State
+
+	storage map[thor.Address]map[thor.Bytes32]thor.Bytes32
+	logs    []StructLog
+	output  []byte
+	gasUsed uint64
+	err     error
+
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// NewStructLogger returns a new logger.
+func NewStructLogger(cfg *Config, st *state.State) *StructLogger {
+	logger := &StructLogger{
+		state:   st,
+		storage: make(map[thor.Address]map[thor.Bytes32]thor.Bytes32),
+	}
+	if cfg != nil {
+		logger.cfg = *cfg
+	}
+	return logger
+}
+
+// Hooks returns the tracing hooks of the logger.
+func (l *StructLogger) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnOpcode: l.OnOpcode,
+		OnExit:   l.OnExit,
+	}
+}
+
+// OnOpcode logs a new structured log message and pushes it out to the environment.
+//
+// OnOpcode also tracks SLOAD/SSTORE ops to track storage change.
+func (l *StructLogger) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	// If tracing was interrupted, set the error and stop
+	if l.interrupt.Load() {
+		return
+	}
+	// check if already accumulated the specified number of logs
+	if l.cfg.Limit != 0 && l.cfg.Limit <= len(l.logs) {
+		return
+	}
+
+	op := vm.OpCode(opcode)
+	log := StructLog{
+		Pc:      pc,
+		Op:      op.String(),
+		Gas:     gas,
+		GasCost: cost,
+		Depth:   depth,
+	}
+	if err != nil {
+		log.Error = err.Error()
+	}
+
+	// Copy a snapshot of the current memory state to a new buffer
+	if l.cfg.EnableMemory {
+		memory := scope.MemoryData()
+		for i := 0; i+32 <= len(memory); i += 32 {
+			log.Memory = append(log.Memory, fmt.Sprintf("%x", memory[i:i+32]))
+		}
+	}
+
+	stack := scope.StackData()
+	// Copy a snapshot of the current stack state to a new buffer
+	if !l.cfg.DisableStack {
+		log.Stack = make([]hexutil.U256, len(stack))
+		for i, item := range stack {
+			log.Stack[i] = hexutil.U256(item)
+		}
+	}
+
+	stackLen := len(stack)
+	// Copy a snapshot of the current storage to a new container
+	if !l.cfg.DisableStorage && (op == vm.SLOAD || op == vm.SSTORE) {
+		contract := thor.Address(scope.Address())
+		if l.storage[contract] == nil {
+			l.storage[contract] = make(map[thor.Bytes32]thor.Bytes32)
+		}
+		switch {
+		case op == vm.SLOAD && stackLen >= 1:
+			// capture SLOAD opcodes and record the read entry in the local storage
+			key := thor.Bytes32(stack[stackLen-1].Bytes32())
+			value, err := l.state.GetStorage(contract, key)
+			if err != nil {
+				l.Stop(err)
+				return
+			}
+			l.storage[contract][key] = value
+		case op == vm.SSTORE && stackLen >= 2:
+			// capture SSTORE opcodes and record the written entry in the local storage.
+			key := thor.Bytes32(stack[stackLen-1].Bytes32())
+			value := thor.Bytes32(stack[stackLen-2].Bytes32())
+			l.storage[contract][key] = value
+		}
+		log.Storage = make(map[common.Hash]common.Hash, len(l.storage[contract]))
+		for k, v := range l.storage[contract] {
+			log.Storage[common.Hash(k)] = common.Hash(v)
+		}
+	}
+	if l.cfg.EnableReturnData && len(rData) > 0 {
+		log.ReturnData = append([]byte{}, rData...)
+	}
+
+	l.logs = append(l.logs, log)
+}
+
+// OnExit is called a call frame finishes processing.
+func (l *StructLogger) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if depth != 0 {
+		return
+	}
+	l.output = output
+	l.gasUsed = gasUsed
+	l.err = err
+}
+
+// GetResult returns the JSON encoded result of the trace.
+func (l *StructLogger) GetResult() (json.RawMessage, error) {
+	// Tracing aborted
+	if l.reason != nil {
+		return nil, l.reason
+	}
+	failed := l.err != nil
+	returnData := l.output
+	// Return data when successful and revert reason when reverted, otherwise empty.
+	if failed && l.err != vm.ErrExecutionReverted {
+		returnData = []byte{}
+	}
+	return json.Marshal(&ExecutionResult{
+		Gas:         l.gasUsed,
+		Failed:      failed,
+		ReturnValue: fmt.Sprintf("%x", returnData),
+		StructLogs:  l.StructLogs(),
+	})
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (l *StructLogger) Stop(err error) {
+	l.reason = err
+	l.interrupt.Store(true)
+}
+
+// StructLogs returns the captured log entries.
+func (l *StructLogger) StructLogs() []StructLog {
+	if l.logs == nil {
+		return []StructLog{}
+	}
+	return l.logs
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/ashkanabbasii/thor/tracers"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("4byte", newFourByteTracer)
+}
+
+// fourByteTracer searches for 4byte-identifiers, and collects them for post-processing.
+// It collects the methods identifiers along with the size of the supplied data, so
+// a reversed signature can be matched against the size of the data.
+//
+// Example result:
+//
+//	{
+//	  0x27dc297e-128: 1,
+//	  0x38cc4831-0: 2,
+//	  0x524f3889-96: 1,
+//	  0xadf59f99-288: 1,
+//	  0xc281d19e-0: 1
+//	}
+type fourByteTracer struct {
+	ids       map[string]int // ids aggregates the 4byte ids found
+	interrupt atomic.Bool    // Atomic flag to signal execution interruption
+	reason    error          // Textual reason for the interruption
+}
+
+// newFourByteTracer returns a native tracer which collects
+// 4 byte-identifiers of a clause, and implements tracing hooks.
+func newFourByteTracer(_ *tracers.Context, _ json.RawMessage) (*tracers.Tracer, error) {
+	t := &fourByteTracer{
+		ids: make(map[string]int),
+	}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnEnter: t.OnEnter,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// isPrecompiled returns whether the addr is a precompile.
+func (t *fourByteTracer) isPrecompiled(addr common.Address) bool {
+	for _, p := range vm.PrecompiledAddressesIstanbul {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// store saves the given identifier and datasize.
+func (t *fourByteTracer) store(id []byte, size int) {
+	key := common.Bytes2Hex(id) + "-" + strconv.Itoa(size)
+	t.ids["0x"+key]++
+}
+
+// OnEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *fourByteTracer) OnEnter(depth int, opcode byte, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	// Skip if tracing was interrupted
+	if t.interrupt.Load() {
+		return
+	}
+	if len(input) < 4 {
+		return
+	}
+	op := vm.OpCode(opcode)
+	// primarily we want to avoid CREATE/CREATE2/SELFDESTRUCT
+	if op != vm.DELEGATECALL && op != vm.STATICCALL &&
+		op != vm.CALL && op != vm.CALLCODE {
+		return
+	}
+	// Skip any pre-compile invocations, those are just fancy opcodes
+	if t.isPrecompiled(to) {
+		return
+	}
+	t.store(input[0:4], len(input)-4)
+}
+
+// GetResult returns the json-encoded nested list of call traces, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.ids)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *fourByteTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
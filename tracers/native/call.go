@@ -0,0 +1,221 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tracers"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("call", newCallTracer)
+}
+
+type callLog struct {
+	Address thor.Address   `json:"address"`
+	Topics  []thor.Bytes32 `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+	// Position of the log relative to subcalls within the same trace
+	Position hexutil.Uint `json:"position"`
+}
+
+type callFrame struct {
+	Type         string         `json:"type"`
+	From         thor.Address   `json:"from"`
+	Gas          hexutil.Uint64 `json:"gas"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	To           *thor.Address  `json:"to,omitempty"`
+	Input        hexutil.Bytes  `json:"input"`
+	Output       hexutil.Bytes  `json:"output,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	RevertReason string         `json:"revertReason,omitempty"`
+	Calls        []callFrame    `json:"calls,omitempty"`
+	Logs         []callLog      `json:"logs,omitempty"`
+	Value        *hexutil.Big   `json:"value,omitempty"`
+}
+
+func (f *callFrame) failed() bool {
+	return len(f.Error) > 0
+}
+
+func (f *callFrame) processOutput(output []byte, err error, reverted bool) {
+	output = copyBytes(output)
+	if err == nil {
+		f.Output = output
+		return
+	}
+	f.Error = err.Error()
+	if f.Type == vm.CREATE.String() || f.Type == vm.CREATE2.String() {
+		f.To = nil
+	}
+	if !reverted || len(output) == 0 {
+		return
+	}
+	f.Output = output
+	if unpacked, err := abi.UnpackRevert(output); err == nil {
+		f.RevertReason = unpacked
+	}
+}
+
+type callTracer struct {
+	callstack []callFrame
+	config    callTracerConfig
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+type callTracerConfig struct {
+	OnlyTopCall bool `json:"onlyTopCall"` // If true, call tracer won't collect any subcalls
+	WithLog     bool `json:"withLog"`     // If true, call tracer will collect event logs
+}
+
+// newCallTracer returns a native tracer which tracks
+// call frames of a clause, and implements tracing hooks.
+func newCallTracer(_ *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
+	var config callTracerConfig
+	if len(cfg) > 0 {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	// First callframe contains tx context info
+	// and is populated on start and end.
+	t := &callTracer{callstack: make([]callFrame, 0, 1), config: config}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnEnter: t.OnEnter,
+			OnExit:  t.OnExit,
+			OnLog:   t.OnLog,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// OnEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *callTracer) OnEnter(depth int, typ byte, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	// Skip if tracing was interrupted
+	if t.interrupt.Load() {
+		return
+	}
+	if depth > 0 && t.config.OnlyTopCall {
+		return
+	}
+
+	toCopy := thor.Address(to)
+	call := callFrame{
+		Type:  vm.OpCode(typ).String(),
+		From:  thor.Address(from),
+		To:    &toCopy,
+		Input: copyBytes(input),
+		Gas:   hexutil.Uint64(gas),
+	}
+	if value != nil {
+		call.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// OnExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *callTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if depth > 0 && t.config.OnlyTopCall {
+		return
+	}
+	size := len(t.callstack)
+	if size == 0 {
+		return
+	}
+
+	if depth == 0 {
+		t.callstack[0].GasUsed = hexutil.Uint64(gasUsed)
+		t.callstack[0].processOutput(output, err, reverted)
+		return
+	}
+	if size <= 1 {
+		return
+	}
+	// Pop call.
+	call := t.callstack[size-1]
+	t.callstack = t.callstack[:size-1]
+	size--
+
+	call.GasUsed = hexutil.Uint64(gasUsed)
+	call.processOutput(output, err, reverted)
+	// Nest call into parent.
+	t.callstack[size-1].Calls = append(t.callstack[size-1].Calls, call)
+}
+
+// OnLog is called when a log is emitted.
+func (t *callTracer) OnLog(log *types.Log) {
+	// Only logs need to be captured via opcode processing
+	if !t.config.WithLog {
+		return
+	}
+	// Avoid processing nested calls when only caring about top call
+	if t.config.OnlyTopCall && len(t.callstack) > 1 {
+		return
+	}
+	// Skip if tracing was interrupted
+	if t.interrupt.Load() || len(t.callstack) == 0 {
+		return
+	}
+	topics := make([]thor.Bytes32, len(log.Topics))
+	for i, topic := range log.Topics {
+		topics[i] = thor.Bytes32(topic)
+	}
+	l := callLog{
+		Address:  thor.Address(log.Address),
+		Topics:   topics,
+		Data:     copyBytes(log.Data),
+		Position: hexutil.Uint(len(t.callstack[len(t.callstack)-1].Calls)),
+	}
+	t.callstack[len(t.callstack)-1].Logs = append(t.callstack[len(t.callstack)-1].Logs, l)
+}
+
+// GetResult returns the json-encoded nested list of call traces, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if len(t.callstack) != 1 {
+		return nil, t.reason
+	}
+	if t.config.WithLog {
+		clearFailedLogs(&t.callstack[0], false)
+	}
+	res, err := json.Marshal(&t.callstack[0])
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *callTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// clearFailedLogs clears the logs of a callframe and all its children
+// in case of execution failure.
+func clearFailedLogs(cf *callFrame, parentFailed bool) {
+	failed := cf.failed() || parentFailed
+	if failed {
+		cf.Logs = nil
+	}
+	for i := range cf.Calls {
+		clearFailedLogs(&cf.Calls[i], failed)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package native
+
+import (
+	"encoding/json"
+
+	"github.com/ashkanabbasii/thor/tracers"
+	"github.com/ethereum/go-ethereum/core/tracing"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("noop", newNoopTracer)
+}
+
+// noopTracer is a go implementation of the tracing hooks that performs no
+// action. It's mostly useful for testing purposes.
+type noopTracer struct{}
+
+// newNoopTracer returns a new noop tracer.
+func newNoopTracer(_ *tracers.Context, _ json.RawMessage) (*tracers.Tracer, error) {
+	t := &noopTracer{}
+	return &tracers.Tracer{
+		Hooks:     &tracing.Hooks{},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// GetResult returns an empty json object.
+func (t *noopTracer) GetResult() (json.RawMessage, error) {
+	return json.RawMessage(`{}`), nil
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *noopTracer) Stop(err error) {
+}
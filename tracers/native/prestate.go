@@ -0,0 +1,154 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tracers"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("prestate", newPrestateTracer)
+}
+
+type account struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Energy  *hexutil.Big                `json:"energy"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// prestateTracer collects the accounts and storage slots touched by a
+// clause, with values as they were before the clause executed.
+type prestateTracer struct {
+	state     *state.State
+	blockTime uint64
+	pre       map[common.Address]*account
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newPrestateTracer returns a native tracer which collects the
+// pre-state of a clause, and implements tracing hooks.
+func newPrestateTracer(ctx *tracers.Context, _ json.RawMessage) (*tracers.Tracer, error) {
+	t := &prestateTracer{
+		state:     ctx.State,
+		blockTime: ctx.BlockTime,
+		pre:       make(map[common.Address]*account),
+	}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnEnter:  t.OnEnter,
+			OnOpcode: t.OnOpcode,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// OnEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+// It's called before any value transfer, so the accounts are still intact.
+func (t *prestateTracer) OnEnter(depth int, typ byte, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.lookupAccount(thor.Address(from))
+	t.lookupAccount(thor.Address(to))
+}
+
+// OnOpcode is called before executing each opcode, it looks up the accounts
+// and storage slots the opcode is going to touch.
+func (t *prestateTracer) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	if err != nil || t.interrupt.Load() {
+		return
+	}
+	op := vm.OpCode(opcode)
+	stackData := scope.StackData()
+	stackLen := len(stackData)
+	caller := thor.Address(scope.Address())
+	switch {
+	case stackLen >= 1 && (op == vm.SLOAD || op == vm.SSTORE):
+		t.lookupStorage(caller, thor.Bytes32(stackData[stackLen-1].Bytes32()))
+	case stackLen >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
+		t.lookupAccount(thor.Address(stackData[stackLen-1].Bytes20()))
+	case stackLen >= 5 && (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE):
+		t.lookupAccount(thor.Address(stackData[stackLen-2].Bytes20()))
+	}
+}
+
+// GetResult returns the json-encoded pre-state, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.pre)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// lookupAccount fetches details of an account and adds it to the prestate
+// if it doesn't exist there.
+func (t *prestateTracer) lookupAccount(addr thor.Address) {
+	if _, ok := t.pre[common.Address(addr)]; ok {
+		return
+	}
+
+	balance, err := t.state.GetBalance(addr)
+	if err != nil {
+		t.Stop(err)
+		return
+	}
+	energy, err := t.state.GetEnergy(addr, t.blockTime)
+	if err != nil {
+		t.Stop(err)
+		return
+	}
+	code, err := t.state.GetCode(addr)
+	if err != nil {
+		t.Stop(err)
+		return
+	}
+	t.pre[common.Address(addr)] = &account{
+		Balance: (*hexutil.Big)(balance),
+		Energy:  (*hexutil.Big)(energy),
+		Code:    code,
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage fetches the requested storage slot and adds
+// it to the prestate of the given contract.
+func (t *prestateTracer) lookupStorage(addr thor.Address, key thor.Bytes32) {
+	t.lookupAccount(addr)
+	acc, ok := t.pre[common.Address(addr)]
+	if !ok {
+		return
+	}
+	if _, ok := acc.Storage[common.Hash(key)]; ok {
+		return
+	}
+	value, err := t.state.GetStorage(addr, key)
+	if err != nil {
+		t.Stop(err)
+		return
+	}
+	acc.Storage[common.Hash(key)] = common.Hash(value)
+}
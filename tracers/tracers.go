@@ -0,0 +1,77 @@
+// Copyright (c) 2024 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+// Package tracers is a manager for clause tracing engines.
+package tracers
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/pkg/errors"
+)
+
+// DefaultName is the name of the tracer used when no name is given.
+const DefaultName = "structLogger"
+
+// Context contains some contextual infos for a clause execution that is not
+// available from within the EVM object.
+type Context struct {
+	BlockID     thor.Bytes32 // ID of the block the clause is contained within (zero if simulated call)
+	BlockTime   uint64       // Timestamp of the block, used to compute energy
+	TxIndex     int          // Index of the transaction within a block (zero if simulated call)
+	TxID        thor.Bytes32 // ID of the transaction being traced (zero if simulated call)
+	ClauseIndex int          // Index of the clause within the transaction
+	State       *state.State // State the clause is executed on
+}
+
+// Tracer wraps the vm hooks of a tracer and exposes its result.
+type Tracer struct {
+	*tracing.Hooks
+	// GetResult returns the JSON encoded result of the trace.
+	GetResult func() (json.RawMessage, error)
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop func(err error)
+}
+
+type ctorFn func(*Context, json.RawMessage) (*Tracer, error)
+
+type directory struct {
+	elems map[string]ctorFn
+}
+
+// DefaultDirectory is the collection of tracers bundled by default.
+var DefaultDirectory = directory{elems: make(map[string]ctorFn)}
+
+// Register registers a tracer constructor by name.
+func (d *directory) Register(name string, f ctorFn) {
+	d.elems[name] = f
+}
+
+// New returns a new instance of a tracer, by iterating through the
+// registered lookups. Name is the name of the tracer, empty name
+// stands for the default struct logger.
+func (d *directory) New(name string, ctx *Context, cfg json.RawMessage) (*Tracer, error) {
+	if name == "" {
+		name = DefaultName
+	}
+	if f, ok := d.elems[name]; ok {
+		return f(ctx, cfg)
+	}
+	return nil, errors.New("tracer not defined")
+}
+
+// Names returns the names of all registered tracers.
+func (d *directory) Names() []string {
+	names := make([]string, 0, len(d.elems))
+	for name := range d.elems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -117,6 +117,12 @@ func (e *ExtendedTrie) Get(key []byte) (val, meta []byte, err error) {
 	return nil, nil, nil
 }
 
+// Prove constructs a merkle proof for key, the encoded nodes on the path to key are
+// written into proofDb. See Trie.Prove.
+func (e *ExtendedTrie) Prove(key []byte, proofDb DatabaseWriter) error {
+	return e.trie.Prove(key, 0, proofDb)
+}
+
 // Update associates key with value and metadata in the trie. Subsequent calls to
 // Get will return value. If value has length zero, any existing value
 // is deleted from the trie and calls to Get will return nil.
@@ -0,0 +1,143 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package txpool
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+)
+
+// devNull is a WriteCloser that just discards anything written into it. Its
+// goal is to allow the journal to write into a fake journal when loading
+// transactions on startup without printing warnings due to no file being
+// ready for write.
+type devNull struct{}
+
+func (*devNull) Write(p []byte) (n int, err error) { return len(p), nil }
+func (*devNull) Close() error                      { return nil }
+
+// txJournal is a rotating log of transactions with the aim of storing pending
+// transactions on disk so that they can survive node restarts.
+// Each entry is the raw RLP encoded transaction.
+type txJournal struct {
+	path   string
+	lock   sync.Mutex
+	writer io.WriteCloser
+}
+
+func newTxJournal(path string) *txJournal {
+	return &txJournal{path: path}
+}
+
+// load parses the journal file and injects all transactions into the pool.
+// The journal is inactive while loading, so that the txs are not written back.
+func (j *txJournal) load(add func(tx *tx.Transaction) error) (total int, dropped int, err error) {
+	input, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// skip parsing if the journal file doesn't exist at all
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	defer input.Close()
+
+	j.lock.Lock()
+	j.writer = new(devNull)
+	j.lock.Unlock()
+	defer func() {
+		j.lock.Lock()
+		j.writer = nil
+		j.lock.Unlock()
+	}()
+
+	stream := rlp.NewStream(input, 0)
+	for {
+		var trx tx.Transaction
+		if err := stream.Decode(&trx); err != nil {
+			if err != io.EOF {
+				// a partially written entry is expected if the node crashed
+				return total, dropped, errors.Wrap(err, "decode journal")
+			}
+			return total, dropped, nil
+		}
+		total++
+		if err := add(&trx); err != nil {
+			logger.Debug("failed to add journaled tx", "id", trx.ID(), "err", err)
+			dropped++
+		}
+	}
+}
+
+// insert appends the tx to the journal.
+func (j *txJournal) insert(tx *tx.Transaction) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.writer == nil {
+		return errors.New("no active journal")
+	}
+	return rlp.Encode(j.writer, tx)
+}
+
+// rotate regenerates the journal with the given txs, which are the current content of the pool.
+func (j *txJournal) rotate(txs tx.Transactions) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	// close the current journal (if any is open)
+	if j.writer != nil {
+		if err := j.writer.Close(); err != nil {
+			return err
+		}
+		j.writer = nil
+	}
+
+	// generate a new journal with the contents of the current pool
+	replacement, err := os.OpenFile(j.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err := rlp.Encode(replacement, tx); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
+	if err := replacement.Close(); err != nil {
+		return err
+	}
+
+	// replace the live journal with the newly generated one
+	if err := os.Rename(j.path+".new", j.path); err != nil {
+		return err
+	}
+	sink, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	j.writer = sink
+	logger.Debug("regenerated tx journal", "txs", len(txs))
+	return nil
+}
+
+// close flushes the journal contents to disk and closes the file.
+func (j *txJournal) close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	var err error
+	if j.writer != nil {
+		err = j.writer.Close()
+		j.writer = nil
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package txpool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadAll(t *testing.T, journal *txJournal) tx.Transactions {
+	var txs tx.Transactions
+	_, _, err := journal.load(func(trx *tx.Transaction) error {
+		txs = append(txs, trx)
+		return nil
+	})
+	assert.Nil(t, err)
+	return txs
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "txs.rlp")
+	journal := newTxJournal(path)
+
+	tx1 := newTx(1, richKey, 0, 1, nil)
+	tx2 := newTx(1, richKey, 0, 2, nil)
+	tx3 := newTx(1, richKey, 0, 3, nil)
+
+	// missing journal file is not an error
+	assert.Empty(t, loadAll(t, journal))
+	// not active before rotated
+	assert.NotNil(t, journal.insert(tx1))
+
+	assert.Nil(t, journal.rotate(tx.Transactions{tx1}))
+	assert.Nil(t, journal.insert(tx2))
+	assert.Nil(t, journal.close())
+
+	txs := loadAll(t, journal)
+	assert.Equal(t, 2, len(txs))
+	assert.Equal(t, tx1.ID(), txs[0].ID())
+	assert.Equal(t, tx2.ID(), txs[1].ID())
+
+	// compacted
+	assert.Nil(t, journal.rotate(tx.Transactions{tx3}))
+	assert.Nil(t, journal.close())
+	txs = loadAll(t, journal)
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, tx3.ID(), txs[0].ID())
+	_, err := os.Stat(path + ".new")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestJournalPartialEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "txs.rlp")
+	journal := newTxJournal(path)
+
+	tx1 := newTx(1, richKey, 0, 1, nil)
+	assert.Nil(t, journal.rotate(tx.Transactions{tx1}))
+	assert.Nil(t, journal.close())
+
+	// simulate a crash in the middle of writing an entry
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = f.Write([]byte{0xf8, 0xff})
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	var txs tx.Transactions
+	total, dropped, err := journal.load(func(trx *tx.Transaction) error {
+		txs = append(txs, trx)
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, total)
+	assert.Zero(t, dropped)
+	assert.Equal(t, tx1.ID(), txs[0].ID())
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package txpool
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/runtime"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/pkg/errors"
+)
+
+type txObject struct {
+	*tx.Transaction
+	resolved *runtime.ResolvedTransaction
+
+	timeAdded       int64
+	localSubmitted  bool
+	executable      bool
+	overallGasPrice *big.Int // don't touch this value, it's only be used in pool's housekeeping
+	rankIndex       int      // index in the rank heap of txObjectMap
+}
+
+func resolveTx(tx *tx.Transaction, localSubmitted bool) (*txObject, error) {
+	resolved, err := runtime.ResolveTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &txObject{
+		Transaction:    tx,
+		resolved:       resolved,
+		timeAdded:      time.Now().UnixNano(),
+		localSubmitted: localSubmitted,
+	}, nil
+}
+
+func (o *txObject) Origin() thor.Address {
+	return o.resolved.Origin
+}
+
+func (o *txObject) Delegator() *thor.Address {
+	return o.resolved.Delegator
+}
+
+// Executable checks whether the tx can be packed into the block next to headBlock.
+// It returns error if the tx will never be executable.
+// A tx with dependency is not executable until the dependency is packed.
+func (o *txObject) Executable(chain *chain.Chain, state *state.State, headBlock *block.Header) (bool, error) {
+	switch {
+	case o.Gas() > headBlock.GasLimit():
+		return false, errors.New("gas too large")
+	case o.IsExpired(headBlock.Number() + 1): // Check tx expiration on top of next block
+		return false, errors.New("expired")
+	case o.BlockRef().Number() > headBlock.Number()+uint32(5*60/thor.BlockInterval):
+		// reject deferred tx which will be applied after 5mins
+		return false, errors.New("block ref out of schedule")
+	}
+
+	if has, err := chain.HasTransaction(o.ID(), o.BlockRef().Number()); err != nil {
+		return false, err
+	} else if has {
+		return false, errors.New("known tx")
+	}
+
+	if dep := o.DependsOn(); dep != nil {
+		txMeta, err := chain.GetTransactionMeta(*dep)
+		if err != nil {
+			if chain.IsNotFound(err) {
+				// the dependency is not packed yet
+				return false, nil
+			}
+			return false, err
+		}
+
+		if txMeta.Reverted {
+			return false, errors.New("dep reverted")
+		}
+	}
+
+	if o.BlockRef().Number() > headBlock.Number() {
+		return false, nil
+	}
+
+	checkpoint := state.NewCheckpoint()
+	defer state.RevertTo(checkpoint)
+
+	if _, _, _, _, err := o.resolved.BuyGas(state, headBlock.Timestamp()+thor.BlockInterval); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// rankedBelow returns whether the tx is less valuable to keep than the other one.
+// Non-executable txs are ranked below executable ones, then txs are ranked by overall gas price,
+// and the later added one is ranked below on a tie.
+func (o *txObject) rankedBelow(other *txObject) bool {
+	if o.executable != other.executable {
+		return !o.executable
+	}
+	switch {
+	case o.overallGasPrice == nil && other.overallGasPrice != nil:
+		return true
+	case o.overallGasPrice != nil && other.overallGasPrice == nil:
+		return false
+	case o.overallGasPrice != nil:
+		if c := o.overallGasPrice.Cmp(other.overallGasPrice); c != 0 {
+			return c < 0
+		}
+	}
+	return o.timeAdded > other.timeAdded
+}
+
+// sortTxObjsByOverallGasPriceDesc sort tx objects by overall gas price from high to low.
+func sortTxObjsByOverallGasPriceDesc(txObjs []*txObject) {
+	sort.Slice(txObjs, func(i, j int) bool {
+		gp1, gp2 := txObjs[i].overallGasPrice, txObjs[j].overallGasPrice
+		return gp1.Cmp(gp2) > 0
+	})
+}
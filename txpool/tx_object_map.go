@@ -0,0 +1,177 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package txpool
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/pkg/errors"
+)
+
+// txObjectMap to maintain mapping of tx hash to tx object, and account quota.
+// Tx objects are also kept in a heap with the lowest ranked one on top.
+type txObjectMap struct {
+	lock      sync.RWMutex
+	mapByHash map[thor.Bytes32]*txObject
+	mapByID   map[thor.Bytes32]*txObject
+	quota     map[thor.Address]int
+	ranked    rankHeap
+}
+
+func newTxObjectMap() *txObjectMap {
+	return &txObjectMap{
+		mapByHash: make(map[thor.Bytes32]*txObject),
+		mapByID:   make(map[thor.Bytes32]*txObject),
+		quota:     make(map[thor.Address]int),
+	}
+}
+
+func (m *txObjectMap) ContainsHash(txHash thor.Bytes32) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	_, found := m.mapByHash[txHash]
+	return found
+}
+
+// Add adds the tx object, the quota of both the origin and the delegator are consumed.
+// A non-positive limitPerAccount means unlimited.
+func (m *txObjectMap) Add(txObj *txObject, limitPerAccount int) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	hash := txObj.Hash()
+	if _, found := m.mapByHash[hash]; found {
+		return nil
+	}
+
+	exceeded := func(addr thor.Address) bool {
+		return limitPerAccount > 0 && m.quota[addr] >= limitPerAccount
+	}
+
+	if exceeded(txObj.Origin()) {
+		return errors.New("account quota exceeded")
+	}
+
+	delegator := txObj.Delegator()
+	if delegator != nil {
+		if exceeded(*delegator) {
+			return errors.New("delegator quota exceeded")
+		}
+		m.quota[*delegator]++
+	}
+
+	m.quota[txObj.Origin()]++
+	m.mapByHash[hash] = txObj
+	m.mapByID[txObj.ID()] = txObj
+	heap.Push(&m.ranked, txObj)
+	return nil
+}
+
+func (m *txObjectMap) GetByID(id thor.Bytes32) *txObject {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.mapByID[id]
+}
+
+func (m *txObjectMap) RemoveByHash(txHash thor.Bytes32) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if txObj, ok := m.mapByHash[txHash]; ok {
+		m.releaseQuota(txObj.Origin())
+		if delegator := txObj.Delegator(); delegator != nil {
+			m.releaseQuota(*delegator)
+		}
+		delete(m.mapByHash, txHash)
+		delete(m.mapByID, txObj.ID())
+		heap.Remove(&m.ranked, txObj.rankIndex)
+		return true
+	}
+	return false
+}
+
+func (m *txObjectMap) releaseQuota(addr thor.Address) {
+	if m.quota[addr] > 1 {
+		m.quota[addr]--
+	} else {
+		delete(m.quota, addr)
+	}
+}
+
+// Lowest returns the tx object with the lowest rank. See txObject.rankedBelow.
+func (m *txObjectMap) Lowest() *txObject {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if len(m.ranked) == 0 {
+		return nil
+	}
+	return m.ranked[0]
+}
+
+// Rerank restores the order of the rank heap, it should be called once ranks of tx objects are changed.
+func (m *txObjectMap) Rerank() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	heap.Init(&m.ranked)
+}
+
+func (m *txObjectMap) ToTxObjects() []*txObject {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	txObjs := make([]*txObject, 0, len(m.mapByHash))
+	for _, txObj := range m.mapByHash {
+		txObjs = append(txObjs, txObj)
+	}
+	return txObjs
+}
+
+func (m *txObjectMap) ToTxs() tx.Transactions {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	txs := make(tx.Transactions, 0, len(m.mapByHash))
+	for _, txObj := range m.mapByHash {
+		txs = append(txs, txObj.Transaction)
+	}
+	return txs
+}
+
+func (m *txObjectMap) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return len(m.mapByHash)
+}
+
+// rankHeap implements heap.Interface, the lowest ranked tx object is on top.
+type rankHeap []*txObject
+
+func (h rankHeap) Len() int           { return len(h) }
+func (h rankHeap) Less(i, j int) bool { return h[i].rankedBelow(h[j]) }
+func (h rankHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].rankIndex = i
+	h[j].rankIndex = j
+}
+
+func (h *rankHeap) Push(x interface{}) {
+	txObj := x.(*txObject)
+	txObj.rankIndex = len(*h)
+	*h = append(*h, txObj)
+}
+
+func (h *rankHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	txObj := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return txObj
+}
@@ -0,0 +1,514 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package txpool
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/co"
+	"github.com/ashkanabbasii/thor/log"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/pkg/errors"
+)
+
+const (
+	// max size of tx allowed
+	maxTxSize = 64 * 1024
+	// default interval to regenerate the journal
+	defaultRejournal = time.Hour
+)
+
+var logger = log.WithContext("pkg", "txpool")
+
+// Options options for tx pool.
+type Options struct {
+	Limit           int
+	LimitPerAccount int // max txs per origin or delegator, unlimited if zero
+	MaxLifetime     time.Duration
+	Journal         string        // path of the journal file to persist txs across restarts, disabled if empty
+	Rejournal       time.Duration // interval to regenerate the journal
+}
+
+// TxEvent will be posted when tx is added or status changed.
+type TxEvent struct {
+	Tx         *tx.Transaction
+	Executable *bool
+}
+
+// TxPool maintains unprocessed transactions.
+type TxPool struct {
+	options Options
+	repo    *chain.Repository
+	stater  *state.Stater
+
+	executables    atomic.Value
+	all            *txObjectMap
+	addedAfterWash uint32
+	journal        *txJournal
+
+	ctx    context.Context
+	cancel func()
+	txFeed event.Feed
+	scope  event.SubscriptionScope
+	goes   co.Goes
+}
+
+// New create a new TxPool instance.
+// Shutdown is required to be called at end.
+func New(repo *chain.Repository, stater *state.Stater, options Options) *TxPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &TxPool{
+		options: options,
+		repo:    repo,
+		stater:  stater,
+		all:     newTxObjectMap(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	if options.Journal != "" {
+		pool.journal = newTxJournal(options.Journal)
+		pool.loadJournal()
+	}
+
+	pool.goes.Go(pool.housekeeping)
+	return pool
+}
+
+// loadJournal replays txs in the journal, and then compacts the journal with txs accepted.
+// Txs are revalidated as if they were newly received, and expired ones are dropped.
+func (p *TxPool) loadJournal() {
+	headBlock := p.repo.BestBlockSummary().Header
+	total, dropped, err := p.journal.load(func(trx *tx.Transaction) error {
+		if trx.IsExpired(headBlock.Number() + 1) {
+			return errors.New("expired")
+		}
+		return p.add(trx, false, false)
+	})
+	if err != nil {
+		logger.Warn("failed to load tx journal", "err", err)
+	}
+	logger.Info("loaded tx journal", "total", total, "dropped", dropped)
+
+	if err := p.journal.rotate(p.all.ToTxs()); err != nil {
+		logger.Warn("failed to rotate tx journal", "err", err)
+	}
+}
+
+func (p *TxPool) housekeeping() {
+	logger.Debug("enter housekeeping")
+	defer logger.Debug("leave housekeeping")
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var rejournal <-chan time.Time
+	if p.journal != nil {
+		interval := p.options.Rejournal
+		if interval <= 0 {
+			interval = defaultRejournal
+		}
+		rejournalTicker := time.NewTicker(interval)
+		defer rejournalTicker.Stop()
+		rejournal = rejournalTicker.C
+	}
+
+	headSummary := p.repo.BestBlockSummary()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-rejournal:
+			if err := p.journal.rotate(p.all.ToTxs()); err != nil {
+				logger.Warn("failed to rotate tx journal", "err", err)
+			}
+		case <-ticker.C:
+			var headBlockChanged bool
+			if newHeadSummary := p.repo.BestBlockSummary(); newHeadSummary.Header.ID() != headSummary.Header.ID() {
+				if newHeadSummary.Header.ParentID() != headSummary.Header.ID() {
+					p.reinject(headSummary, newHeadSummary)
+				}
+				headSummary = newHeadSummary
+				headBlockChanged = true
+			}
+			if !isChainSynced(uint64(time.Now().Unix()), headSummary.Header.Timestamp()) {
+				// skip washing txs if not synced
+				continue
+			}
+			poolLen := p.all.Len()
+			// do wash on
+			// 1. head block changed
+			// 2. pool size exceeds limit
+			// 3. new tx added while pool size is small
+			if headBlockChanged ||
+				poolLen > p.options.Limit ||
+				(poolLen < 200 && atomic.LoadUint32(&p.addedAfterWash) > 0) {
+				atomic.StoreUint32(&p.addedAfterWash, 0)
+
+				startTime := mclock.Now()
+				executables, removed, err := p.wash(headSummary)
+				elapsed := mclock.Now() - startTime
+
+				ctx := []interface{}{
+					"len", poolLen,
+					"removed", removed,
+					"elapsed", common.PrettyDuration(elapsed),
+				}
+				if err != nil {
+					ctx = append(ctx, "err", err)
+				} else {
+					p.executables.Store(executables)
+				}
+
+				logger.Debug("wash done", ctx...)
+			}
+		}
+	}
+}
+
+// Close cleanup inner go routines.
+func (p *TxPool) Close() {
+	p.cancel()
+	p.scope.Close()
+	p.goes.Wait()
+	if p.journal != nil {
+		if err := p.journal.close(); err != nil {
+			logger.Warn("failed to close tx journal", "err", err)
+		}
+	}
+	logger.Debug("closed")
+}
+
+// SubscribeTxEvent receivers will receive a tx.
+func (p *TxPool) SubscribeTxEvent(ch chan *TxEvent) event.Subscription {
+	return p.scope.Track(p.txFeed.Subscribe(ch))
+}
+
+// reinject adds back txs of blocks, which are dropped from the trunk due to the switch of head block.
+func (p *TxPool) reinject(oldHead, newHead *chain.BlockSummary) {
+	oldChain := p.repo.NewChain(oldHead.Header.ID())
+	ids, err := oldChain.Exclude(p.repo.NewChain(newHead.Header.ID()))
+	if err != nil {
+		logger.Warn("failed to find dropped blocks", "err", err)
+		return
+	}
+	for _, id := range ids {
+		txs, err := p.repo.GetBlockTransactions(id)
+		if err != nil {
+			logger.Warn("failed to get txs of dropped block", "id", id, "err", err)
+			return
+		}
+		for _, tx := range txs {
+			if err := p.add(tx, false, false); err != nil {
+				logger.Debug("failed to reinject tx", "id", tx.ID(), "err", err)
+			}
+		}
+	}
+}
+
+// validateTxBasics runs the stateless checks, which don't rely on the chain status.
+func (p *TxPool) validateTxBasics(trx *tx.Transaction, headSummary *chain.BlockSummary) (*txObject, error) {
+	switch {
+	case trx.ChainTag() != p.repo.ChainTag():
+		return nil, badTxError{"chain tag mismatch"}
+	case trx.Size() > maxTxSize:
+		return nil, txRejectedError{"size too large"}
+	}
+	if err := trx.TestFeatures(headSummary.Header.TxsFeatures()); err != nil {
+		return nil, txRejectedError{err.Error()}
+	}
+	// signature and intrinsic gas are checked while resolving
+	return resolveTx(trx, false)
+}
+
+func (p *TxPool) add(newTx *tx.Transaction, rejectNonExecutable bool, localSubmitted bool) error {
+	if p.all.ContainsHash(newTx.Hash()) {
+		// tx already in the pool
+		return nil
+	}
+
+	headSummary := p.repo.BestBlockSummary()
+
+	txObj, err := p.validateTxBasics(newTx, headSummary)
+	if err != nil {
+		if IsBadTx(err) || IsTxRejected(err) {
+			return err
+		}
+		return badTxError{err.Error()}
+	}
+	txObj.localSubmitted = localSubmitted
+
+	if thor.IsOriginBlocked(txObj.Origin()) {
+		// tx origin blocked
+		return nil
+	}
+
+	if isChainSynced(uint64(time.Now().Unix()), headSummary.Header.Timestamp()) {
+		var (
+			state = p.stater.NewState(headSummary.Header.StateRoot(), headSummary.Header.Number(), headSummary.Conflicts, headSummary.SteadyNum)
+			chain = p.repo.NewChain(headSummary.Header.ID())
+		)
+		executable, err := txObj.Executable(chain, state, headSummary.Header)
+		if err != nil {
+			return txRejectedError{err.Error()}
+		}
+
+		if rejectNonExecutable && !executable {
+			return txRejectedError{"tx is not executable"}
+		}
+
+		baseGasPrice, err := getBaseGasPrice(state)
+		if err != nil {
+			return err
+		}
+		provedWork, err := txObj.ProvedWork(headSummary.Header.Number(), chain.GetBlockID)
+		if err != nil {
+			return err
+		}
+		txObj.overallGasPrice = txObj.OverallGasPrice(baseGasPrice, provedWork)
+		txObj.executable = executable
+
+		if err := p.all.Add(txObj, p.options.LimitPerAccount); err != nil {
+			return txRejectedError{err.Error()}
+		}
+		// evict the lowest ranked tx when the pool is full, which may be the new one
+		if p.all.Len() > p.options.Limit {
+			if lowest := p.all.Lowest(); lowest != nil {
+				p.all.RemoveByHash(lowest.Hash())
+				if lowest == txObj {
+					return txRejectedError{"pool is full"}
+				}
+				logger.Debug("tx evicted due to pool limit", "id", lowest.ID())
+			}
+		}
+
+		p.goes.Go(func() {
+			p.txFeed.Send(&TxEvent{newTx, &executable})
+		})
+		logger.Debug("tx added", "id", newTx.ID(), "executable", executable)
+	} else {
+		// we skip steps that rely on head block when chain is not synced,
+		// but check the pool's limit
+		if p.all.Len() >= p.options.Limit {
+			return txRejectedError{"pool is full"}
+		}
+		if err := p.all.Add(txObj, p.options.LimitPerAccount); err != nil {
+			return txRejectedError{err.Error()}
+		}
+		logger.Debug("tx added", "id", newTx.ID())
+		p.goes.Go(func() {
+			p.txFeed.Send(&TxEvent{newTx, nil})
+		})
+	}
+	if p.journal != nil {
+		if err := p.journal.insert(newTx); err != nil {
+			logger.Warn("failed to journal tx", "id", newTx.ID(), "err", err)
+		}
+	}
+	atomic.AddUint32(&p.addedAfterWash, 1)
+	return nil
+}
+
+// Add adds a new tx into pool.
+// It's not assumed as an error if the tx to be added is already in the pool.
+func (p *TxPool) Add(newTx *tx.Transaction) error {
+	return p.add(newTx, false, false)
+}
+
+// AddLocal adds new locally submitted tx into pool.
+func (p *TxPool) AddLocal(newTx *tx.Transaction) error {
+	return p.add(newTx, false, true)
+}
+
+// Get get pooled tx by id.
+func (p *TxPool) Get(id thor.Bytes32) *tx.Transaction {
+	if txObj := p.all.GetByID(id); txObj != nil {
+		return txObj.Transaction
+	}
+	return nil
+}
+
+// StrictlyAdd adds a new tx into pool. A rejection error will be returned, if tx is not executable at this time.
+func (p *TxPool) StrictlyAdd(newTx *tx.Transaction) error {
+	return p.add(newTx, true, false)
+}
+
+// Remove removes tx from pool by its Hash.
+func (p *TxPool) Remove(txHash thor.Bytes32, txID thor.Bytes32) bool {
+	if p.all.RemoveByHash(txHash) {
+		logger.Debug("tx removed", "id", txID)
+		return true
+	}
+	return false
+}
+
+// Executables returns executable txs, sorted by overall gas price from high to low.
+// A tx with dependency is released only after the dependency is packed.
+func (p *TxPool) Executables() tx.Transactions {
+	if sorted, ok := p.executables.Load().(tx.Transactions); ok {
+		return sorted
+	}
+	return nil
+}
+
+// Dump dumps all txs in the pool.
+func (p *TxPool) Dump() tx.Transactions {
+	return p.all.ToTxs()
+}
+
+// Len returns the count of txs in the pool.
+func (p *TxPool) Len() int {
+	return p.all.Len()
+}
+
+// wash to evict txs that are over limit, out of lifetime, out of energy, settled, expired or dep failed.
+func (p *TxPool) wash(headSummary *chain.BlockSummary) (executables tx.Transactions, removed int, err error) {
+	all := p.all.ToTxObjects()
+	var toRemove []*txObject
+	defer func() {
+		if err != nil {
+			// in case of error, simply cut pool size to limit
+			for i, txObj := range all {
+				if len(all)-i <= p.options.Limit {
+					break
+				}
+				removed++
+				p.all.RemoveByHash(txObj.Hash())
+			}
+		} else {
+			for _, txObj := range toRemove {
+				p.all.RemoveByHash(txObj.Hash())
+			}
+			removed = len(toRemove)
+		}
+		// ranks are updated by washing
+		p.all.Rerank()
+	}()
+
+	// recreate state every time to avoid high RAM usage when the pool at hight water-mark.
+	newState := func() *state.State {
+		return p.stater.NewState(headSummary.Header.StateRoot(), headSummary.Header.Number(), headSummary.Conflicts, headSummary.SteadyNum)
+	}
+	baseGasPrice, err := getBaseGasPrice(newState())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		chain             = p.repo.NewChain(headSummary.Header.ID())
+		executableObjs    = make([]*txObject, 0, len(all))
+		nonExecutableObjs = make([]*txObject, 0, len(all))
+		now               = time.Now().UnixNano()
+	)
+	for _, txObj := range all {
+		if thor.IsOriginBlocked(txObj.Origin()) {
+			toRemove = append(toRemove, txObj)
+			logger.Debug("tx washed out", "id", txObj.ID(), "err", "blocked")
+			continue
+		}
+
+		// out of lifetime
+		if !txObj.localSubmitted && now > txObj.timeAdded+int64(p.options.MaxLifetime) {
+			toRemove = append(toRemove, txObj)
+			logger.Debug("tx washed out", "id", txObj.ID(), "err", "out of lifetime")
+			continue
+		}
+		// settled, out of energy or dep reverted
+		executable, err := txObj.Executable(chain, newState(), headSummary.Header)
+		if err != nil {
+			toRemove = append(toRemove, txObj)
+			logger.Debug("tx washed out", "id", txObj.ID(), "err", err)
+			continue
+		}
+
+		if executable {
+			provedWork, err := txObj.ProvedWork(headSummary.Header.Number(), chain.GetBlockID)
+			if err != nil {
+				toRemove = append(toRemove, txObj)
+				logger.Debug("tx washed out", "id", txObj.ID(), "err", err)
+				continue
+			}
+			txObj.overallGasPrice = txObj.OverallGasPrice(baseGasPrice, provedWork)
+			executableObjs = append(executableObjs, txObj)
+		} else {
+			nonExecutableObjs = append(nonExecutableObjs, txObj)
+		}
+	}
+
+	// sort objs by price from high to low
+	sortTxObjsByOverallGasPriceDesc(executableObjs)
+
+	limit := p.options.Limit
+
+	// remove over limit txs, from non-executables to low priced
+	if len(executableObjs) > limit {
+		for _, txObj := range nonExecutableObjs {
+			toRemove = append(toRemove, txObj)
+			logger.Debug("non-executable tx washed out due to pool limit", "id", txObj.ID())
+		}
+		for _, txObj := range executableObjs[limit:] {
+			toRemove = append(toRemove, txObj)
+			logger.Debug("executable tx washed out due to pool limit", "id", txObj.ID())
+		}
+		executableObjs = executableObjs[:limit]
+	} else if len(executableObjs)+len(nonExecutableObjs) > limit {
+		// executableObjs + nonExecutableObjs over pool limit
+		for _, txObj := range nonExecutableObjs[limit-len(executableObjs):] {
+			toRemove = append(toRemove, txObj)
+			logger.Debug("non-executable tx washed out due to pool limit", "id", txObj.ID())
+		}
+	}
+
+	executables = make(tx.Transactions, 0, len(executableObjs))
+	var toBroadcast tx.Transactions
+
+	for _, obj := range executableObjs {
+		executables = append(executables, obj.Transaction)
+		if !obj.executable {
+			obj.executable = true
+			toBroadcast = append(toBroadcast, obj.Transaction)
+		}
+	}
+
+	p.goes.Go(func() {
+		for _, tx := range toBroadcast {
+			executable := true
+			p.txFeed.Send(&TxEvent{tx, &executable})
+		}
+	})
+	return executables, 0, nil
+}
+
+// getBaseGasPrice returns the base gas price, the initial value is used if the param is not set.
+func getBaseGasPrice(state *state.State) (*big.Int, error) {
+	baseGasPrice, err := builtin.Params.Native(state).Get(thor.KeyBaseGasPrice)
+	if err != nil {
+		return nil, err
+	}
+	if baseGasPrice.Sign() == 0 {
+		return thor.InitialBaseGasPrice, nil
+	}
+	return baseGasPrice, nil
+}
+
+func isChainSynced(nowTimestamp, blockTimestamp uint64) bool {
+	timeDiff := nowTimestamp - blockTimestamp
+	if blockTimestamp > nowTimestamp {
+		timeDiff = blockTimestamp - nowTimestamp
+	}
+	return timeDiff < thor.BlockInterval*6
+}
@@ -0,0 +1,340 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package txpool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ashkanabbasii/thor/block"
+	"github.com/ashkanabbasii/thor/builtin"
+	"github.com/ashkanabbasii/thor/chain"
+	"github.com/ashkanabbasii/thor/muxdb"
+	"github.com/ashkanabbasii/thor/state"
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	richKey, _ = crypto.GenerateKey()
+	poorKey, _ = crypto.GenerateKey()
+)
+
+func keyAddr(key *ecdsa.PrivateKey) thor.Address {
+	return thor.Address(crypto.PubkeyToAddress(key.PublicKey))
+}
+
+func newTestPool(t *testing.T, options Options) (*TxPool, *chain.Repository) {
+	db := muxdb.NewMem()
+	stater := state.NewStater(db)
+
+	now := uint64(time.Now().Unix())
+	st := stater.NewState(thor.Bytes32{}, 0, 0, 0)
+	st.SetEnergy(keyAddr(richKey), new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6)), now)
+	// the storage of an empty account is not committed
+	st.SetCode(builtin.Params.Address, builtin.Params.RuntimeBytecodes())
+	builtin.Params.Native(st).Set(thor.KeyBaseGasPrice, thor.InitialBaseGasPrice)
+	stage, err := st.Stage(0, 0)
+	assert.Nil(t, err)
+	root, err := stage.Commit()
+	assert.Nil(t, err)
+
+	genesis := new(block.Builder).
+		ParentID(thor.Bytes32{0xff, 0xff, 0xff, 0xff}).
+		Timestamp(now).
+		GasLimit(thor.InitialGasLimit).
+		StateRoot(root).
+		ReceiptsRoot(tx.Receipts(nil).RootHash()).
+		Build()
+	repo, err := chain.NewRepository(db, genesis)
+	assert.Nil(t, err)
+
+	pool := New(repo, stater, options)
+	t.Cleanup(pool.Close)
+	return pool, repo
+}
+
+func newTx(chainTag byte, key *ecdsa.PrivateKey, gasPriceCoef uint8, nonce uint64, dependsOn *thor.Bytes32) *tx.Transaction {
+	to := thor.BytesToAddress([]byte("to"))
+	return tx.MustSign(new(tx.Builder).
+		ChainTag(chainTag).
+		Clause(tx.NewClause(&to)).
+		GasPriceCoef(gasPriceCoef).
+		Gas(21000).
+		Expiration(100).
+		Nonce(nonce).
+		DependsOn(dependsOn).
+		Build(), key)
+}
+
+func TestAddAndExecutables(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 10, MaxLifetime: time.Hour})
+
+	low := newTx(repo.ChainTag(), richKey, 0, 1, nil)
+	high := newTx(repo.ChainTag(), richKey, 255, 2, nil)
+	assert.Nil(t, pool.Add(low))
+	assert.Nil(t, pool.AddLocal(high))
+	// add again is not an error
+	assert.Nil(t, pool.Add(low))
+	assert.Equal(t, 2, pool.Len())
+	assert.Equal(t, high, pool.Get(high.ID()))
+
+	executables, removed, err := pool.wash(repo.BestBlockSummary())
+	assert.Nil(t, err)
+	assert.Zero(t, removed)
+	assert.Equal(t, tx.Transactions{high, low}, executables)
+
+	assert.True(t, pool.Remove(high.Hash(), high.ID()))
+	assert.False(t, pool.Remove(high.Hash(), high.ID()))
+	assert.Nil(t, pool.Get(high.ID()))
+	assert.Equal(t, tx.Transactions{low}, pool.Dump())
+}
+
+func TestStatelessValidation(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 10, MaxLifetime: time.Hour})
+	to := thor.BytesToAddress([]byte("to"))
+
+	err := pool.Add(newTx(repo.ChainTag()+1, richKey, 0, 1, nil))
+	assert.True(t, IsBadTx(err), err)
+
+	// unsigned
+	err = pool.Add(new(tx.Builder).ChainTag(repo.ChainTag()).Clause(tx.NewClause(&to)).Gas(21000).Expiration(100).Build())
+	assert.True(t, IsBadTx(err), err)
+
+	// intrinsic gas not covered
+	err = pool.Add(tx.MustSign(new(tx.Builder).ChainTag(repo.ChainTag()).Clause(tx.NewClause(&to)).Gas(20000).Expiration(100).Build(), richKey))
+	assert.True(t, IsBadTx(err), err)
+
+	// delegation is not supported by the head block
+	var features tx.Features
+	features.SetDelegated(true)
+	err = pool.Add(tx.MustSignDelegated(new(tx.Builder).ChainTag(repo.ChainTag()).Clause(tx.NewClause(&to)).Gas(21000).Expiration(100).Features(features).Build(), richKey, poorKey))
+	assert.True(t, IsTxRejected(err), err)
+
+	// too large
+	err = pool.Add(tx.MustSign(new(tx.Builder).ChainTag(repo.ChainTag()).Clause(tx.NewClause(&to).WithData(make([]byte, maxTxSize))).Gas(10_000_000).Expiration(100).Build(), richKey))
+	assert.True(t, IsTxRejected(err), err)
+
+	// blocked origin is dropped silently
+	thor.MockBlocklist([]string{keyAddr(poorKey).String()})
+	defer thor.MockBlocklist(nil)
+	assert.Nil(t, pool.Add(newTx(repo.ChainTag(), poorKey, 0, 1, nil)))
+
+	assert.Zero(t, pool.Len())
+}
+
+func TestStatefulValidation(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 10, MaxLifetime: time.Hour})
+	to := thor.BytesToAddress([]byte("to"))
+
+	// no energy to pay
+	err := pool.Add(newTx(repo.ChainTag(), poorKey, 0, 1, nil))
+	assert.True(t, IsTxRejected(err), err)
+
+	// gas exceeds block gas limit
+	err = pool.Add(tx.MustSign(new(tx.Builder).ChainTag(repo.ChainTag()).Clause(tx.NewClause(&to)).Gas(thor.InitialGasLimit+1).Expiration(100).Build(), richKey))
+	assert.True(t, IsTxRejected(err), err)
+
+	// block ref too far in future
+	err = pool.Add(tx.MustSign(new(tx.Builder).ChainTag(repo.ChainTag()).Clause(tx.NewClause(&to)).Gas(21000).BlockRef(tx.NewBlockRef(1000)).Expiration(100).Build(), richKey))
+	assert.True(t, IsTxRejected(err), err)
+
+	// depends on a tx not packed yet
+	dep := thor.BytesToBytes32([]byte("dep"))
+	trx := newTx(repo.ChainTag(), richKey, 0, 1, &dep)
+	assert.Nil(t, pool.Add(trx))
+	err = pool.StrictlyAdd(newTx(repo.ChainTag(), richKey, 0, 2, &dep))
+	assert.True(t, IsTxRejected(err), err)
+
+	executables, _, err := pool.wash(repo.BestBlockSummary())
+	assert.Nil(t, err)
+	assert.Empty(t, executables)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestPoolLimit(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 2, MaxLifetime: time.Hour})
+
+	assert.Nil(t, pool.Add(newTx(repo.ChainTag(), richKey, 0, 1, nil)))
+	assert.Nil(t, pool.Add(newTx(repo.ChainTag(), richKey, 0, 2, nil)))
+	err := pool.Add(newTx(repo.ChainTag(), richKey, 0, 3, nil))
+	assert.True(t, IsTxRejected(err), err)
+}
+
+func TestAccountQuota(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 10, LimitPerAccount: 1, MaxLifetime: time.Hour})
+
+	first := newTx(repo.ChainTag(), richKey, 0, 1, nil)
+	assert.Nil(t, pool.Add(first))
+	err := pool.Add(newTx(repo.ChainTag(), richKey, 0, 2, nil))
+	assert.True(t, IsTxRejected(err), err)
+
+	// quota is released on removal
+	assert.True(t, pool.Remove(first.Hash(), first.ID()))
+	assert.Nil(t, pool.Add(newTx(repo.ChainTag(), richKey, 0, 2, nil)))
+}
+
+func TestEvictLowestPriced(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 2, LimitPerAccount: 10, MaxLifetime: time.Hour})
+
+	low := newTx(repo.ChainTag(), richKey, 0, 1, nil)
+	mid := newTx(repo.ChainTag(), richKey, 100, 2, nil)
+	high := newTx(repo.ChainTag(), richKey, 255, 3, nil)
+	assert.Nil(t, pool.Add(low))
+	assert.Nil(t, pool.Add(mid))
+	assert.Nil(t, pool.Add(high))
+
+	assert.Equal(t, 2, pool.Len())
+	assert.Nil(t, pool.Get(low.ID()))
+	assert.NotNil(t, pool.Get(mid.ID()))
+	assert.NotNil(t, pool.Get(high.ID()))
+
+	// lower than all in the pool
+	err := pool.Add(newTx(repo.ChainTag(), richKey, 0, 4, nil))
+	assert.True(t, IsTxRejected(err), err)
+	assert.Equal(t, 2, pool.Len())
+}
+
+func TestDependencyReleasedAfterPacked(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 10, LimitPerAccount: 10, MaxLifetime: time.Hour})
+	genesis := repo.GenesisBlock().Header()
+
+	dep := newTx(repo.ChainTag(), richKey, 0, 1, nil)
+	depID := dep.ID()
+	dependent := newTx(repo.ChainTag(), richKey, 255, 2, &depID)
+	other := newTx(repo.ChainTag(), richKey, 100, 3, nil)
+
+	assert.Nil(t, pool.Add(dependent))
+	assert.Nil(t, pool.Add(dep))
+	assert.Nil(t, pool.Add(other))
+
+	// the dependent tx has the highest price, but is held until its dependency is packed
+	executables, removed, err := pool.wash(repo.BestBlockSummary())
+	assert.Nil(t, err)
+	assert.Zero(t, removed)
+	assert.Equal(t, tx.Transactions{other, dep}, executables)
+
+	blk := new(block.Builder).
+		ParentID(genesis.ID()).
+		Timestamp(genesis.Timestamp() + thor.BlockInterval).
+		GasLimit(genesis.GasLimit()).
+		StateRoot(genesis.StateRoot()).
+		Transaction(dep).
+		ReceiptsRoot(tx.Receipts{&tx.Receipt{}}.RootHash()).
+		Build()
+	sig, err := crypto.Sign(blk.Header().SigningHash().Bytes(), richKey)
+	assert.Nil(t, err)
+	blk = blk.WithSignature(sig)
+	assert.Nil(t, repo.AddBlock(blk, tx.Receipts{&tx.Receipt{}}, 0))
+	assert.Nil(t, repo.SetBestBlockID(blk.Header().ID()))
+
+	executables, removed, err = pool.wash(repo.BestBlockSummary())
+	assert.Nil(t, err)
+	// the packed dependency is washed out
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, tx.Transactions{dependent, other}, executables)
+}
+
+func TestReinject(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 10, LimitPerAccount: 10, MaxLifetime: time.Hour})
+	genesis := repo.GenesisBlock().Header()
+
+	trx := newTx(repo.ChainTag(), richKey, 0, 1, nil)
+	newBlock := func(timestamp uint64, txs ...*tx.Transaction) *block.Block {
+		builder := new(block.Builder).
+			ParentID(genesis.ID()).
+			Timestamp(timestamp).
+			GasLimit(genesis.GasLimit()).
+			StateRoot(genesis.StateRoot())
+		receipts := make(tx.Receipts, 0, len(txs))
+		for _, trx := range txs {
+			builder.Transaction(trx)
+			receipts = append(receipts, &tx.Receipt{})
+		}
+		blk := builder.ReceiptsRoot(receipts.RootHash()).Build()
+		sig, err := crypto.Sign(blk.Header().SigningHash().Bytes(), richKey)
+		assert.Nil(t, err)
+		blk = blk.WithSignature(sig)
+		assert.Nil(t, repo.AddBlock(blk, receipts, 0))
+		return blk
+	}
+
+	b1a := newBlock(genesis.Timestamp()+thor.BlockInterval, trx)
+	b1b := newBlock(genesis.Timestamp() + thor.BlockInterval*2)
+
+	oldHead, err := repo.GetBlockSummary(b1a.Header().ID())
+	assert.Nil(t, err)
+	newHead, err := repo.GetBlockSummary(b1b.Header().ID())
+	assert.Nil(t, err)
+	assert.Nil(t, repo.SetBestBlockID(b1b.Header().ID()))
+
+	pool.reinject(oldHead, newHead)
+	assert.Equal(t, trx, pool.Get(trx.ID()))
+}
+
+func TestJournalReplay(t *testing.T) {
+	options := Options{
+		Limit:           10,
+		LimitPerAccount: 10,
+		MaxLifetime:     time.Hour,
+		Journal:         filepath.Join(t.TempDir(), "txs.rlp"),
+	}
+	pool, repo := newTestPool(t, options)
+
+	trx := newTx(repo.ChainTag(), richKey, 0, 1, nil)
+	assert.Nil(t, pool.Add(trx))
+	pool.Close()
+
+	// accepted tx is journaled
+	txs := loadAll(t, newTxJournal(options.Journal))
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, trx.ID(), txs[0].ID())
+
+	// append an expired tx to the journal
+	expired := tx.MustSign(new(tx.Builder).
+		ChainTag(repo.ChainTag()).
+		Clause(tx.NewClause(&thor.Address{})).
+		Gas(21000).
+		Expiration(0).
+		Nonce(2).
+		Build(), richKey)
+	journal := newTxJournal(options.Journal)
+	assert.Nil(t, journal.rotate(tx.Transactions{trx, expired}))
+	assert.Nil(t, journal.close())
+
+	pool = New(repo, pool.stater, options)
+	defer pool.Close()
+
+	assert.Equal(t, 1, pool.Len())
+	assert.Equal(t, trx.ID(), pool.Get(trx.ID()).ID())
+	assert.Nil(t, pool.Get(expired.ID()))
+
+	// journal is compacted
+	txs = loadAll(t, newTxJournal(options.Journal))
+	assert.Equal(t, 1, len(txs))
+	assert.Equal(t, trx.ID(), txs[0].ID())
+}
+
+func TestWashOutOfLifetime(t *testing.T) {
+	pool, repo := newTestPool(t, Options{Limit: 10, LimitPerAccount: 10, MaxLifetime: time.Nanosecond})
+
+	remote := newTx(repo.ChainTag(), richKey, 0, 1, nil)
+	local := newTx(repo.ChainTag(), richKey, 0, 2, nil)
+	assert.Nil(t, pool.Add(remote))
+	assert.Nil(t, pool.AddLocal(local))
+	time.Sleep(time.Millisecond)
+
+	// local txs are kept
+	executables, removed, err := pool.wash(repo.BestBlockSummary())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, tx.Transactions{local}, executables)
+}
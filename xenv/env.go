@@ -0,0 +1,36 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+// Package xenv defines the execution environment of clauses.
+package xenv
+
+import (
+	"math/big"
+
+	"github.com/ashkanabbasii/thor/thor"
+	"github.com/ashkanabbasii/thor/tx"
+)
+
+// BlockContext block context.
+type BlockContext struct {
+	Beneficiary thor.Address
+	Signer      thor.Address
+	Number      uint32
+	Time        uint64
+	GasLimit    uint64
+	TotalScore  uint64
+}
+
+// TransactionContext transaction context.
+type TransactionContext struct {
+	ID          thor.Bytes32
+	Origin      thor.Address
+	GasPayer    thor.Address
+	GasPrice    *big.Int
+	ProvedWork  *big.Int
+	BlockRef    tx.BlockRef
+	Expiration  uint32
+	ClauseCount uint32
+}